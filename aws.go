@@ -0,0 +1,490 @@
+package multiconfig
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// awsContainerEndpoint is the address of the ECS container credentials
+	// provider, used with AWS_CONTAINER_CREDENTIALS_RELATIVE_URI.
+	awsContainerEndpoint = "http://169.254.170.2"
+
+	// awsMetadataEndpoint is the address of the EC2 instance metadata
+	// service.
+	awsMetadataEndpoint = "http://169.254.169.254"
+
+	// awsSTSEndpoint is the address of the AWS Security Token Service,
+	// exchanging a web identity token for the credentials of a role.
+	awsSTSEndpoint = "https://sts.amazonaws.com"
+
+	// awsNow returns the time requests are signed at.
+	awsNow = time.Now
+
+	// awsSessions caches the temporary credentials until they expire, keyed
+	// by their source.
+	awsSessions = struct {
+		sync.Mutex
+		m map[string]awsCredentials
+	}{m: make(map[string]awsCredentials)}
+)
+
+// awsCredentials are the credentials signing the requests to AWS.
+type awsCredentials struct {
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string
+	SessionToken    string `json:"Token"`
+
+	// Expiration is the time temporary credentials expire at, zero for
+	// long-term ones.
+	Expiration time.Time
+}
+
+// awsClient performs the requests to the JSON APIs of AWS services, such as
+// SSM and Secrets Manager.
+type awsClient struct {
+	// service is the name of the service, as used in its endpoint and in the
+	// signature, i.e: "ssm"
+	service string
+
+	// target is the prefix of the X-Amz-Target header, i.e: "AmazonSSM"
+	target string
+
+	region   string
+	endpoint string
+	client   *http.Client
+}
+
+// newAWSClient returns a client of the service in the given region, using
+// AWS_REGION or AWS_DEFAULT_REGION if empty. The endpoint defaults to the
+// public one of the service.
+func newAWSClient(service, target, region, endpoint string, client *http.Client) (*awsClient, error) {
+	region = envOr(envOr(region, "AWS_REGION"), "AWS_DEFAULT_REGION")
+	if region == "" {
+		return nil, fmt.Errorf("multiconfig: %s: region is not set", service)
+	}
+
+	if endpoint == "" {
+		endpoint = "https://" + service + "." + region + ".amazonaws.com"
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &awsClient{
+		service:  service,
+		target:   target,
+		region:   region,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		client:   client,
+	}, nil
+}
+
+// call calls the given action of the service with the input and decodes the
+// response into out.
+func (a *awsClient) call(ctx context.Context, action string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	creds, err := awsCredentialsFromEnv(ctx, a.client)
+	if err != nil {
+		return fmt.Errorf("multiconfig: %s: %s: %s", a.service, action, err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, a.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", a.target+"."+action)
+	a.sign(req, body, creds, awsNow().UTC())
+
+	resp, err := a.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+
+		return fmt.Errorf("multiconfig: %s: %s: unexpected status %s: %s %s",
+			a.service, action, resp.Status, apiErr.Type, apiErr.Message)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("multiconfig: %s: %s: %s", a.service, action, err)
+	}
+
+	return nil
+}
+
+// sign signs the request with the Signature Version 4 of AWS.
+func (a *awsClient) sign(req *http.Request, body []byte, creds awsCredentials, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for key := range req.Header {
+		headers[strings.ToLower(key)] = strings.TrimSpace(req.Header.Get(key))
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hexSHA256(body),
+	}, "\n")
+
+	scope := date + "/" + a.region + "/" + a.service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hexSHA256([]byte(canonicalRequest))
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{date, a.region, a.service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, hex.EncodeToString(hmacSHA256(key, stringToSign))))
+}
+
+// awsCredentialsFromEnv returns the credentials given by the environment
+// variables AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN,
+// or else by the role of AWS_ROLE_ARN assumed with the web identity token of
+// AWS_WEB_IDENTITY_TOKEN_FILE, i.e: on EKS, or else by the profile of the
+// shared credentials file, or else by the ECS container credentials
+// provider, or else by the role of the EC2 instance. Temporary credentials
+// are cached until they expire.
+func awsCredentialsFromEnv(ctx context.Context, client *http.Client) (awsCredentials, error) {
+	if id := os.Getenv("AWS_ACCESS_KEY_ID"); id != "" {
+		return awsCredentials{
+			AccessKeyID:     id,
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+
+	if path := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"); path != "" {
+		role := os.Getenv("AWS_ROLE_ARN")
+		return awsCachedCredentials(role+" "+path, func() (awsCredentials, error) {
+			return awsWebIdentityCredentials(ctx, client, role, path)
+		})
+	}
+
+	if creds, ok, err := awsSharedCredentials(); ok || err != nil {
+		return creds, err
+	}
+
+	uri := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if rel := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); rel != "" {
+		uri = awsContainerEndpoint + rel
+	}
+
+	if uri != "" {
+		return awsCachedCredentials(uri, func() (awsCredentials, error) {
+			return awsContainerCredentials(ctx, client, uri)
+		})
+	}
+
+	return awsCachedCredentials(awsMetadataEndpoint, func() (awsCredentials, error) {
+		return awsInstanceCredentials(ctx, client)
+	})
+}
+
+// awsCachedCredentials returns the credentials cached for the given source,
+// or else the ones returned by fetch, which are cached if they expire.
+func awsCachedCredentials(source string, fetch func() (awsCredentials, error)) (awsCredentials, error) {
+	awsSessions.Lock()
+	creds, ok := awsSessions.m[source]
+	awsSessions.Unlock()
+
+	// the credentials are renewed a minute before they expire, so they
+	// don't expire during a request
+	if ok && awsNow().Add(time.Minute).Before(creds.Expiration) {
+		return creds, nil
+	}
+
+	creds, err := fetch()
+	if err != nil {
+		return creds, err
+	}
+
+	if !creds.Expiration.IsZero() {
+		awsSessions.Lock()
+		awsSessions.m[source] = creds
+		awsSessions.Unlock()
+	}
+
+	return creds, nil
+}
+
+// awsSharedCredentials returns the credentials of the profile named by
+// AWS_PROFILE, or of the default one, in the shared credentials file named
+// by AWS_SHARED_CREDENTIALS_FILE, or else ~/.aws/credentials. ok is false if
+// no profile is named and the file or its default profile doesn't exist.
+func awsSharedCredentials() (creds awsCredentials, ok bool, err error) {
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return creds, false, nil
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+
+	profile := os.Getenv("AWS_PROFILE")
+	named := profile != ""
+	if !named {
+		profile = "default"
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !named && os.IsNotExist(err) {
+			return creds, false, nil
+		}
+		return creds, false, fmt.Errorf("reading the credentials of profile %s: %s", profile, err)
+	}
+
+	section, found := "", false
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if line[0] == '[' && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			found = found || section == profile
+			continue
+		}
+
+		key, val, _ := strings.Cut(line, "=")
+		if section != profile {
+			continue
+		}
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "aws_access_key_id":
+			creds.AccessKeyID = strings.TrimSpace(val)
+		case "aws_secret_access_key":
+			creds.SecretAccessKey = strings.TrimSpace(val)
+		case "aws_session_token":
+			creds.SessionToken = strings.TrimSpace(val)
+		}
+	}
+
+	switch {
+	case !found && !named:
+		return creds, false, nil
+	case !found:
+		return creds, false, fmt.Errorf("profile %s not found in %s", profile, path)
+	case creds.AccessKeyID == "" || creds.SecretAccessKey == "":
+		return creds, false, fmt.Errorf("profile %s of %s has no aws_access_key_id or aws_secret_access_key", profile, path)
+	}
+
+	return creds, true, nil
+}
+
+// awsWebIdentityCredentials returns the credentials of the given role,
+// assumed with the web identity token read from path.
+func awsWebIdentityCredentials(ctx context.Context, client *http.Client, role, path string) (awsCredentials, error) {
+	var creds awsCredentials
+	if role == "" {
+		return creds, fmt.Errorf("AWS_WEB_IDENTITY_TOKEN_FILE is set but AWS_ROLE_ARN is not")
+	}
+
+	token, err := ioutil.ReadFile(path)
+	if err != nil {
+		return creds, fmt.Errorf("reading the web identity token: %s", err)
+	}
+
+	session := os.Getenv("AWS_ROLE_SESSION_NAME")
+	if session == "" {
+		session = fmt.Sprintf("multiconfig-%d", awsNow().UnixNano())
+	}
+
+	form := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {role},
+		"RoleSessionName":  {session},
+		"WebIdentityToken": {strings.TrimSpace(string(token))},
+	}
+
+	// the token is sent in the body, so that it doesn't end up in the
+	// errors holding the URL
+	req, err := http.NewRequest(http.MethodPost, awsSTSEndpoint+"/", strings.NewReader(form.Encode()))
+	if err != nil {
+		return creds, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return creds, fmt.Errorf("assuming role %s: %s", role, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Code    string `xml:"Error>Code"`
+			Message string `xml:"Error>Message"`
+		}
+		xml.NewDecoder(resp.Body).Decode(&apiErr)
+
+		return creds, fmt.Errorf("assuming role %s: unexpected status %s: %s %s", role, resp.Status, apiErr.Code, apiErr.Message)
+	}
+
+	var out struct {
+		Credentials struct {
+			AccessKeyID     string `xml:"AccessKeyId"`
+			SecretAccessKey string
+			SessionToken    string
+			Expiration      time.Time
+		} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return creds, fmt.Errorf("assuming role %s: %s", role, err)
+	}
+
+	return awsCredentials(out.Credentials), nil
+}
+
+// awsContainerCredentials returns the credentials of the ECS task served at
+// the given URL.
+func awsContainerCredentials(ctx context.Context, client *http.Client, url string) (awsCredentials, error) {
+	var creds awsCredentials
+
+	header := http.Header{}
+	if token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"); token != "" {
+		header.Set("Authorization", token)
+	}
+
+	data, err := awsGet(ctx, client, http.MethodGet, url, header)
+	if err != nil {
+		return creds, fmt.Errorf("fetching container credentials: %s", err)
+	}
+
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return creds, fmt.Errorf("fetching container credentials: %s", err)
+	}
+
+	return creds, nil
+}
+
+// awsInstanceCredentials returns the credentials of the role of the EC2
+// instance, from its metadata service.
+func awsInstanceCredentials(ctx context.Context, client *http.Client) (awsCredentials, error) {
+	var creds awsCredentials
+
+	// the metadata service answers quickly or isn't there at all, i.e: when
+	// not running on AWS
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	token, err := awsGet(ctx, client, http.MethodPut, awsMetadataEndpoint+"/latest/api/token",
+		http.Header{"X-Aws-Ec2-Metadata-Token-Ttl-Seconds": {"21600"}})
+	if err != nil {
+		return creds, fmt.Errorf("no credentials found in the environment nor in the instance metadata: %s", err)
+	}
+
+	header := http.Header{"X-Aws-Ec2-Metadata-Token": {string(token)}}
+	path := awsMetadataEndpoint + "/latest/meta-data/iam/security-credentials/"
+
+	role, err := awsGet(ctx, client, http.MethodGet, path, header)
+	if err != nil {
+		return creds, fmt.Errorf("fetching the instance role: %s", err)
+	}
+
+	data, err := awsGet(ctx, client, http.MethodGet, path+url.PathEscape(strings.TrimSpace(string(role))), header)
+	if err != nil {
+		return creds, fmt.Errorf("fetching the instance credentials: %s", err)
+	}
+
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return creds, fmt.Errorf("fetching the instance credentials: %s", err)
+	}
+
+	return creds, nil
+}
+
+// awsGet performs a request to a credentials provider and returns the
+// response body.
+func awsGet(ctx context.Context, client *http.Client, method, url string, header http.Header) ([]byte, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s %s: unexpected status %s", method, url, resp.Status)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+func hexSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
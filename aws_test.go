@@ -0,0 +1,427 @@
+package multiconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAWSSign(t *testing.T) {
+	// the example of the AWS Signature Version 4 documentation
+	req, err := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	a := &awsClient{service: "iam", region: "us-east-1"}
+	a.sign(req, nil, awsCredentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}, time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-date, " +
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization is\n%s\nwant\n%s", got, want)
+	}
+}
+
+// setAWSEnv sets the given AWS environment variables, and unsets the other
+// ones, for the duration of a test. The shared credentials file is looked for
+// in an empty directory, and no credentials are cached.
+func setAWSEnv(t *testing.T, env map[string]string) {
+	if env == nil {
+		env = map[string]string{}
+	}
+
+	if _, ok := env["AWS_SHARED_CREDENTIALS_FILE"]; !ok {
+		env["AWS_SHARED_CREDENTIALS_FILE"] = filepath.Join(t.TempDir(), "credentials")
+	}
+
+	resetSessions := func() {
+		awsSessions.Lock()
+		awsSessions.m = make(map[string]awsCredentials)
+		awsSessions.Unlock()
+	}
+	resetSessions()
+	t.Cleanup(resetSessions)
+
+	for _, key := range []string{
+		"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN",
+		"AWS_REGION", "AWS_DEFAULT_REGION",
+		"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "AWS_CONTAINER_CREDENTIALS_FULL_URI",
+		"AWS_PROFILE", "AWS_SHARED_CREDENTIALS_FILE",
+		"AWS_WEB_IDENTITY_TOKEN_FILE", "AWS_ROLE_ARN", "AWS_ROLE_SESSION_NAME",
+	} {
+		old, ok := os.LookupEnv(key)
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(key, old)
+			} else {
+				os.Unsetenv(key)
+			}
+		})
+
+		if val, set := env[key]; set {
+			os.Setenv(key, val)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+// newFakeSSM serves GetParametersByPath two parameters at a time.
+func newFakeSSM(t *testing.T, params map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") ||
+			r.Header.Get("X-Amz-Target") != "AmazonSSM.GetParametersByPath" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type": "AccessDeniedException", "message": "denied"}`))
+			return
+		}
+
+		var in struct {
+			Path           string
+			Recursive      bool
+			WithDecryption bool
+			NextToken      string
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil || !in.Recursive || !in.WithDecryption {
+			t.Errorf("unexpected request: %+v, %v", in, err)
+		}
+
+		var names []string
+		for name := range params {
+			if strings.HasPrefix(name, in.Path) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		start := 0
+		if in.NextToken != "" {
+			for i, name := range names {
+				if name == in.NextToken {
+					start = i
+				}
+			}
+		}
+
+		type param struct{ Name, Value string }
+		resp := struct {
+			Parameters []param
+			NextToken  string `json:",omitempty"`
+		}{}
+		for i := start; i < len(names) && i < start+2; i++ {
+			resp.Parameters = append(resp.Parameters, param{names[i], params[names[i]]})
+		}
+		if start+2 < len(names) {
+			resp.NextToken = names[start+2]
+		}
+
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestSSMLoader(t *testing.T) {
+	setAWSEnv(t, map[string]string{
+		"AWS_ACCESS_KEY_ID":     "AKID",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+		"AWS_REGION":            "eu-west-1",
+	})
+
+	srv := newFakeSSM(t, map[string]string{
+		"/myapp/prod/name":                       "koding",
+		"/myapp/prod/port":                       "6060",
+		"/myapp/prod/enabled":                    "true",
+		"/myapp/prod/id":                         "1234567890",
+		"/myapp/prod/labels":                     "123,456",
+		"/myapp/prod/users":                      "ankara,istanbul",
+		"/myapp/prod/interval":                   "10s",
+		"/myapp/prod/postgres/enabled":           "true",
+		"/myapp/prod/postgres/port":              "5432",
+		"/myapp/prod/postgres/hosts":             "192.168.2.1,192.168.2.2,192.168.2.3",
+		"/myapp/prod/postgres/dbname":            "configdb",
+		"/myapp/prod/postgres/availabilityratio": "8.23",
+		"/myapp/dev/name":                        "dev",
+	})
+	defer srv.Close()
+
+	s := &Server{}
+	if err := (&SSMLoader{Path: "/myapp/prod/", Endpoint: srv.URL}).Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	testStruct(t, s, getDefaultServer())
+}
+
+func TestSSMLoaderErrors(t *testing.T) {
+	setAWSEnv(t, map[string]string{
+		"AWS_ACCESS_KEY_ID":     "other",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+	})
+
+	srv := newFakeSSM(t, nil)
+	defer srv.Close()
+
+	err := (&SSMLoader{Path: "/myapp/prod/", Endpoint: srv.URL}).Load(&Server{})
+	if err == nil || !strings.Contains(err.Error(), "region is not set") {
+		t.Errorf("expected a region error, got: %v", err)
+	}
+
+	err = (&SSMLoader{Path: "/myapp/prod/", Region: "eu-west-1", Endpoint: srv.URL}).Load(&Server{})
+	if err == nil || !strings.Contains(err.Error(), "AccessDeniedException denied") {
+		t.Errorf("expected an access denied error, got: %v", err)
+	}
+}
+
+func TestSecretsManagerLoader(t *testing.T) {
+	setAWSEnv(t, map[string]string{
+		"AWS_REGION":                             "eu-west-1",
+		"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI": "/v2/credentials/task",
+	})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/credentials/task":
+			w.Write([]byte(`{"AccessKeyId": "AKID", "SecretAccessKey": "secret", "Token": "session"}`))
+		case r.Header.Get("X-Amz-Target") == "secretsmanager.GetSecretValue":
+			if r.Header.Get("X-Amz-Security-Token") != "session" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			var in struct{ SecretId string }
+			json.NewDecoder(r.Body).Decode(&in)
+			if in.SecretId != "myapp/prod" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"__type": "ResourceNotFoundException", "message": "not found"}`))
+				return
+			}
+
+			w.Write([]byte(`{"Name": "myapp/prod", "SecretString": "{\"Name\": \"koding\", \"Postgres\": {\"Port\": 5432}}"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	defer func(endpoint string) { awsContainerEndpoint = endpoint }(awsContainerEndpoint)
+	awsContainerEndpoint = srv.URL
+
+	s := &Server{}
+	if err := (&SecretsManagerLoader{SecretID: "myapp/prod", Endpoint: srv.URL}).Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Name != "koding" || s.Postgres.Port != 5432 {
+		t.Errorf("secret not loaded: %+v", s)
+	}
+
+	err := (&SecretsManagerLoader{SecretID: "missing", Endpoint: srv.URL}).Load(&Server{})
+	if err == nil || !strings.Contains(err.Error(), "ResourceNotFoundException") {
+		t.Errorf("expected a not found error, got: %v", err)
+	}
+}
+
+func TestAWSInstanceCredentials(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest/api/token" {
+			if r.Method != http.MethodPut {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Write([]byte("imds-token"))
+			return
+		}
+
+		if r.Header.Get("X-Aws-Ec2-Metadata-Token") != "imds-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/latest/meta-data/iam/security-credentials/":
+			w.Write([]byte("app-role"))
+		case "/latest/meta-data/iam/security-credentials/app-role":
+			w.Write([]byte(`{"Code": "Success", "AccessKeyId": "AKID", "SecretAccessKey": "secret", "Token": "session"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	setAWSEnv(t, nil)
+
+	defer func(endpoint string) { awsMetadataEndpoint = endpoint }(awsMetadataEndpoint)
+	awsMetadataEndpoint = srv.URL
+
+	creds, err := awsCredentialsFromEnv(context.Background(), http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := (awsCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "session"}); creds != want {
+		t.Errorf("credentials are %+v, want %+v", creds, want)
+	}
+}
+
+func TestAWSCredentialsCache(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprintf(w, `{"AccessKeyId": "AKID", "SecretAccessKey": "secret", "Token": "session", "Expiration": %q}`,
+			awsNow().Add(time.Hour).UTC().Format(time.RFC3339))
+	}))
+	defer srv.Close()
+
+	setAWSEnv(t, map[string]string{"AWS_CONTAINER_CREDENTIALS_FULL_URI": srv.URL})
+
+	now := time.Now()
+	defer func(f func() time.Time) { awsNow = f }(awsNow)
+	awsNow = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := awsCredentialsFromEnv(context.Background(), http.DefaultClient); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("credentials fetched %d times, want them cached", n)
+	}
+
+	// the credentials are renewed shortly before they expire
+	now = now.Add(time.Hour - 30*time.Second)
+	if _, err := awsCredentialsFromEnv(context.Background(), http.DefaultClient); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("credentials fetched %d times, want them renewed", n)
+	}
+}
+
+func TestAWSSharedCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials")
+	data := `
+[default]
+aws_access_key_id = AKID
+aws_secret_access_key = secret
+
+# a profile with a session
+[dev]
+aws_access_key_id=DEVKEY
+aws_secret_access_key=devsecret
+aws_session_token=devsession
+`
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		profile string
+		want    awsCredentials
+		err     string
+	}{
+		{profile: "", want: awsCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}},
+		{profile: "dev", want: awsCredentials{AccessKeyID: "DEVKEY", SecretAccessKey: "devsecret", SessionToken: "devsession"}},
+		{profile: "prod", err: "profile prod not found in " + path},
+	}
+
+	for _, test := range tests {
+		setAWSEnv(t, map[string]string{"AWS_SHARED_CREDENTIALS_FILE": path, "AWS_PROFILE": test.profile})
+
+		creds, err := awsCredentialsFromEnv(context.Background(), http.DefaultClient)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("profile %q: got error %v, want %s", test.profile, err, test.err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("profile %q: %s", test.profile, err)
+		}
+
+		if creds != test.want {
+			t.Errorf("profile %q: credentials are %+v, want %+v", test.profile, creds, test.want)
+		}
+	}
+}
+
+func TestAWSWebIdentityCredentials(t *testing.T) {
+	expiration := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Method != http.MethodPost || r.Form.Get("Action") != "AssumeRoleWithWebIdentity" ||
+			r.Form.Get("RoleArn") != "arn:aws:iam::123456789012:role/app" || r.Form.Get("WebIdentityToken") != "jwt" ||
+			r.Form.Get("RoleSessionName") != "app" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`<ErrorResponse><Error><Code>InvalidIdentityToken</Code><Message>bad token</Message></Error></ErrorResponse>`))
+			return
+		}
+
+		fmt.Fprintf(w, `<AssumeRoleWithWebIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleWithWebIdentityResult>
+    <Credentials>
+      <AccessKeyId>AKID</AccessKeyId>
+      <SecretAccessKey>secret</SecretAccessKey>
+      <SessionToken>session</SessionToken>
+      <Expiration>%s</Expiration>
+    </Credentials>
+  </AssumeRoleWithWebIdentityResult>
+</AssumeRoleWithWebIdentityResponse>`, expiration.Format(time.RFC3339))
+	}))
+	defer srv.Close()
+
+	defer func(endpoint string) { awsSTSEndpoint = endpoint }(awsSTSEndpoint)
+	awsSTSEndpoint = srv.URL
+
+	token := filepath.Join(t.TempDir(), "token")
+	if err := ioutil.WriteFile(token, []byte("jwt\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	setAWSEnv(t, map[string]string{
+		"AWS_WEB_IDENTITY_TOKEN_FILE": token,
+		"AWS_ROLE_ARN":                "arn:aws:iam::123456789012:role/app",
+		"AWS_ROLE_SESSION_NAME":       "app",
+	})
+
+	creds, err := awsCredentialsFromEnv(context.Background(), http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := awsCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "session", Expiration: expiration}
+	if !creds.Expiration.Equal(want.Expiration) {
+		t.Errorf("credentials expire at %s, want %s", creds.Expiration, want.Expiration)
+	}
+	creds.Expiration = want.Expiration
+	if creds != want {
+		t.Errorf("credentials are %+v, want %+v", creds, want)
+	}
+
+	setAWSEnv(t, map[string]string{
+		"AWS_WEB_IDENTITY_TOKEN_FILE": token,
+		"AWS_ROLE_ARN":                "arn:aws:iam::123456789012:role/other",
+	})
+
+	_, err = awsCredentialsFromEnv(context.Background(), http.DefaultClient)
+	if err == nil || !strings.Contains(err.Error(), "InvalidIdentityToken bad token") {
+		t.Errorf("expected an invalid token error, got: %v", err)
+	}
+}
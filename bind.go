@@ -0,0 +1,175 @@
+package multiconfig
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/fatih/structs"
+)
+
+// BindStandard registers a flag for each field of the struct s on the given
+// flag set and returns a loader resolving the fields from, in order of
+// increasing precedence:
+//
+//	default tags < files in paths < environment variables < flags
+//
+// The flag set is parsed by the caller, i.e:
+//
+//	m := multiconfig.BindStandard(flag.CommandLine, "APP", cfg, "config.toml")
+//	flag.Parse()
+//	m.MustLoad(cfg)
+//
+// Flags are named like the ones of a FlagLoader and environment variables are
+// looked up with the envPrefix like an EnvironmentLoader. Only the flags
+// given on the command line are applied, so an unset flag never clobbers a
+// value from the environment, a file or a default tag.
+func BindStandard(fs *flag.FlagSet, envPrefix string, s interface{}, paths ...string) *DefaultLoader {
+	return bind(&flagDefs{FlagLoader: &FlagLoader{EnvPrefix: envPrefix}, flagSet: fs}, envPrefix, s, paths)
+}
+
+// Flag is the definition of a flag bound by BindFlags.
+type Flag struct {
+	// Name is the name of the flag, i.e: "db-url".
+	Name string
+
+	// Shorthand is the one-letter alias given by the flag tag, if any.
+	Shorthand string
+
+	// Usage is the usage message of the flag.
+	Usage string
+
+	// NoOptDefVal is the value of the flag when given without a value, "true"
+	// for booleans, like the NoOptDefVal of a pflag.Flag.
+	NoOptDefVal string
+
+	// Value stores the value of the flag once parsed.
+	Value FlagValue
+}
+
+// FlagValue is the value of a flag. It satisfies both flag.Value and the
+// pflag.Value interface of github.com/spf13/pflag.
+type FlagValue interface {
+	flag.Value
+
+	// Type returns the name of the type of the value.
+	Type() string
+}
+
+// BindFlags is like BindStandard for flag sets other than *flag.FlagSet,
+// such as the pflag.FlagSet of a cobra command. Each flag is handed to
+// register, which defines it on the flag set, i.e:
+//
+//	m := multiconfig.BindFlags(func(f *multiconfig.Flag) {
+//		fl := cmd.Flags().VarPF(f.Value, f.Name, f.Shorthand, f.Usage)
+//		fl.NoOptDefVal = f.NoOptDefVal
+//	}, "APP", cfg, "config.toml")
+//
+// The returned loader is used once the flag set was parsed, i.e: within the
+// Run function of the command, so os.Args is only parsed once.
+func BindFlags(register func(f *Flag), envPrefix string, s interface{}, paths ...string) *DefaultLoader {
+	f := &flagDefs{FlagLoader: &FlagLoader{EnvPrefix: envPrefix}}
+	f.register = func(_ string, _ *structs.Field, fl *Flag) { register(fl) }
+
+	return bind(f, envPrefix, s, paths)
+}
+
+// bind defines the flags of the fields of s with f and returns a loader
+// applying the flags set on the command line over the other sources.
+func bind(f *flagDefs, envPrefix string, s interface{}, paths []string) *DefaultLoader {
+	b := &boundFlags{values: make(map[string]*boundValue)}
+
+	f.newValue = func(_ interface{}, path, name string, field *structs.Field) FlagValue {
+		v := &boundValue{typ: reflect.TypeOf(field.Value()), path: path, name: name}
+		b.values[path] = v
+		return v
+	}
+
+	for _, field := range structs.Fields(s) {
+		f.processField(s, field.Name(), f.fieldName(field), field)
+	}
+
+	loaders := []Loader{&TagLoader{}}
+	for _, path := range paths {
+		if l := fileLoader(nil, path); l != nil {
+			loaders = append(loaders, l)
+		}
+	}
+
+	loaders = append(loaders, &EnvironmentLoader{Prefix: envPrefix}, b)
+	return newDefaultLoader(loaders...)
+}
+
+// boundFlags loads the values of the flags bound by BindStandard or
+// BindFlags that were set on the command line.
+type boundFlags struct {
+	// values are the bound flag values keyed by field path.
+	values map[string]*boundValue
+}
+
+// Load sets the fields of the struct s whose flag was set during parsing.
+func (b *boundFlags) Load(s interface{}) error {
+	paths := make([]string, 0, len(b.values))
+	for path, v := range b.values {
+		if v.set {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		v := b.values[path]
+
+		field, ok := fieldByPath(s, path)
+		if !ok {
+			return fmt.Errorf("multiconfig: field '%s' not found", path)
+		}
+
+		if err := fieldSet(field, v.value, "", path); err != nil {
+			return withLoader(err, "flag -"+v.name)
+		}
+
+		markLoaded(s, path, "flag -"+v.name)
+	}
+
+	return nil
+}
+
+// boundValue is the FlagValue of a bound field. It only checks and stores
+// the given string, which is set on the field when loading.
+type boundValue struct {
+	typ  reflect.Type
+	path string
+	name string
+
+	// set is true once the flag was given on the command line.
+	set   bool
+	value string
+}
+
+func (v *boundValue) Set(val string) error {
+	if err := setString(reflect.New(v.typ).Elem(), val, "", v.path); err != nil {
+		return err
+	}
+
+	v.value = val
+	v.set = true
+	return nil
+}
+
+func (v *boundValue) Type() string {
+	return v.typ.String()
+}
+
+func (v *boundValue) String() string {
+	if v == nil {
+		return ""
+	}
+
+	return v.value
+}
+
+func (v *boundValue) IsBoolFlag() bool {
+	return v.typ != nil && v.typ.Kind() == reflect.Bool
+}
@@ -0,0 +1,97 @@
+package multiconfig
+
+import (
+	"flag"
+	"io"
+	"testing"
+)
+
+func TestBindStandard(t *testing.T) {
+	t.Setenv("BIND_NAME", "env")
+	t.Setenv("BIND_POSTGRES_PORT", "6432")
+	t.Setenv("BIND_POSTGRES_DBNAME", "envdb")
+
+	s := new(Server)
+	fs := flag.NewFlagSet("bind", flag.ContinueOnError)
+	m := BindStandard(fs, "BIND", s, testTOML)
+
+	args := []string{"-name", "flag", "-postgres-port", "7432", "-enabled=false"}
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	want := getDefaultServer()
+	want.Name = "flag"
+	want.Enabled = false
+	want.Postgres.Port = 7432
+	want.Postgres.DBName = "envdb"
+
+	testStruct(t, s, want)
+}
+
+func TestBindStandardInvalidFlag(t *testing.T) {
+	s := new(Server)
+	fs := flag.NewFlagSet("bind", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	BindStandard(fs, "BIND", s)
+
+	if err := fs.Parse([]string{"-port", "http"}); err == nil {
+		t.Error("parsing an invalid port should fail")
+	}
+}
+
+func TestBindFlags(t *testing.T) {
+	type Options struct {
+		DBURL   string `flag:"db-url,d" default:"postgres://localhost"`
+		Port    int    `default:"8080"`
+		Verbose bool
+	}
+
+	// flags keeps the definitions the way a pflag.FlagSet would
+	flags := map[string]*Flag{}
+
+	s := new(Options)
+	m := BindFlags(func(f *Flag) { flags[f.Name] = f }, "BIND", s)
+
+	db, port, verbose := flags["db-url"], flags["port"], flags["verbose"]
+	if db == nil || port == nil || verbose == nil {
+		t.Fatalf("missing flag definitions: %v", flags)
+	}
+
+	if db.Shorthand != "d" || port.Shorthand != "" {
+		t.Errorf("wrong shorthands: %q, %q", db.Shorthand, port.Shorthand)
+	}
+
+	if verbose.NoOptDefVal != "true" || port.NoOptDefVal != "" {
+		t.Errorf("wrong NoOptDefVal: %q, %q", verbose.NoOptDefVal, port.NoOptDefVal)
+	}
+
+	if port.Value.Type() != "int" {
+		t.Errorf("wrong type: %q", port.Value.Type())
+	}
+
+	if err := port.Value.Set("http"); err == nil {
+		t.Error("setting an invalid port should fail")
+	}
+
+	// as pflag would do while parsing "-d postgres://db --verbose"
+	if err := db.Value.Set("postgres://db"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verbose.Value.Set(verbose.NoOptDefVal); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.DBURL != "postgres://db" || s.Port != 8080 || !s.Verbose {
+		t.Errorf("unexpected config: %+v", s)
+	}
+}
@@ -0,0 +1,35 @@
+package multiconfig
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// base64Prefix marks a base64 encoded value of a []byte field, i.e:
+// "base64:a29kaW5n".
+const base64Prefix = "base64:"
+
+// decodeBytes returns the value of a []byte field given by s, in any source:
+// the decoded value of a "base64:" prefix, or else the bytes of s, which are
+// base64 encoded if encoded is true, as for JSON.
+func decodeBytes(s string, encoded bool) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(s, base64Prefix):
+		return decodeBase64(strings.TrimPrefix(s, base64Prefix))
+	case encoded:
+		return decodeBase64(s)
+	}
+
+	return []byte(s), nil
+}
+
+// decodeBase64 decodes the standard base64 encoding of s, padded or not.
+// Whitespace is ignored, for the values wrapped on several lines.
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.Join(strings.Fields(s), "")
+	if strings.HasSuffix(s, "=") {
+		return base64.StdEncoding.DecodeString(s)
+	}
+
+	return base64.RawStdEncoding.DecodeString(s)
+}
@@ -0,0 +1,66 @@
+package multiconfig
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type blobServer struct {
+	Cert []byte
+	Key  []byte
+	Salt []byte `default:"base64:c2FsdA=="`
+}
+
+func TestBlobFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cert.pem")
+	cert := []byte("-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n")
+	if err := ioutil.WriteFile(path, cert, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	loaders := map[string]Loader{
+		"env": &EnvironmentLoader{getenv: testEnvironment{
+			"BLOBSERVER_CERT": string(cert),
+			"BLOBSERVER_KEY":  "base64:a29k\naW5n",
+		}.get},
+		"flag": &FlagLoader{Args: []string{"-cert", string(cert), "-key", "base64:a29kaW5n"}},
+		"toml": &TOMLLoader{Reader: strings.NewReader("cert = \"file:" + path + "\"\nkey = \"base64:a29kaW5n\"\n"), FileOptions: FileOptions{FileRefs: true}},
+		"json": &JSONLoader{Reader: strings.NewReader(`{"cert": "file:` + path + `", "key": "a29kaW5n"}`), FileOptions: FileOptions{FileRefs: true}},
+	}
+
+	for name, l := range loaders {
+		s := &blobServer{}
+		if err := MultiLoader(&TagLoader{}, l).Load(s); err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+
+		if string(s.Cert) != string(cert) || string(s.Key) != "koding" || string(s.Salt) != "salt" {
+			t.Errorf("%s: unexpected values: %q, %q, %q", name, s.Cert, s.Key, s.Salt)
+		}
+	}
+
+	// values without prefix are kept as is, except in JSON
+	s := &blobServer{}
+	if err := (&YAMLLoader{Reader: strings.NewReader("key: koding\n")}).Load(s); err != nil || string(s.Key) != "koding" {
+		t.Errorf("unexpected key %q: %v", s.Key, err)
+	}
+
+	err := (&EnvironmentLoader{getenv: testEnvironment{"BLOBSERVER_KEY": "base64:!"}.get}).Load(&blobServer{})
+	if err == nil || !strings.HasPrefix(err.Error(), "multiconfig: field 'Key'") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err = (&TOMLLoader{Reader: strings.NewReader("cert = \"file:/missing.pem\"\n"), FileOptions: FileOptions{FileRefs: true}}).Load(&blobServer{})
+	if err == nil || !strings.HasPrefix(err.Error(), "multiconfig: field 'Cert'") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// files are only read from the sources allowing it
+	s = &blobServer{}
+	if err := (&TOMLLoader{Reader: strings.NewReader("cert = \"file:" + path + "\"\n")}).Load(s); err != nil || string(s.Cert) != "file:"+path {
+		t.Errorf("unexpected cert %q: %v", s.Cert, err)
+	}
+}
@@ -0,0 +1,98 @@
+package multiconfig
+
+import (
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// Builder builds a DefaultLoader from the sources added to it. Sources are
+// loaded in the order they're added, each one overriding the values set by
+// the previous ones, i.e:
+//
+//	m := multiconfig.NewBuilder().
+//		WithTags().
+//		WithEnv("APP").
+//		WithFile("config.toml"). // the file wins over the environment
+//		WithFlags().
+//		Build()
+//
+// Unlike New and NewWithPath, only the sources added are loaded.
+type Builder struct {
+	loaders []Loader
+}
+
+// NewBuilder returns a Builder without any source.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// WithTags adds the default tags of the fields, see TagLoader.
+func (b *Builder) WithTags() *Builder {
+	return b.WithLoader(&TagLoader{})
+}
+
+// WithFile adds the given configuration files, loaded in order. Paths may be
+// directories or glob patterns, whose files are loaded in lexical order,
+// like the paths of NewWithPaths. The format of a file is given by its
+// extension.
+func (b *Builder) WithFile(paths ...string) *Builder {
+	return b.WithFS(nil, paths...)
+}
+
+// WithFS is like WithFile but reads the files from the given file system,
+// such as an embed.FS. A nil fsys is the operating system's file system.
+func (b *Builder) WithFS(fsys fs.FS, paths ...string) *Builder {
+	for _, path := range paths {
+		files := expandPath(fsys, path)
+		for _, file := range files {
+			l := fileLoader(fsys, file)
+			if l == nil && len(files) == 1 && file == path {
+				// a file named explicitly must be loaded
+				l = unsupportedFormat(strings.TrimPrefix(filepath.Ext(file), "."))
+			}
+
+			if l != nil {
+				b.WithLoader(l)
+			}
+		}
+	}
+
+	return b
+}
+
+// WithReader adds the configuration read from r in the given format, see
+// NewWithReader.
+func (b *Builder) WithReader(r io.Reader, format Format) *Builder {
+	var l Loader = unsupportedFormat(format)
+	if rl := readerLoader(string(format), r); rl != nil {
+		l = rl
+	}
+
+	return b.WithLoader(l)
+}
+
+// WithEnv adds the environment variables, named after the given prefix or,
+// if empty, after the name of the struct, see EnvironmentLoader.
+func (b *Builder) WithEnv(prefix string) *Builder {
+	return b.WithLoader(&EnvironmentLoader{Prefix: prefix})
+}
+
+// WithFlags adds the command line flags, see FlagLoader.
+func (b *Builder) WithFlags() *Builder {
+	return b.WithLoader(&FlagLoader{})
+}
+
+// WithLoader adds any loader, such as a remote source or a loader with
+// custom options, i.e: &EnvironmentLoader{Prefix: "APP", CamelCase: true}.
+func (b *Builder) WithLoader(l Loader) *Builder {
+	b.loaders = append(b.loaders, l)
+	return b
+}
+
+// Build returns a DefaultLoader running the loaders of the sources in the
+// order they were added, with the default validators.
+func (b *Builder) Build() *DefaultLoader {
+	return newDefaultLoader(append([]Loader(nil), b.loaders...)...)
+}
@@ -0,0 +1,69 @@
+package multiconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	t.Setenv("SERVER_NAME", "env")
+	t.Setenv("SERVER_PORT", "7070")
+
+	// the file is loaded after the environment, and wins over it
+	s := new(Server)
+	if err := NewBuilder().WithTags().WithEnv("").WithFile(testTOML).Build().Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	want := getDefaultServer()
+	want.Port = 7070
+	testStruct(t, s, want)
+
+	// sources not added are not loaded
+	s = new(Server)
+	if err := NewBuilder().WithFile(testTOML).Build().Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Name != "koding" || s.Port != 0 || s.Postgres.DBName != "" {
+		t.Errorf("unexpected config: %+v", s)
+	}
+
+	s = new(Server)
+	reader := strings.NewReader(`{"Name": "reader"}`)
+	if err := NewBuilder().WithEnv("").WithReader(reader, JSON).Build().Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Name != "reader" || s.Port != 7070 {
+		t.Errorf("unexpected config: %+v", s)
+	}
+}
+
+func TestBuilderUnsupportedFile(t *testing.T) {
+	err := NewBuilder().WithFile("testdata/config.conf").Build().Load(new(Server))
+	if err == nil || err.Error() != `multiconfig: unsupported format "conf"` {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// the files of a directory with an unsupported extension are skipped
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("configs"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "server.toml"), []byte(`Name = "dir"`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := new(Server)
+	if err := NewBuilder().WithFile(dir).Build().Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Name != "dir" {
+		t.Errorf("unexpected name: %q", s.Name)
+	}
+}
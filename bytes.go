@@ -0,0 +1,146 @@
+package multiconfig
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Bytes is a size in bytes, parsed from a human-readable value by every
+// loader, the way time.Duration is:
+//
+//	type Cache struct {
+//		Size multiconfig.Bytes `default:"512MB"`
+//	}
+//
+// A value is a number, possibly with a fraction, followed by an optional
+// unit. The decimal units "k", "M", "G", "T", "P" and "E" are powers of 1000
+// and the binary units "Ki", "Mi", "Gi", "Ti", "Pi" and "Ei" powers of 1024.
+// Units are case insensitive and may end with "B", so "10k", "10kb" and
+// "10KB" are all 10000 bytes while "2GiB" is 2147483648 bytes. A number
+// without unit, such as an integer of a config file, is a count of bytes.
+type Bytes int64
+
+var bytesType = reflect.TypeOf(Bytes(0))
+
+// Common sizes.
+const (
+	Byte     Bytes = 1
+	Kilobyte       = 1000 * Byte
+	Megabyte       = 1000 * Kilobyte
+	Gigabyte       = 1000 * Megabyte
+	Terabyte       = 1000 * Gigabyte
+	Petabyte       = 1000 * Terabyte
+	Exabyte        = 1000 * Petabyte
+
+	Kibibyte = 1024 * Byte
+	Mebibyte = 1024 * Kibibyte
+	Gibibyte = 1024 * Mebibyte
+	Tebibyte = 1024 * Gibibyte
+	Pebibyte = 1024 * Tebibyte
+	Exbibyte = 1024 * Pebibyte
+)
+
+// byteUnits are the units of Bytes, from the largest, as written by String.
+var byteUnits = []struct {
+	name string
+	size Bytes
+}{
+	{"EiB", Exbibyte}, {"EB", Exabyte},
+	{"PiB", Pebibyte}, {"PB", Petabyte},
+	{"TiB", Tebibyte}, {"TB", Terabyte},
+	{"GiB", Gibibyte}, {"GB", Gigabyte},
+	{"MiB", Mebibyte}, {"MB", Megabyte},
+	{"KiB", Kibibyte}, {"kB", Kilobyte},
+}
+
+// ParseBytes parses a size such as "512MB" or "2GiB" into Bytes. Negative
+// sizes are rejected.
+func ParseBytes(s string) (Bytes, error) {
+	s = strings.TrimSpace(s)
+
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '-' && r != '+'
+	})
+	if i == -1 {
+		i = len(s)
+	}
+
+	num, unit := s[:i], strings.ToLower(strings.TrimSpace(s[i:]))
+	if num == "" {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	if strings.HasPrefix(num, "-") {
+		return 0, fmt.Errorf("invalid size %q, a size can't be negative", s)
+	}
+
+	size := Byte
+	if unit = strings.TrimSuffix(unit, "b"); unit != "" {
+		found := false
+		for _, u := range byteUnits {
+			if strings.ToLower(strings.TrimSuffix(u.name, "B")) == unit {
+				size, found = u.size, true
+				break
+			}
+		}
+
+		if !found {
+			return 0, fmt.Errorf("invalid size %q, unknown unit %q", s, s[i:])
+		}
+	}
+
+	// integers are multiplied exactly, large values losing no precision
+	if n, err := strconv.ParseInt(num, 10, 64); err == nil {
+		if n != 0 && (n*int64(size))/n != int64(size) {
+			return 0, fmt.Errorf("invalid size %q, out of range", s)
+		}
+
+		return Bytes(n) * size, nil
+	}
+
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	// float64(math.MaxInt64) rounds up to 2^63, which overflows
+	f *= float64(size)
+	if f >= math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %q, out of range", s)
+	}
+
+	return Bytes(math.Round(f)), nil
+}
+
+// String returns the size with the largest unit it's a multiple of, i.e:
+// "512MB" or "2GiB".
+func (b Bytes) String() string {
+	if b != 0 {
+		for _, u := range byteUnits {
+			if b%u.size == 0 {
+				return strconv.FormatInt(int64(b/u.size), 10) + u.name
+			}
+		}
+	}
+
+	return strconv.FormatInt(int64(b), 10) + "B"
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (b *Bytes) UnmarshalText(text []byte) error {
+	size, err := ParseBytes(string(text))
+	if err != nil {
+		return err
+	}
+
+	*b = size
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (b Bytes) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
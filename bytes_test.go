@@ -0,0 +1,109 @@
+package multiconfig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseBytes(t *testing.T) {
+	tests := map[string]Bytes{
+		"0":          0,
+		"512":        512,
+		"512B":       512,
+		"10k":        10 * Kilobyte,
+		"10 kB":      10 * Kilobyte,
+		"512MB":      512 * Megabyte,
+		"512mb":      512 * Megabyte,
+		"2GiB":       2 * Gibibyte,
+		"2gi":        2 * Gibibyte,
+		"1.5KiB":     1536,
+		"1.5G":       1500 * Megabyte,
+		"8EiB":       0,
+		"8.0EiB":     0,
+		"-1":         0,
+		"-5MB":       0,
+		"+5MB":       5 * Megabyte,
+		"7EiB":       7 * Exbibyte,
+		"invalid":    0,
+		"10 parsecs": 0,
+	}
+
+	for s, want := range tests {
+		got, err := ParseBytes(s)
+		if want == 0 && s != "0" {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %d", s, got)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %s", s, err)
+			continue
+		}
+
+		if got != want {
+			t.Errorf("%s: got %d, want %d", s, got, want)
+		}
+	}
+}
+
+func TestBytesString(t *testing.T) {
+	tests := map[Bytes]string{
+		0:               "0B",
+		512:             "512B",
+		1536:            "1536B",
+		10 * Kilobyte:   "10kB",
+		2 * Gibibyte:    "2GiB",
+		2000 * Kibibyte: "2000KiB",
+		1500 * Megabyte: "1500MB",
+		3 * Terabyte:    "3TB",
+	}
+
+	for b, want := range tests {
+		if got := b.String(); got != want {
+			t.Errorf("%d: got %s, want %s", int64(b), got, want)
+		}
+
+		if parsed, err := ParseBytes(want); err != nil || parsed != b {
+			t.Errorf("%s: parsed back as %d, %v", want, parsed, err)
+		}
+	}
+}
+
+type Limits struct {
+	Cache  Bytes `default:"512MB"`
+	Upload Bytes
+	Parts  []Bytes
+}
+
+func TestBytesLoaders(t *testing.T) {
+	sources := map[string]Loader{
+		"json":  &JSONLoader{Reader: strings.NewReader(`{"Upload": "2GiB", "Parts": [1024, "5MiB"]}`)},
+		"toml":  &TOMLLoader{Reader: strings.NewReader("Upload = \"2GiB\"\nParts = [1024, \"5MiB\"]\n")},
+		"yaml":  &YAMLLoader{Reader: strings.NewReader("upload: 2GiB\nparts: [1024, 5MiB]\n")},
+		"env":   &EnvironmentLoader{getenv: testEnvironment{"LIMITS_UPLOAD": "2GiB", "LIMITS_PARTS": "1024,5MiB"}.get},
+		"flags": &FlagLoader{Args: []string{"-upload", "2GiB", "-parts", "1024,5MiB"}},
+	}
+
+	for name, loader := range sources {
+		l := &Limits{}
+		if err := MultiLoader(&TagLoader{}, loader).Load(l); err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+
+		if l.Cache != 512*Megabyte || l.Upload != 2*Gibibyte {
+			t.Errorf("%s: sizes are wrong: %s, %s", name, l.Cache, l.Upload)
+		}
+
+		if len(l.Parts) != 2 || l.Parts[0] != Kibibyte || l.Parts[1] != 5*Mebibyte {
+			t.Errorf("%s: parts are wrong: %v", name, l.Parts)
+		}
+	}
+
+	err := (&FlagLoader{Args: []string{"-upload", "2 lightyears"}}).Load(&Limits{})
+	if err == nil || !strings.Contains(err.Error(), "multiconfig: field 'Upload': invalid size") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package multiconfig
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// typeCache holds the analysis of the struct types being loaded, such as the
+// default tags of their fields or the names of their environment variables
+// and flags, keyed by the type and the options of the loader analyzing it,
+// or by the name of the field. Loading the same type again, i.e: the config
+// of each tenant of a service, skips the reflection walk over its fields.
+var typeCache sync.Map
+
+// cached returns the value cached for key, computing it with fn on a miss.
+func cached(key interface{}, fn func() interface{}) interface{} {
+	if v, ok := typeCache.Load(key); ok {
+		return v
+	}
+
+	v, _ := typeCache.LoadOrStore(key, fn())
+	return v
+}
+
+// resetTypeCache forgets the analysis of every type. It's called once a
+// decoder is registered, as decoders change which fields are nested.
+func resetTypeCache() {
+	typeCache.Range(func(key, _ interface{}) bool {
+		typeCache.Delete(key)
+		return true
+	})
+}
+
+// structFields returns the fields of the struct type t which are loaded,
+// the ones listed by structs.Fields: the exported fields not tagged with
+// `structs:"-"`.
+func structFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" || field.Tag.Get("structs") == "-" {
+			continue
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields
+}
+
+// isNestedType reports whether the fields of a field of type t are loaded
+// one by one: t is a struct, or a pointer to a struct which doesn't
+// implement flag.Value, and it isn't set as a whole by an unmarshaler.
+func isNestedType(t reflect.Type) bool {
+	elem := t
+	if t.Kind() == reflect.Ptr {
+		if t.Implements(flagValueType) {
+			return false
+		}
+		elem = t.Elem()
+	}
+
+	return elem.Kind() == reflect.Struct && !isUnmarshalerType(t)
+}
+
+// isFlattened reports whether a field of type t, whose structs tag is tag,
+// is a nested struct tagged with `structs:",flatten"`. Its fields are loaded
+// as if they were the fields of the struct holding it: from the keys, the
+// environment variables and the flags of the latter.
+func isFlattened(t reflect.Type, tag string) bool {
+	_, opts, _ := strings.Cut(tag, ",")
+	return isNestedType(t) && hasRule(opts, "flatten")
+}
+
+// reachable reports whether the field at the given index sequence of the
+// struct v is reached without going through a nil pointer.
+func reachable(v reflect.Value, index []int) bool {
+	for _, i := range index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return false
+			}
+			v = v.Elem()
+		}
+
+		v = v.Field(i)
+	}
+
+	return true
+}
@@ -0,0 +1,165 @@
+package multiconfig
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestTypeCacheRepeatedLoads(t *testing.T) {
+	type Postgres struct {
+		Host string `default:"localhost"`
+		Port int    `default:"5432"`
+	}
+
+	type Tenant struct {
+		Name     string
+		Postgres *Postgres
+		Replica  *Postgres
+	}
+
+	env := &EnvironmentLoader{getenv: testEnvironment{
+		"TENANT_NAME":          "acme",
+		"TENANT_REPLICA_PORT":  "6432",
+		"TENANT_POSTGRES_HOST": "db",
+	}.get}
+
+	// the pointers of each tenant differ while their type is the same
+	tenants := []*Tenant{{}, {Postgres: &Postgres{}}, {Replica: &Postgres{Host: "replica"}}}
+	for i, tenant := range tenants {
+		if err := newDefaultLoader(&TagLoader{}, env).Load(tenant); err != nil {
+			t.Fatalf("tenant %d: %s", i, err)
+		}
+	}
+
+	if tenants[0].Postgres == nil || tenants[0].Postgres.Host != "db" || tenants[0].Postgres.Port != 0 {
+		t.Errorf("tenant 0: unexpected postgres: %+v", tenants[0].Postgres)
+	}
+
+	if tenants[1].Postgres.Host != "db" || tenants[1].Postgres.Port != 5432 {
+		t.Errorf("tenant 1: unexpected postgres: %+v", tenants[1].Postgres)
+	}
+
+	if tenants[2].Replica.Host != "localhost" || tenants[2].Replica.Port != 6432 {
+		t.Errorf("tenant 2: unexpected replica: %+v", tenants[2].Replica)
+	}
+
+	for i, tenant := range tenants {
+		if tenant.Name != "acme" {
+			t.Errorf("tenant %d: unexpected name %q", i, tenant.Name)
+		}
+	}
+}
+
+func TestTypeCachePrefixes(t *testing.T) {
+	type Tenant struct {
+		Name     string
+		Postgres Postgres
+	}
+
+	size := func() (n int) {
+		typeCache.Range(func(_, _ interface{}) bool { n++; return true })
+		return n
+	}
+
+	load := func(prefix string) *Tenant {
+		env := &EnvironmentLoader{Prefix: prefix, CamelCase: true, getenv: testEnvironment{
+			"ACME_NAME":              "acme",
+			"ACME_POSTGRES_DB_NAME":  "acmedb",
+			"OTHER_POSTGRES_DB_NAME": "otherdb",
+		}.get}
+
+		tenant := new(Tenant)
+		if err := env.Load(tenant); err != nil {
+			t.Fatal(err)
+		}
+		return tenant
+	}
+
+	load("warmup")
+	before := size()
+
+	// the names of the variables are cached once for every prefix
+	acme, other := load("ACME"), load("OTHER")
+	for i := 0; i < 10; i++ {
+		load("TENANT" + strconv.Itoa(i))
+	}
+
+	if after := size(); after != before {
+		t.Errorf("the cache grew with the prefixes: %d entries, %d before", after, before)
+	}
+
+	if acme.Name != "acme" || acme.Postgres.DBName != "acmedb" || other.Postgres.DBName != "otherdb" {
+		t.Errorf("unexpected tenants: %+v %+v", acme, other)
+	}
+}
+
+func TestTypeCacheRegisterDecoder(t *testing.T) {
+	type Cents struct {
+		Amount int
+	}
+
+	type Product struct {
+		Price Cents
+	}
+
+	env := &EnvironmentLoader{getenv: testEnvironment{
+		"PRODUCT_PRICE":        "250",
+		"PRODUCT_PRICE_AMOUNT": "100",
+	}.get}
+
+	p := new(Product)
+	if err := env.Load(p); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Price.Amount != 100 {
+		t.Errorf("the fields of the struct are not loaded: %+v", p)
+	}
+
+	// registering a decoder sets the struct as a whole from then on
+	typ := reflect.TypeOf(Cents{})
+	RegisterDecoder(typ, func(s string) (interface{}, error) {
+		n, err := strconv.Atoi(s)
+		return Cents{Amount: n}, err
+	})
+	defer RegisterDecoder(typ, nil)
+
+	p = new(Product)
+	if err := env.Load(p); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Price.Amount != 250 {
+		t.Errorf("the struct is not decoded as a whole: %+v", p)
+	}
+}
+
+func TestTypeCacheFlattened(t *testing.T) {
+	e := &EnvironmentLoader{getenv: testEnvironment{
+		"TAGGEDSERVER_NAME": "flat",
+		"TAGGEDSERVER_PORT": "6432",
+	}.get}
+
+	want := []string{
+		"TAGGEDSERVER_AVAILABILITYRATIO",
+		"TAGGEDSERVER_DBNAME",
+		"TAGGEDSERVER_ENABLED",
+		"TAGGEDSERVER_HOSTS",
+		"TAGGEDSERVER_NAME",
+		"TAGGEDSERVER_PORT",
+	}
+
+	if got := e.envNames(&TaggedServer{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected names: %v", got)
+	}
+
+	s := new(TaggedServer)
+	if err := e.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Name != "flat" || s.Postgres.Port != 6432 {
+		t.Errorf("unexpected config: %+v", s)
+	}
+}
@@ -0,0 +1,106 @@
+package multiconfig
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/fatih/structs"
+)
+
+// Discrepancy describes a field whose value differs between two sources.
+type Discrepancy struct {
+	// Field is the path of the field, i.e: "Postgres.Port"
+	Field string
+
+	// A and B hold the values loaded by the first and second loader. Values
+	// of fields tagged with `secret:"true"` are redacted.
+	A, B interface{}
+}
+
+// String returns a human readable form of the discrepancy.
+func (d Discrepancy) String() string {
+	return fmt.Sprintf("%s: %v != %v", d.Field, d.A, d.B)
+}
+
+// CompareSources loads the config defined by the pointer of struct s
+// separately from loaders a and b, without merging them, and reports every
+// field where both sources disagree. s is only used as a template, it's not
+// modified. This is useful to detect drift between sources, i.e: an
+// environment variable that silently diverges from the committed config file.
+func CompareSources(s interface{}, a, b Loader) ([]Discrepancy, error) {
+	typ := reflect.TypeOf(s)
+	if typ == nil || typ.Kind() != reflect.Ptr || typ.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("multiconfig: CompareSources needs a pointer to a struct, got %T", s)
+	}
+
+	sa := reflect.New(typ.Elem()).Interface()
+	if err := a.Load(sa); err != nil {
+		return nil, err
+	}
+
+	sb := reflect.New(typ.Elem()).Interface()
+	if err := b.Load(sb); err != nil {
+		return nil, err
+	}
+
+	return compareFields("", structs.Fields(sa), structs.Fields(sb)), nil
+}
+
+// compareFields compares the given fields of two structs of the same type
+// recursively and returns the fields which have different values. Structs
+// set as a whole, such as time.Time, are compared as single values.
+func compareFields(prefix string, a, b []*structs.Field) []Discrepancy {
+	var discrepancies []Discrepancy
+
+	for i, fa := range a {
+		if !fa.IsExported() {
+			continue
+		}
+
+		fb := b[i]
+		fieldName := prefix + fa.Name()
+
+		if fa.Kind() == reflect.Struct && !isUnmarshaler(fa) {
+			discrepancies = append(discrepancies,
+				compareFields(fieldName+".", fa.Fields(), fb.Fields())...)
+			continue
+		}
+
+		if reflect.DeepEqual(fa.Value(), fb.Value()) {
+			continue
+		}
+
+		discrepancies = append(discrepancies, Discrepancy{
+			Field: fieldName,
+			A:     redact(fa),
+			B:     redact(fb),
+		})
+	}
+
+	return discrepancies
+}
+
+// redacted is the value shown instead of a secret field's value.
+const redacted = "****"
+
+// redact returns the value of the field, or a mask if the field is tagged
+// with `secret:"true"`.
+func redact(field *structs.Field) interface{} {
+	return redactValue(field, field.Value())
+}
+
+// redactValue returns v, a value of the field or of one of its elements, or
+// a mask if the field is tagged with `secret:"true"`.
+func redactValue(field *structs.Field, v interface{}) interface{} {
+	if isSecret(field) {
+		return redacted
+	}
+
+	return v
+}
+
+// isSecret reports whether the field is tagged with `secret:"true"`. The
+// values of secrets are redacted wherever multiconfig prints them.
+func isSecret(field *structs.Field) bool {
+	return field.Tag("secret") == "true"
+}
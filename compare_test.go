@@ -0,0 +1,48 @@
+package multiconfig
+
+import (
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCompareSources(t *testing.T) {
+	type Database struct {
+		Host     string
+		Port     int
+		Password string `secret:"true"`
+	}
+
+	type DriftServer struct {
+		Name     string
+		Database Database
+	}
+
+	os.Setenv("DRIFTSERVER_NAME", "koding")
+	os.Setenv("DRIFTSERVER_DATABASE_PORT", "5433")
+	os.Setenv("DRIFTSERVER_DATABASE_PASSWORD", "env-secret")
+	defer func() {
+		os.Unsetenv("DRIFTSERVER_NAME")
+		os.Unsetenv("DRIFTSERVER_DATABASE_PORT")
+		os.Unsetenv("DRIFTSERVER_DATABASE_PASSWORD")
+	}()
+
+	file := &JSONLoader{Path: "testdata/drift.json"}
+	env := &EnvironmentLoader{}
+
+	got, err := CompareSources(&DriftServer{}, file, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Discrepancy{
+		{Field: "Database.Host", A: "localhost", B: ""},
+		{Field: "Database.Port", A: 5432, B: 5433},
+		{Field: "Database.Password", A: redacted, B: redacted},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+}
@@ -0,0 +1,274 @@
+package multiconfig
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/fatih/structs"
+)
+
+// RangeValidator validates that the value of a field is within the bounds
+// given by its tags:
+//
+//	Port    int           `min:"1" max:"65535"`
+//	Timeout time.Duration `max:"1m"`
+//
+// The bounds are parsed the way the field is, so a time.Duration or a Bytes
+// field takes "1m" or "512MB". The bounds of strings, slices and maps apply to
+// their length, the one of strings being counted in characters.
+type RangeValidator struct {
+	// MinTagName holds the tag name of the lower bound. The default is "min"
+	MinTagName string
+
+	// MaxTagName holds the tag name of the upper bound. The default is "max"
+	MaxTagName string
+}
+
+// Validate validates that the fields of the given struct tagged with
+// MinTagName or MaxTagName are within their bounds. Nil pointers are not
+// validated.
+func (r *RangeValidator) Validate(s interface{}) error {
+	minTag, maxTag := r.MinTagName, r.MaxTagName
+	if minTag == "" {
+		minTag = "min"
+	}
+
+	if maxTag == "" {
+		maxTag = "max"
+	}
+
+	return validateFields(s, func(name string, field *structs.Field, v reflect.Value) error {
+		if tag := field.Tag(minTag); tag != "" {
+			if err := checkBound(name, minTag, tag, v, -1, isSecret(field)); err != nil {
+				return err
+			}
+		}
+
+		if tag := field.Tag(maxTag); tag != "" {
+			if err := checkBound(name, maxTag, tag, v, 1, isSecret(field)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// checkBound checks the value v of the field name against the bound given by
+// tag. sign is -1 for a lower bound and 1 for an upper bound. The value of a
+// secret isn't printed.
+func checkBound(name, tagName, tag string, v reflect.Value, sign int, secret bool) error {
+	bound := "at least"
+	if sign > 0 {
+		bound = "at most"
+	}
+
+	if n, ok := length(v); ok {
+		limit, err := strconv.Atoi(tag)
+		if err != nil {
+			return fieldErrorf(name, v.Interface(), "has an invalid %s tag: %s", tagName, err)
+		}
+
+		if compare(n, limit) == sign {
+			return fieldErrorf(name, v.Interface(), "must have a length of %s %d, got %d", bound, limit, n)
+		}
+
+		return nil
+	}
+
+	limit := reflect.New(v.Type()).Elem()
+	if err := convertString(limit, tag, "", name); err != nil {
+		return fieldErrorf(name, v.Interface(), "has an invalid %s tag: %s", tagName, err)
+	}
+
+	var c int
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		c = compare(v.Int(), limit.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		c = compare(v.Uint(), limit.Uint())
+	case reflect.Float32, reflect.Float64:
+		c = compare(v.Float(), limit.Float())
+	default:
+		return fieldErrorf(name, v.Interface(), "has unsupported type for the %s tag: %s", tagName, v.Type())
+	}
+
+	if c == sign {
+		got := v.Interface()
+		if secret {
+			got = redacted
+		}
+
+		return fieldErrorf(name, v.Interface(), "must be %s %v, got %v", bound, limit.Interface(), got)
+	}
+
+	return nil
+}
+
+// LenValidator validates that the length of a string, a slice or a map is
+// the one given by its tag, the one of strings being counted in characters:
+//
+//	Key string `len:"32"`
+type LenValidator struct {
+	// TagName holds the validator tag name. The default is "len"
+	TagName string
+}
+
+// Validate validates the length of the fields of the given struct tagged
+// with TagName. Nil pointers are not validated.
+func (l *LenValidator) Validate(s interface{}) error {
+	tagName := l.TagName
+	if tagName == "" {
+		tagName = "len"
+	}
+
+	return validateFields(s, func(name string, field *structs.Field, v reflect.Value) error {
+		tag := field.Tag(tagName)
+		if tag == "" {
+			return nil
+		}
+
+		want, err := strconv.Atoi(tag)
+		if err != nil {
+			return fieldErrorf(name, v.Interface(), "has an invalid %s tag: %s", tagName, err)
+		}
+
+		n, ok := length(v)
+		if !ok {
+			return fieldErrorf(name, v.Interface(), "has unsupported type for the %s tag: %s", tagName, v.Type())
+		}
+
+		if n != want {
+			return fieldErrorf(name, v.Interface(), "must have a length of %d, got %d", want, n)
+		}
+
+		return nil
+	})
+}
+
+// PatternValidator validates that a string matches the regular expression of
+// its tag. The whole string must match, and the elements of a slice of
+// strings are validated one by one:
+//
+//	Region string `pattern:"[a-z]{2}-[a-z]+-[0-9]"`
+type PatternValidator struct {
+	// TagName holds the validator tag name. The default is "pattern"
+	TagName string
+}
+
+// Validate validates that the fields of the given struct tagged with TagName
+// match their pattern. Nil pointers are not validated.
+func (p *PatternValidator) Validate(s interface{}) error {
+	tagName := p.TagName
+	if tagName == "" {
+		tagName = "pattern"
+	}
+
+	return validateFields(s, func(name string, field *structs.Field, v reflect.Value) error {
+		tag := field.Tag(tagName)
+		if tag == "" {
+			return nil
+		}
+
+		re, err := regexp.Compile("^(?:" + tag + ")$")
+		if err != nil {
+			return fieldErrorf(name, v.Interface(), "has an invalid %s tag: %s", tagName, err)
+		}
+
+		values := []reflect.Value{v}
+		if v.Kind() == reflect.Slice {
+			values = values[:0]
+			for i := 0; i < v.Len(); i++ {
+				values = append(values, v.Index(i))
+			}
+		}
+
+		for _, value := range values {
+			if value.Kind() != reflect.String {
+				return fieldErrorf(name, v.Interface(), "has unsupported type for the %s tag: %s", tagName, v.Type())
+			}
+
+			if !re.MatchString(value.String()) {
+				return fieldErrorf(name, v.Interface(), "must match the pattern '%s', got '%v'", tag, redactValue(field, value.String()))
+			}
+		}
+
+		return nil
+	})
+}
+
+// validateFields calls fn with the path, the field and the value of each
+// field of the struct s, nested structs being walked field by field, and
+// returns the errors of all the calls. The value of a non-nil pointer is the
+// value it points to, and nil pointers are skipped. The value of the errors
+// of secrets is redacted.
+func validateFields(s interface{}, fn func(name string, field *structs.Field, v reflect.Value) error) error {
+	var errs ValidationErrors
+
+	var walk func(prefix string, fields []*structs.Field)
+	walk = func(prefix string, fields []*structs.Field) {
+		for _, field := range fields {
+			if !field.IsExported() {
+				continue
+			}
+
+			name := prefix + field.Name()
+			switch {
+			case isStructPtr(field) && !isUnmarshaler(field):
+				if !field.IsZero() {
+					walk(name+".", field.Fields())
+				}
+			case field.Kind() == reflect.Struct && !isUnmarshaler(field):
+				walk(name+".", field.Fields())
+			default:
+				v := reflect.ValueOf(field.Value())
+				if v.Kind() == reflect.Ptr {
+					if v.IsNil() {
+						continue
+					}
+
+					v = v.Elem()
+				}
+
+				err := fn(name, field, v)
+
+				var fe *FieldError
+				if isSecret(field) && errors.As(err, &fe) {
+					fe.Value = redacted
+				}
+
+				errs.add(err)
+			}
+		}
+	}
+
+	walk("", structs.Fields(s))
+	return errs.err()
+}
+
+// length returns the length of v if it's a string, a slice or a map.
+func length(v reflect.Value) (int, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return utf8.RuneCountInString(v.String()), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len(), true
+	}
+
+	return 0, false
+}
+
+// compare returns -1, 0 or 1 if a is less than, equal to or greater than b.
+func compare[T int | int64 | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,142 @@
+package multiconfig
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Listener struct {
+	Port    int           `min:"1" max:"65535"`
+	Ratio   float64       `max:"1"`
+	Timeout time.Duration `min:"1s" max:"1m"`
+	Buffer  Bytes         `max:"1MiB"`
+	Backlog *uint         `min:"16"`
+	Name    string        `min:"3" max:"8" pattern:"[a-z]+"`
+	Key     string        `len:"4"`
+	Hosts   []string      `min:"1" pattern:"[a-z.]+"`
+	TLS     *TLS
+}
+
+type TLS struct {
+	Versions map[string]bool `len:"2"`
+}
+
+func validListener() *Listener {
+	return &Listener{
+		Port:    443,
+		Ratio:   0.5,
+		Timeout: 30 * time.Second,
+		Buffer:  512 * Kibibyte,
+		Name:    "edge",
+		Key:     "clé!",
+		Hosts:   []string{"example.com"},
+		TLS:     &TLS{Versions: map[string]bool{"1.2": true, "1.3": true}},
+	}
+}
+
+func TestConstraintValidators(t *testing.T) {
+	validator := MultiValidator(&RangeValidator{}, &LenValidator{}, &PatternValidator{})
+
+	if err := validator.Validate(validListener()); err != nil {
+		t.Fatal(err)
+	}
+
+	var backlog uint = 8
+
+	tests := map[string]func(l *Listener){
+		"multiconfig: field 'Port' must be at least 1, got 0":                    func(l *Listener) { l.Port = 0 },
+		"multiconfig: field 'Port' must be at most 65535, got 70000":             func(l *Listener) { l.Port = 70000 },
+		"multiconfig: field 'Ratio' must be at most 1, got 1.5":                  func(l *Listener) { l.Ratio = 1.5 },
+		"multiconfig: field 'Timeout' must be at most 1m0s, got 2m0s":            func(l *Listener) { l.Timeout = 2 * time.Minute },
+		"multiconfig: field 'Buffer' must be at most 1MiB, got 2MiB":             func(l *Listener) { l.Buffer = 2 * Mebibyte },
+		"multiconfig: field 'Backlog' must be at least 16, got 8":                func(l *Listener) { l.Backlog = &backlog },
+		"multiconfig: field 'Name' must have a length of at most 8, got 9":       func(l *Listener) { l.Name = "edgeproxy" },
+		"multiconfig: field 'Name' must match the pattern '[a-z]+', got 'Edge'":  func(l *Listener) { l.Name = "Edge" },
+		"multiconfig: field 'Key' must have a length of 4, got 3":                func(l *Listener) { l.Key = "abc" },
+		"multiconfig: field 'Hosts' must have a length of at least 1, got 0":     func(l *Listener) { l.Hosts = nil },
+		"multiconfig: field 'Hosts' must match the pattern '[a-z.]+', got 'a_b'": func(l *Listener) { l.Hosts = append(l.Hosts, "a_b") },
+		"multiconfig: field 'TLS.Versions' must have a length of 2, got 1":       func(l *Listener) { delete(l.TLS.Versions, "1.2") },
+	}
+
+	for want, change := range tests {
+		l := validListener()
+		change(l)
+
+		err := validator.Validate(l)
+		if err == nil || err.Error() != want {
+			t.Errorf("expected %q, got: %v", want, err)
+		}
+	}
+
+	// nil pointers are not provided
+	l := validListener()
+	l.TLS = nil
+	if err := validator.Validate(l); err != nil {
+		t.Errorf("nil pointer validated: %s", err)
+	}
+}
+
+func TestConstraintValidatorsInvalidTag(t *testing.T) {
+	type Invalid struct {
+		Port    int     `min:"one"`
+		Enabled bool    `max:"1"`
+		Name    string  `pattern:"[a-"`
+		Ratio   float64 `len:"2"`
+	}
+
+	tests := map[string]Validator{
+		"multiconfig: field 'Port' has an invalid min tag":                  &RangeValidator{},
+		"multiconfig: field 'Enabled' has unsupported type for the max tag": &RangeValidator{MinTagName: "-"},
+		"multiconfig: field 'Name' has an invalid pattern tag":              &PatternValidator{},
+		"multiconfig: field 'Ratio' has unsupported type for the len tag":   &LenValidator{},
+	}
+
+	for want, validator := range tests {
+		err := validator.Validate(&Invalid{})
+		if err == nil || !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("expected %q, got: %v", want, err)
+		}
+	}
+}
+
+func TestConstraintValidatorsDefaultLoader(t *testing.T) {
+	type Limited struct {
+		Port int `default:"80" max:"1024"`
+	}
+
+	m := newDefaultLoader(&TagLoader{}, &FlagLoader{Args: []string{"-port", "8080"}})
+
+	_, err := LoadWith[Limited](m)
+	if err == nil || err.Error() != "multiconfig: field 'Port' must be at most 1024, got 8080" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestConstraintValidatorsSecrets(t *testing.T) {
+	type Credentials struct {
+		Key  string `pattern:"[a-f0-9]+" secret:"true"`
+		PIN  int    `max:"9999" secret:"true"`
+		Mode string `oneof:"read,write" secret:"true"`
+	}
+
+	s := &Credentials{Key: "s3cr3t", PIN: 123456, Mode: "admin"}
+	err := newDefaultLoader().Validate(s)
+	if err == nil {
+		t.Fatal("validating invalid secrets should fail")
+	}
+
+	for _, value := range []string{"s3cr3t", "123456", "admin"} {
+		if strings.Contains(err.Error(), value) {
+			t.Errorf("the secret %s is printed: %s", value, err)
+		}
+	}
+
+	for _, fe := range err.(ValidationErrors) {
+		var e *FieldError
+		if errors.As(fe, &e) && e.Value != redacted {
+			t.Errorf("%s: the value of the error is not redacted: %v", e.Path, e.Value)
+		}
+	}
+}
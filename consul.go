@@ -0,0 +1,135 @@
+package multiconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultConsulAddress is the address of the Consul agent used if neither the
+// Address of a ConsulLoader nor CONSUL_HTTP_ADDR are set.
+const DefaultConsulAddress = "http://127.0.0.1:8500"
+
+// ConsulLoader satisfies the loader interface. It loads the configuration
+// from the keys of the Consul KV store starting with Prefix. The rest of each
+// key is split on "/" into nested fields, so with a prefix of
+// "myapp/config/" the key "myapp/config/postgres/port" sets Postgres.Port.
+// Values are converted the same way environment variables are.
+type ConsulLoader struct {
+	// Prefix is the prefix of the keys, i.e: "myapp/config/".
+	Prefix string
+
+	// Address is the address of the Consul agent. If empty,
+	// CONSUL_HTTP_ADDR is used, then DefaultConsulAddress.
+	Address string
+
+	// Token is the ACL token. If empty, CONSUL_HTTP_TOKEN is used.
+	Token string
+
+	// Datacenter is the datacenter to read from. The default is the one of
+	// the agent.
+	Datacenter string
+
+	// Client is used to perform the requests. If nil, http.DefaultClient is
+	// used.
+	Client *http.Client
+
+	// Strict rejects the keys which don't match any field instead of
+	// ignoring them.
+	Strict bool
+
+	// Watch makes DefaultLoader.Watch poll the keys for changes, every
+	// RemoteWatchInterval.
+	Watch bool
+}
+
+// Load loads the source into the config defined by struct s
+func (c *ConsulLoader) Load(s interface{}) error {
+	return c.LoadContext(context.Background(), s)
+}
+
+// LoadContext is like Load but the request is bound to the given context, so
+// it honors its cancellation and deadline.
+func (c *ConsulLoader) LoadContext(ctx context.Context, s interface{}) error {
+	pairs, err := c.pairs(ctx)
+	if err != nil {
+		return err
+	}
+
+	return decodeKV("consul "+c.Prefix, c.Prefix, "/", pairs, s, c.Strict)
+}
+
+// revision returns the checksum of the keys, for Watch.
+func (c *ConsulLoader) revision(ctx context.Context) ([]byte, error) {
+	pairs, err := c.pairs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return kvSum(pairs), nil
+}
+
+// pairs returns the keys starting with the prefix and their values.
+func (c *ConsulLoader) pairs(ctx context.Context) (map[string]string, error) {
+	if c.Prefix == "" {
+		return nil, ErrSourceNotSet
+	}
+
+	addr := envOr(c.Address, "CONSUL_HTTP_ADDR")
+	if addr == "" {
+		addr = DefaultConsulAddress
+	}
+
+	query := url.Values{"recurse": {"true"}}
+	if c.Datacenter != "" {
+		query.Set("dc", c.Datacenter)
+	}
+
+	u := withScheme(addr) + "/v1/kv/" + strings.TrimPrefix(c.Prefix, "/") + "?" + query.Encode()
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if token := envOr(c.Token, "CONSUL_HTTP_TOKEN"); token != "" {
+		req.Header.Set("X-Consul-Token", token)
+	}
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// no key starts with the prefix
+	if resp.StatusCode == http.StatusNotFound {
+		return map[string]string{}, nil
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("multiconfig: consul: GET %s: unexpected status %s", u, resp.Status)
+	}
+
+	var kvs []struct {
+		Key   string
+		Value []byte
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&kvs); err != nil {
+		return nil, fmt.Errorf("multiconfig: consul: GET %s: %s", u, err)
+	}
+
+	pairs := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		pairs[kv.Key] = string(kv.Value)
+	}
+
+	return pairs, nil
+}
@@ -0,0 +1,150 @@
+package multiconfig
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeConsul serves the recursive reads of the Consul KV store from kvs.
+type fakeConsul struct {
+	sync.Mutex
+	kvs map[string]string
+}
+
+func (f *fakeConsul) set(key, value string) {
+	f.Lock()
+	defer f.Unlock()
+	f.kvs[key] = value
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if r.Header.Get("X-Consul-Token") != "token" {
+		http.Error(w, "ACL not found", http.StatusForbidden)
+		return
+	}
+
+	if !strings.HasPrefix(r.URL.Path, "/v1/kv/") || r.URL.Query().Get("recurse") != "true" {
+		http.NotFound(w, r)
+		return
+	}
+	prefix := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+
+	type kv struct {
+		Key   string
+		Value []byte
+	}
+	var kvs []kv
+	for key, val := range f.kvs {
+		if strings.HasPrefix(key, prefix) {
+			kvs = append(kvs, kv{key, []byte(val)})
+		}
+	}
+
+	if len(kvs) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+
+	json.NewEncoder(w).Encode(kvs)
+}
+
+func newFakeConsul() *fakeConsul {
+	return &fakeConsul{kvs: map[string]string{
+		"myapp/config/":                           "",
+		"myapp/config/name":                       "koding",
+		"myapp/config/port":                       "6060",
+		"myapp/config/enabled":                    "true",
+		"myapp/config/id":                         "1234567890",
+		"myapp/config/labels":                     "123,456",
+		"myapp/config/users":                      "ankara,istanbul",
+		"myapp/config/interval":                   "10s",
+		"myapp/config/postgres/":                  "",
+		"myapp/config/postgres/enabled":           "true",
+		"myapp/config/postgres/port":              "5432",
+		"myapp/config/postgres/hosts":             "192.168.2.1,192.168.2.2,192.168.2.3",
+		"myapp/config/postgres/dbname":            "configdb",
+		"myapp/config/postgres/availabilityratio": "8.23",
+	}}
+}
+
+func TestConsulLoader(t *testing.T) {
+	srv := httptest.NewServer(newFakeConsul())
+	defer srv.Close()
+
+	s := &Server{}
+	l := &ConsulLoader{Prefix: "myapp/config/", Address: srv.URL, Token: "token"}
+	if err := l.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	testStruct(t, s, getDefaultServer())
+
+	// a prefix without keys loads nothing
+	s = &Server{}
+	l.Prefix = "missing/"
+	if err := l.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Name != "" {
+		t.Errorf("nothing should be loaded, got name %q", s.Name)
+	}
+
+	l.Token = "wrong"
+	if err := l.Load(&Server{}); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected a permission error, got: %v", err)
+	}
+}
+
+func TestConsulLoaderWatch(t *testing.T) {
+	consul := newFakeConsul()
+	srv := httptest.NewServer(consul)
+	defer srv.Close()
+
+	m := newDefaultLoader(&TagLoader{}, &ConsulLoader{
+		Prefix:  "myapp/config/",
+		Address: srv.URL,
+		Token:   "token",
+		Watch:   true,
+	})
+	m.RemoteWatchInterval = 10 * time.Millisecond
+
+	s := new(Server)
+	m.MustLoad(s)
+
+	changes := make(chan *Server, 1)
+	stop, err := m.Watch(s, func(old, new interface{}) {
+		changes <- new.(*Server)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	consul.set("myapp/config/postgres/port", "6432")
+
+	select {
+	case c := <-changes:
+		if c.Postgres.Port != 6432 || c.Name != "koding" {
+			t.Errorf("reloaded config is wrong: %+v", c)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("config change not notified")
+	}
+
+	// without the Watch option, there is nothing to watch
+	m = newDefaultLoader(&ConsulLoader{Prefix: "myapp/config/", Address: srv.URL, Token: "token"})
+	if _, err := m.Watch(s, func(old, new interface{}) {}); err != ErrNothingToWatch {
+		t.Errorf("expected ErrNothingToWatch, got: %v", err)
+	}
+}
@@ -0,0 +1,729 @@
+package multiconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+	tomlUnmarshalerType = reflect.TypeOf((*toml.Unmarshaler)(nil)).Elem()
+)
+
+// decodeOptions holds the options of the file loaders applied when
+// decoding a source.
+type decodeOptions struct {
+	// source is the name of the source reported for the loaded fields
+	source string
+
+	// truncateNumbers stores floats with a fractional part into integer
+	// fields without their fractional part
+	truncateNumbers bool
+
+	// strict rejects the keys which don't match any field
+	strict bool
+
+	// expandEnv expands the environment variables referenced by the string
+	// values
+	expandEnv bool
+
+	// fileRefs replaces the string values referencing a file by its content
+	fileRefs bool
+
+	// supportedVersion is the range of schema versions accepted, if set
+	supportedVersion *VersionRange
+
+	// fsys and path are the file system and the path of the source, if it's
+	// a file, used to resolve the files it includes
+	fsys fs.FS
+	path string
+
+	// including are the paths of the files including the source
+	including []string
+
+	// nameTag is the tag naming the fields whose format tag doesn't
+	nameTag string
+}
+
+// decodeSource decodes data of the given format into the struct pointed by
+// s, or into the slice pointed by s for a YAML file holding a top-level
+// sequence.
+func decodeSource(format string, data []byte, s interface{}, opts decodeOptions) error {
+	docs, err := decodeDocuments(format, data)
+	if err != nil {
+		return &DecodeError{Format: format, Source: opts.source, Err: err}
+	}
+
+	// the documents of a YAML file are merged in order, as files are
+	for _, doc := range docs {
+		if list, ok := doc.([]interface{}); ok {
+			err = decodeSequence(format, list, s, opts)
+		} else {
+			err = decodeTree(format, doc.(map[string]interface{}), s, opts)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decodeSequence decodes a top-level sequence of a YAML document into the
+// slice pointed by s, i.e: a []AppServer.
+func decodeSequence(format string, list []interface{}, s interface{}, opts decodeOptions) error {
+	v := reflect.ValueOf(s)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("multiconfig: cannot load the top-level sequence of %s into %T, a pointer to a slice is required", opts.source, s)
+	}
+
+	var raw interface{} = list
+	var err error
+	if opts.expandEnv {
+		if raw, err = expandValue(opts.source, "", raw, expandEnv); err != nil {
+			return err
+		}
+	}
+
+	d := &decoder{
+		format:          format,
+		target:          s,
+		source:          opts.source,
+		tracking:        isTracking(s),
+		truncateNumbers: opts.truncateNumbers,
+		strict:          opts.strict,
+		fileRefs:        opts.fileRefs,
+		nameTag:         opts.nameTag,
+	}
+
+	if err := d.value("", raw, v.Elem()); err != nil {
+		return withLoader(err, d.source)
+	}
+
+	return d.unknownKeys()
+}
+
+// decodeTree decodes the key tree raw of a source into the struct pointed
+// by s.
+func decodeTree(format string, raw map[string]interface{}, s interface{}, opts decodeOptions) error {
+	if t := reflect.TypeOf(s); t != nil && t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Slice {
+		return fmt.Errorf("multiconfig: cannot load %s into %T, a top-level sequence is required", opts.source, s)
+	}
+
+	if opts.expandEnv {
+		if err := expandTree(opts.source, raw, expandEnv); err != nil {
+			return err
+		}
+	}
+
+	if opts.supportedVersion != nil {
+		if err := opts.supportedVersion.check(opts.source, raw); err != nil {
+			return err
+		}
+	}
+
+	if err := loadIncludes(format, raw, s, opts); err != nil {
+		return err
+	}
+
+	d := &decoder{
+		format:          format,
+		target:          s,
+		source:          opts.source,
+		tracking:        isTracking(s),
+		truncateNumbers: opts.truncateNumbers,
+		strict:          opts.strict,
+		fileRefs:        opts.fileRefs,
+		nameTag:         opts.nameTag,
+	}
+
+	if err := d.decode(raw); err != nil {
+		return withLoader(err, d.source)
+	}
+
+	return d.unknownKeys()
+}
+
+// decoder decodes a generic key tree, as returned by decodeRaw, into a
+// struct. Decoding the key tree instead of handing the data to the decoder of
+// each format makes every format behave the same: keys are matched to the
+// fields case insensitively, scalar values given as strings are converted the
+// same way environment variables and flags are, and the keys which don't
+// match any field are known.
+type decoder struct {
+	// format is the format of the decoded data. Its name is also the name of
+	// the tag used to rename fields, i.e: `json:"db_name"`.
+	format string
+
+	// target is the pointer of struct being loaded
+	target interface{}
+
+	// source is the name of the source reported for the loaded fields
+	source string
+
+	// tracking is true if the loaded fields of target are tracked
+	tracking bool
+
+	// truncateNumbers stores floats with a fractional part into integer
+	// fields without their fractional part instead of rejecting them
+	truncateNumbers bool
+
+	// layout is the layout of the time.Time values of the decoded field,
+	// given by its layout tag
+	layout string
+
+	// fileRefs replaces the string values referencing a file by its content
+	fileRefs bool
+
+	// strict collects the keys which don't match any field into unknown
+	strict  bool
+	unknown []string
+
+	// nameTag is the tag naming the fields whose format tag doesn't, i.e:
+	// "json" for the fields of a TOML file
+	nameTag string
+}
+
+// nameTags returns the tags naming the fields besides the tag of the format.
+func (d *decoder) nameTags() []string {
+	if d.nameTag == "" {
+		return nil
+	}
+
+	return []string{d.nameTag}
+}
+
+// unknownKeys returns the error listing the keys which didn't match any
+// field, if any.
+func (d *decoder) unknownKeys() error {
+	if len(d.unknown) == 0 {
+		return nil
+	}
+
+	sort.Strings(d.unknown)
+	return fmt.Errorf("multiconfig: unknown keys in %s: %s", d.source, strings.Join(d.unknown, ", "))
+}
+
+func (d *decoder) decode(raw map[string]interface{}) error {
+	v := reflect.ValueOf(d.target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("multiconfig: cannot load into %T, a non-nil pointer is required", d.target)
+	}
+
+	return d.value("", raw, v.Elem())
+}
+
+// value decodes data into v. path is the path of v within the target, used
+// in error messages.
+func (d *decoder) value(path string, data interface{}, v reflect.Value) error {
+	if data == nil {
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+			v.Set(reflect.Zero(v.Type()))
+		}
+
+		return nil
+	}
+
+	if s, ok := data.(string); ok && d.fileRefs && strings.HasPrefix(s, fileRefPrefix) {
+		// the file of a []byte field, such as a TLS certificate, is read as
+		// is, without removing its trailing newline
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			b, err := ioutil.ReadFile(strings.TrimPrefix(s, fileRefPrefix))
+			if err != nil {
+				return fieldErr(path, s, err)
+			}
+
+			v.SetBytes(b)
+			return nil
+		}
+
+		content, err := expandFileRef(s)
+		if err != nil {
+			return fieldErr(path, s, err)
+		}
+		data = content
+	}
+
+	// the values held by an interface are kept as decoded, their file
+	// references are replaced beforehand
+	if _, isString := data.(string); d.fileRefs && !isString && v.Kind() == reflect.Interface {
+		var err error
+		if data, err = expandValue(d.source, path, data, expandFileRef); err != nil {
+			return err
+		}
+	}
+
+	if s, ok := data.(string); ok && d.layout != "" && isTimeType(v.Type()) && v.Kind() != reflect.Slice {
+		return setTime(v, s, "", d.layout, path)
+	}
+
+	if text, ok := scalarText(data); ok {
+		if ok, err := decodeHook(v, text); ok {
+			if err != nil {
+				return fieldErr(path, text, err)
+			}
+
+			return nil
+		}
+	}
+
+	if ok, err := d.unmarshaler(path, data, v); ok {
+		return err
+	}
+
+	// a key tree is merged into the key tree already held by an interface,
+	// i.e: the sections of a map[string]interface{} loaded from each file
+	if m, ok := data.(map[string]interface{}); ok && v.Kind() == reflect.Interface && !v.IsNil() {
+		if existing, ok := v.Interface().(map[string]interface{}); ok {
+			return d.mapValue(path, m, reflect.ValueOf(existing))
+		}
+	}
+
+	dv := reflect.ValueOf(data)
+	if dv.Type().AssignableTo(v.Type()) && v.Kind() != reflect.Map && v.Kind() != reflect.Slice {
+		v.Set(dv)
+		return nil
+	}
+
+	if s, ok := data.(string); ok && isScalar(v) {
+		return setString(v, s, "", path)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		return d.value(path, data, v.Elem())
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return d.typeError(path, data, v)
+		}
+
+		v.Set(dv)
+	case reflect.Struct:
+		m, ok := data.(map[string]interface{})
+		if !ok {
+			return d.typeError(path, data, v)
+		}
+
+		return d.structValue(path, m, v)
+	case reflect.Map:
+		m, ok := data.(map[string]interface{})
+		if !ok {
+			return d.typeError(path, data, v)
+		}
+
+		return d.mapValue(path, m, v)
+	case reflect.Slice, reflect.Array:
+		return d.sliceValue(path, data, v)
+	case reflect.Bool:
+		b, ok := data.(bool)
+		if !ok {
+			return d.typeError(path, data, v)
+		}
+
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := d.toInt(path, data, v)
+		if err != nil {
+			return err
+		}
+
+		if v.OverflowInt(i) {
+			return d.typeError(path, data, v)
+		}
+
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if u, ok := data.(uint64); ok && !v.OverflowUint(u) {
+			v.SetUint(u)
+			return nil
+		}
+
+		i, err := d.toInt(path, data, v)
+		if err != nil {
+			return err
+		}
+
+		if i < 0 || v.OverflowUint(uint64(i)) {
+			return d.typeError(path, data, v)
+		}
+
+		v.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		f, err := toFloat(data)
+		if err != nil || v.OverflowFloat(f) {
+			return d.typeError(path, data, v)
+		}
+
+		v.SetFloat(f)
+	default:
+		return d.typeError(path, data, v)
+	}
+
+	return nil
+}
+
+// unmarshaler decodes data with the unmarshaler implemented by the type of
+// v, if any: the unmarshaler of the format, then ConfigUnmarshaler and
+// encoding.TextUnmarshaler for scalar values. It reports whether v
+// implements one.
+func (d *decoder) unmarshaler(path string, data interface{}, v reflect.Value) (bool, error) {
+	if !v.CanAddr() {
+		return false, nil
+	}
+
+	if ok, err := d.formatUnmarshaler(data, v); ok {
+		if err != nil {
+			return true, fieldErr(path, data, err)
+		}
+
+		return true, nil
+	}
+
+	text, ok := scalarText(data)
+	if !ok {
+		return false, nil
+	}
+
+	if ok, err := unmarshalText(v, []byte(text)); ok {
+		if err != nil {
+			return true, fieldErr(path, text, err)
+		}
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// formatUnmarshaler decodes data with the unmarshaler of the format
+// implemented by the type of v, if any.
+func (d *decoder) formatUnmarshaler(data interface{}, v reflect.Value) (bool, error) {
+	// allocate nil pointers so their unmarshaler can be called
+	if v.Kind() == reflect.Ptr && v.IsNil() && v.Type().Elem().Kind() != reflect.Ptr {
+		if !implementsAny(v.Type(), d.format) {
+			return false, nil
+		}
+
+		v.Set(reflect.New(v.Type().Elem()))
+	}
+
+	u := v.Addr()
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		u = v
+	}
+
+	var err error
+	switch t := u.Type(); {
+	case d.format == "toml" && t.Implements(tomlUnmarshalerType):
+		err = u.Interface().(toml.Unmarshaler).UnmarshalTOML(data)
+	case d.format == "json" && t.Implements(jsonUnmarshalerType):
+		var b []byte
+		if b, err = json.Marshal(data); err == nil {
+			err = u.Interface().(json.Unmarshaler).UnmarshalJSON(b)
+		}
+	case d.format == "yaml" && t.Implements(yamlUnmarshalerType):
+		var b []byte
+		if b, err = yaml.Marshal(data); err == nil {
+			err = u.Interface().(yaml.Unmarshaler).UnmarshalYAML(func(out interface{}) error {
+				return yaml.Unmarshal(b, out)
+			})
+		}
+	default:
+		return false, nil
+	}
+
+	return true, err
+}
+
+func implementsAny(t reflect.Type, format string) bool {
+	switch {
+	case format == "toml" && t.Implements(tomlUnmarshalerType),
+		format == "json" && t.Implements(jsonUnmarshalerType),
+		format == "yaml" && t.Implements(yamlUnmarshalerType):
+		return true
+	}
+
+	return false
+}
+
+// scalarText returns the text of a decoded scalar value, as handed to
+// ConfigUnmarshaler and encoding.TextUnmarshaler.
+func scalarText(data interface{}) (string, bool) {
+	switch data.(type) {
+	case string, bool, int, int64, uint64, float64, json.Number:
+		return fmt.Sprint(data), true
+	}
+
+	return "", false
+}
+
+func (d *decoder) structValue(path string, data map[string]interface{}, v reflect.Value) error {
+	fields := keyFields(v.Type(), d.format, d.nameTags()...)
+
+	var rest reflect.Value
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Tag.Get("config") == restTag && field.Type == restType {
+			rest = v.Field(i)
+		}
+	}
+
+	for key, val := range data {
+		index, ok := fields[strings.ToLower(key)]
+		if !ok {
+			if rest.IsValid() && rest.CanSet() {
+				if rest.IsNil() {
+					rest.Set(reflect.MakeMap(rest.Type()))
+				}
+
+				rest.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(val))
+			} else if d.strict && !(path == "" && strings.EqualFold(key, SchemaVersionKey)) {
+				d.unknown = append(d.unknown, didYouMean(strings.TrimPrefix(path+".", "."), key, keyNames(v.Type(), d.format, d.nameTags(), fields)))
+			}
+			continue
+		}
+
+		field := fieldByIndex(v, index)
+		fieldPath := joinPath(path, strings.Join(indexNames(v.Type(), index), "."))
+
+		strategy, err := mergeStrategy(v.Type().FieldByIndex(index).Tag.Get(mergeTag), fieldPath)
+		if err != nil {
+			return err
+		}
+
+		old := reflect.ValueOf(field.Interface())
+		if strategy == mergeReplace && field.Kind() == reflect.Map {
+			field.Set(reflect.Zero(field.Type()))
+		}
+
+		layout := d.layout
+		d.layout = v.Type().FieldByIndex(index).Tag.Get(layoutTag)
+		err = d.value(fieldPath, val, field)
+		d.layout = layout
+		if err != nil {
+			return err
+		}
+
+		if field.Kind() == reflect.Slice {
+			field.Set(mergeSlices(strategy, old, field))
+		}
+
+		// nested structs report their own fields
+		if _, isMap := val.(map[string]interface{}); d.tracking && !(isMap && field.Kind() == reflect.Struct) {
+			markLoaded(d.target, fieldPath, d.source)
+		}
+	}
+
+	return nil
+}
+
+func (d *decoder) mapValue(path string, data map[string]interface{}, v reflect.Value) error {
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(v.Type()))
+	}
+
+	keyType, elemType := v.Type().Key(), v.Type().Elem()
+
+	for key, val := range data {
+		k := reflect.New(keyType).Elem()
+		if err := setString(k, key, "", path); err != nil {
+			return err
+		}
+
+		// decode into a copy of the existing element, if any, so the
+		// element is merged the same way struct fields are
+		elem := reflect.New(elemType).Elem()
+		if existing := v.MapIndex(k); existing.IsValid() {
+			elem.Set(existing)
+		}
+
+		if err := d.value(joinPath(path, key), val, elem); err != nil {
+			return err
+		}
+
+		v.SetMapIndex(k, elem)
+	}
+
+	return nil
+}
+
+func (d *decoder) sliceValue(path string, data interface{}, v reflect.Value) error {
+	// a string is decoded into a byte slice as is, or base64 encoded for
+	// JSON, as encoding/json does, unless it references a file or is
+	// prefixed with "base64:"
+	if s, ok := data.(string); ok && v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+		b, err := decodeBytes(s, d.format == "json")
+		if err != nil {
+			return fieldErr(path, s, err)
+		}
+
+		v.SetBytes(b)
+		return nil
+	}
+
+	// ini and key/value store values are strings only, a list being
+	// separated by commas as it is for environment variables
+	if s, ok := data.(string); ok && (d.format == "ini" || d.format == "kv") {
+		list := []interface{}{}
+		for _, elem := range splitList(s, "") {
+			list = append(list, elem)
+		}
+		data = list
+	}
+
+	dv := reflect.ValueOf(data)
+	if dv.Kind() != reflect.Slice {
+		return d.typeError(path, data, v)
+	}
+
+	n := dv.Len()
+	list := v
+	if v.Kind() == reflect.Slice {
+		// slices are replaced wholesale, not merged
+		list = reflect.MakeSlice(v.Type(), n, n)
+	} else if n > v.Len() {
+		return fieldErr(path, data, fmt.Errorf("%d values don't fit into %s", n, v.Type()))
+	}
+
+	for i := 0; i < n; i++ {
+		if err := d.value(fmt.Sprintf("%s[%d]", path, i), dv.Index(i).Interface(), list.Index(i)); err != nil {
+			return err
+		}
+	}
+
+	v.Set(list)
+	return nil
+}
+
+func (d *decoder) typeError(path string, data interface{}, v reflect.Value) error {
+	return fieldErr(path, data, fmt.Errorf("cannot load %s value %v into %s", d.format, data, v.Type()))
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex but allocates the nil
+// pointers of embedded structs along the way.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+
+		v = v.Field(x)
+	}
+
+	return v
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+// isScalar reports whether v holds a single value which can be converted
+// from a string, as opposed to a collection or a struct.
+func isScalar(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+// toInt converts a decoded number into an int64 to be stored in v. A float
+// with a fractional part is rejected, or truncated if truncateNumbers is
+// enabled. Whole floats such as 6060.0 are always accepted.
+func (d *decoder) toInt(path string, data interface{}, v reflect.Value) (int64, error) {
+	if i, err := toInt(data); err == nil {
+		return i, nil
+	}
+
+	f, err := toFloat(data)
+	if err != nil || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, d.typeError(path, data, v)
+	}
+
+	if !d.truncateNumbers && f != math.Trunc(f) {
+		return 0, fieldErr(path, data, fmt.Errorf("%v can't be stored in %s without losing its fractional part",
+			data, v.Type()))
+	}
+
+	return int64(f), nil
+}
+
+// toInt converts a decoded or any Go integer into an int64.
+func toInt(data interface{}) (int64, error) {
+	switch n := data.(type) {
+	case int:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case uint64:
+		if n > math.MaxInt64 {
+			return 0, fmt.Errorf("%d overflows int64", n)
+		}
+		return int64(n), nil
+	case json.Number:
+		return n.Int64()
+	}
+
+	switch v := reflect.ValueOf(data); v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return toInt(v.Uint())
+	}
+
+	return 0, fmt.Errorf("%v is not an integer", data)
+}
+
+// toFloat converts a decoded or any Go number into a float64.
+func toFloat(data interface{}) (float64, error) {
+	switch n := data.(type) {
+	case float64:
+		return n, nil
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case uint64:
+		return float64(n), nil
+	case json.Number:
+		return n.Float64()
+	}
+
+	switch v := reflect.ValueOf(data); v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), nil
+	}
+
+	return 0, fmt.Errorf("%v is not a number", data)
+}
@@ -0,0 +1,148 @@
+package multiconfig
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/google/go-cmp/cmp"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// nativeDecode decodes data with the decoder of the format, the way the file
+// loaders decoded their sources before the common decoder.
+func nativeDecode(format string, data []byte, s interface{}) error {
+	switch format {
+	case "toml":
+		_, err := toml.Decode(string(data), s)
+		return err
+	case "json":
+		return json.Unmarshal(data, s)
+	default:
+		return yaml.Unmarshal(data, s)
+	}
+}
+
+func formatLoader(format string, data []byte) Loader {
+	return readerLoader(format, strings.NewReader(string(data)))
+}
+
+// TestDecodeCompatibility checks that the sources the decoders of the formats
+// could load are loaded the same by the common decoder.
+func TestDecodeCompatibility(t *testing.T) {
+	type Tagged struct {
+		DBName  string            `json:"db_name" yaml:"db_name" toml:"db_name"`
+		Ratio   float32           `json:"ratio" yaml:"ratio" toml:"ratio"`
+		Hosts   []string          `json:"hosts" yaml:"hosts" toml:"hosts"`
+		Weights map[string]int    `json:"weights" yaml:"weights" toml:"weights"`
+		Primary *Postgres         `json:"primary" yaml:"primary" toml:"primary"`
+		Limits  [2]uint16         `json:"limits" yaml:"limits" toml:"limits"`
+		Labels  map[string]string `json:"labels" yaml:"labels" toml:"labels"`
+	}
+
+	tests := []struct {
+		format string
+		data   string
+	}{
+		{"toml", "db_name = \"configdb\"\nratio = 0.5\nhosts = [\"a\", \"b\"]\nlimits = [10, 20]\n[weights]\na = 1\nb = 2\n[primary]\nPort = 5432\nHosts = [\"db\"]\n[labels]\nenv = \"prod\"\n"},
+		{"json", `{"db_name": "configdb", "ratio": 0.5, "hosts": ["a", "b"], "limits": [10, 20], "weights": {"a": 1, "b": 2}, "primary": {"Port": 5432, "Hosts": ["db"]}, "labels": {"env": "prod"}}`},
+		{"yaml", "db_name: configdb\nratio: 0.5\nhosts: [a, b]\nlimits: [10, 20]\nweights:\n  a: 1\n  b: 2\nprimary:\n  port: 5432\n  hosts: [db]\nlabels:\n  env: prod\n"},
+	}
+
+	for _, test := range tests {
+		want, got := &Tagged{}, &Tagged{}
+		if err := nativeDecode(test.format, []byte(test.data), want); err != nil {
+			t.Fatalf("%s: %s", test.format, err)
+		}
+
+		if err := formatLoader(test.format, []byte(test.data)).Load(got); err != nil {
+			t.Fatalf("%s: %s", test.format, err)
+		}
+
+		if diff := cmp.Diff(want, got, cmp.AllowUnexported(Postgres{})); diff != "" {
+			t.Errorf("%s: (-native +decoder):\n%s", test.format, diff)
+		}
+	}
+
+	for format, path := range map[string]string{"toml": testTOML, "json": testJSON, "yaml": testYAML} {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want, got := &Server{}, &Server{}
+		if err := nativeDecode(format, data, want); err != nil {
+			t.Fatalf("%s: %s", path, err)
+		}
+
+		if err := formatLoader(format, data).Load(got); err != nil {
+			t.Fatalf("%s: %s", path, err)
+		}
+
+		if diff := cmp.Diff(want, got, cmp.AllowUnexported(Server{}, Postgres{})); diff != "" {
+			t.Errorf("%s: (-native +decoder):\n%s", path, diff)
+		}
+	}
+}
+
+// TestDecodeCompatibilityChanges lists the sources the common decoder loads
+// differently than the decoders of the formats did.
+func TestDecodeCompatibilityChanges(t *testing.T) {
+	tests := []struct {
+		desc   string
+		format string
+		data   string
+		want   *Server
+	}{
+		{
+			desc:   "YAML keys match fields case insensitively",
+			format: "yaml",
+			data:   "Name: koding\nPostgres:\n  DBName: configdb\n",
+			want:   &Server{Name: "koding", Postgres: Postgres{DBName: "configdb"}},
+		},
+		{
+			desc:   "strings are converted like environment variables",
+			format: "json",
+			data:   `{"Port": "6060", "Enabled": "true", "Postgres": {"AvailabilityRatio": "8.23"}}`,
+			want:   &Server{Port: 6060, Enabled: true, Postgres: Postgres{AvailabilityRatio: 8.23}},
+		},
+		{
+			desc:   "durations are parsed from strings",
+			format: "toml",
+			data:   "Interval = \"10s\"\n",
+			want:   &Server{Interval: 10000000000},
+		},
+	}
+
+	for _, test := range tests {
+		native := &Server{}
+		if err := nativeDecode(test.format, []byte(test.data), native); err == nil && cmp.Equal(test.want, native, cmp.AllowUnexported(Server{}, Postgres{})) {
+			t.Errorf("%s: the decoder of the format loads the source the same", test.desc)
+		}
+
+		got := &Server{}
+		if err := formatLoader(test.format, []byte(test.data)).Load(got); err != nil {
+			t.Fatalf("%s: %s", test.desc, err)
+		}
+
+		if diff := cmp.Diff(test.want, got, cmp.AllowUnexported(Server{}, Postgres{})); diff != "" {
+			t.Errorf("%s: (-want +got):\n%s", test.desc, diff)
+		}
+	}
+
+	// the values of JSON numbers captured by a rest field keep their
+	// precision
+	s := &struct {
+		Extra map[string]interface{} `config:",rest"`
+	}{}
+
+	if err := formatLoader("json", []byte(`{"id": 9007199254740993}`)).Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if id, ok := s.Extra["id"].(json.Number); !ok || id.String() != "9007199254740993" {
+		t.Errorf("unexpected rest value: %#v", s.Extra["id"])
+	}
+}
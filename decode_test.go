@@ -0,0 +1,124 @@
+package multiconfig
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+type celsius float64
+
+func (c *celsius) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	d, err := time.ParseDuration(strings.Replace(s, "C", "s", 1))
+	if err != nil {
+		return err
+	}
+
+	*c = celsius(d.Seconds())
+	return nil
+}
+
+func TestDecodeFormats(t *testing.T) {
+	type Base struct {
+		Host string
+	}
+
+	type Decoded struct {
+		Base     `yaml:",inline"`
+		DBName   string `json:"db_name" yaml:"db_name" toml:"db_name"`
+		Timeout  time.Duration
+		Started  time.Time
+		Temp     *celsius
+		Ratio    float32
+		Ignored  string `json:"-" yaml:"-" toml:"-"`
+		Counters [2]uint8
+	}
+
+	loaders := []Loader{
+		&TOMLLoader{Reader: strings.NewReader("Host = \"localhost\"\ndb_name = \"configdb\"\nTimeout = \"5s\"\nStarted = 2020-01-02T03:04:05Z\nRatio = 0.5\nIgnored = \"x\"\nCounters = [1, 2]\n")},
+		&JSONLoader{Reader: strings.NewReader(`{"host": "localhost", "db_name": "configdb", "timeout": "5s", "started": "2020-01-02T03:04:05Z", "temp": "21C", "ratio": 0.5, "ignored": "x", "counters": [1, 2]}`)},
+		&YAMLLoader{Reader: strings.NewReader("host: localhost\ndb_name: configdb\ntimeout: 5s\nstarted: 2020-01-02T03:04:05Z\nratio: 0.5\nignored: x\ncounters: [1, 2]\n")},
+	}
+
+	for _, l := range loaders {
+		s := &Decoded{}
+		if err := l.Load(s); err != nil {
+			t.Fatalf("%T: %s", l, err)
+		}
+
+		if s.Host != "localhost" || s.DBName != "configdb" || s.Timeout != 5*time.Second || s.Ratio != 0.5 {
+			t.Errorf("%T: wrong values: %+v", l, s)
+		}
+
+		if !s.Started.Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)) {
+			t.Errorf("%T: Started value is wrong: %v", l, s.Started)
+		}
+
+		if s.Ignored != "" {
+			t.Errorf("%T: Ignored should not be loaded: %s", l, s.Ignored)
+		}
+
+		if s.Counters != [2]uint8{1, 2} {
+			t.Errorf("%T: Counters value is wrong: %v", l, s.Counters)
+		}
+	}
+}
+
+func TestDecodeUnmarshaler(t *testing.T) {
+	s := &struct{ Temp *celsius }{}
+
+	l := &JSONLoader{Reader: strings.NewReader(`{"Temp": "21C"}`)}
+	if err := l.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Temp == nil || *s.Temp != 21 {
+		t.Errorf("Temp value is wrong: %v", s.Temp)
+	}
+}
+
+func TestDecodeTypeError(t *testing.T) {
+	l := &JSONLoader{Reader: strings.NewReader(`{"Postgres": {"Port": "not a port"}}`)}
+
+	if err := l.Load(&Server{}); err == nil {
+		t.Fatal("Port should not be loaded from a string which is not a number")
+	}
+}
+
+func TestFractionalNumbers(t *testing.T) {
+	s := &Server{}
+	l := &JSONLoader{Reader: strings.NewReader(`{"Port": 6060.0, "Postgres": {"Port": 5432.0}}`)}
+	if err := l.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Port != 6060 || s.Postgres.Port != 5432 {
+		t.Errorf("whole floats should be accepted: %d, %d", s.Port, s.Postgres.Port)
+	}
+
+	l = &JSONLoader{Reader: strings.NewReader(`{"Postgres": {"Port": 5432.5}}`)}
+	err := l.Load(s)
+	if err == nil {
+		t.Fatal("a float with a fractional part should be rejected")
+	}
+
+	errStr := "multiconfig: field 'Postgres.Port': 5432.5 can't be stored in int without losing its fractional part"
+	if err.Error() != errStr {
+		t.Fatalf("Err string is wrong: expected %s, got: %s", errStr, err.Error())
+	}
+
+	y := &YAMLLoader{Reader: strings.NewReader("postgres:\n  port: 5432.5\n"), FileOptions: FileOptions{TruncateNumbers: true}}
+	if err := y.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Postgres.Port != 5432 {
+		t.Errorf("float should be truncated with TruncateNumbers: %d", s.Postgres.Port)
+	}
+}
@@ -0,0 +1,113 @@
+package multiconfig
+
+import (
+	"reflect"
+)
+
+// defaulterSource is the source reported for the fields set by a Defaulter.
+const defaulterSource = "computed default"
+
+// Defaulter is implemented by the config structs, or the structs nested in
+// them, whose defaults are computed rather than given by a default tag,
+// such as the host name or the number of CPUs:
+//
+//	func (s *Server) SetDefaults() {
+//		s.Workers = runtime.NumCPU()
+//	}
+//
+// SetDefaults is called by the DefaultLoader before the loaders run, so any
+// source overrides the values it sets.
+type Defaulter interface {
+	SetDefaults()
+}
+
+// ErrorDefaulter is like Defaulter for the defaults whose computation may
+// fail. The error stops the load.
+type ErrorDefaulter interface {
+	Default() error
+}
+
+// defaultsLoader calls the Defaulter or ErrorDefaulter methods of the struct
+// being loaded and of the structs nested in it. The nested structs are set
+// first, so the struct holding them may override their defaults.
+type defaultsLoader struct{}
+
+func (defaultsLoader) Load(s interface{}) error {
+	v := reflect.ValueOf(s)
+	if isRaw(s) || v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	before := reflect.New(v.Elem().Type()).Elem()
+	before.Set(v.Elem())
+
+	if err := callDefaulters("", v.Elem()); err != nil {
+		return withLoader(err, defaulterSource)
+	}
+
+	changedFields("", before, v.Elem(), func(path string) {
+		markLoaded(s, path, defaulterSource)
+	})
+
+	return nil
+}
+
+// callDefaulters calls the defaulter methods of the nested structs of the
+// struct v, found at path, and then the ones of v.
+func callDefaulters(path string, v reflect.Value) error {
+	for _, field := range structFields(v.Type()) {
+		fv := v.FieldByIndex(field.Index)
+		if fv.Kind() == reflect.Ptr {
+			// a nil pointer is a struct not provided, which has no defaults
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+
+		if fv.Kind() == reflect.Struct && !isUnmarshalerType(fv.Type()) {
+			if err := callDefaulters(joinPath(path, field.Name), fv); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch d := v.Addr().Interface().(type) {
+	case Defaulter:
+		d.SetDefaults()
+	case ErrorDefaulter:
+		if err := d.Default(); err != nil {
+			if path == "" {
+				return err
+			}
+
+			return fieldErr(path, nil, err)
+		}
+	}
+
+	return nil
+}
+
+// changedFields calls fn with the path of each field of the struct after,
+// found at path, whose value differs from the one of the struct before. The
+// fields of nested structs are compared one by one.
+func changedFields(path string, before, after reflect.Value, fn func(path string)) {
+	for _, field := range structFields(after.Type()) {
+		b, a := before.FieldByIndex(field.Index), after.FieldByIndex(field.Index)
+		fieldPath := joinPath(path, field.Name)
+
+		if a.Kind() == reflect.Ptr && !a.IsNil() && !b.IsNil() && a.Pointer() == b.Pointer() {
+			// the copy shares the struct pointed to, nothing to compare
+			continue
+		}
+
+		if a.Kind() == reflect.Struct && !isUnmarshalerType(a.Type()) {
+			changedFields(fieldPath, b, a, fn)
+			continue
+		}
+
+		if !reflect.DeepEqual(b.Interface(), a.Interface()) {
+			fn(fieldPath)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package multiconfig
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type computedDatabase struct {
+	Host string
+	Port int
+}
+
+func (c *computedDatabase) SetDefaults() {
+	c.Host = "localhost"
+	c.Port = 5432
+}
+
+type computedServer struct {
+	Name     string
+	Workers  int
+	Database computedDatabase
+	Replica  *computedDatabase
+}
+
+func (c *computedServer) SetDefaults() {
+	c.Workers = 4
+	// the defaults of the nested structs are set first
+	c.Database.Port++
+}
+
+type failingDefaults struct {
+	Name string
+}
+
+func (f *failingDefaults) Default() error {
+	return errors.New("no hostname")
+}
+
+func TestDefaulter(t *testing.T) {
+	s := &computedServer{}
+
+	m := newDefaultLoader(&EnvironmentLoader{
+		getenv: testEnvironment{"COMPUTEDSERVER_WORKERS": "8"}.get,
+	})
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Workers != 8 {
+		t.Errorf("Workers value is wrong: %d, want: 8", s.Workers)
+	}
+
+	if s.Database.Host != "localhost" || s.Database.Port != 5433 {
+		t.Errorf("Database value is wrong: %+v", s.Database)
+	}
+
+	if s.Replica != nil {
+		t.Errorf("Replica is allocated: %+v", s.Replica)
+	}
+
+	want := []string{"Database.Host", "Database.Port", "Workers"}
+	if got := m.LoadedFields(s); !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded fields are wrong: %v, want: %v", got, want)
+	}
+}
+
+func TestDefaulterPointer(t *testing.T) {
+	s := &computedServer{Replica: &computedDatabase{}}
+
+	m := newDefaultLoader()
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Replica.Host != "localhost" {
+		t.Errorf("Replica value is wrong: %+v", s.Replica)
+	}
+}
+
+func TestErrorDefaulter(t *testing.T) {
+	s := &struct {
+		Server failingDefaults
+	}{}
+
+	err := newDefaultLoader().Load(s)
+
+	var fe *FieldError
+	if !errors.As(err, &fe) {
+		t.Fatalf("error is not a FieldError: %v", err)
+	}
+
+	if fe.Path != "Server" || fe.Loader != defaulterSource {
+		t.Errorf("error is wrong: %+v", fe)
+	}
+
+	if err := newDefaultLoader().Load(&failingDefaults{}); err == nil || err.Error() != "no hostname" {
+		t.Errorf("error is wrong: %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package multiconfig
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+
+	"github.com/fatih/structs"
+)
+
+const (
+	// deprecatedTag marks a field as deprecated, its value telling what to
+	// use instead, i.e: `deprecated:"use Postgres.DSN instead"`.
+	deprecatedTag = "deprecated"
+
+	// replacedByTag names the field a deprecated field is migrated to, i.e:
+	// `replacedBy:"Postgres.DSN"`.
+	replacedByTag = "replacedBy"
+)
+
+// deprecationLoader warns about the deprecated fields set by a source and
+// migrates their value to their replacement. It must be the last loader of a
+// MultiLoader.
+type deprecationLoader struct {
+	logger *log.Logger
+}
+
+// Load checks the deprecated fields of s.
+func (l deprecationLoader) Load(s interface{}) error {
+	if isRaw(s) {
+		return nil
+	}
+
+	return l.processFields(s, "", structs.Fields(s))
+}
+
+func (l deprecationLoader) processFields(s interface{}, prefix string, fields []*structs.Field) error {
+	for _, field := range fields {
+		if !field.IsExported() {
+			continue
+		}
+
+		path := prefix + field.Name()
+
+		switch {
+		case isNestedPtr(field):
+			if !field.IsZero() {
+				if err := l.processFields(s, path+".", field.Fields()); err != nil {
+					return err
+				}
+			}
+		case field.Kind() == reflect.Struct && !isUnmarshaler(field):
+			if err := l.processFields(s, path+".", field.Fields()); err != nil {
+				return err
+			}
+		}
+
+		msg := field.Tag(deprecatedTag)
+		if msg == "" || !setBySource(s, path, field) {
+			continue
+		}
+
+		source := loadedSources(s)[path]
+		if source == "" {
+			source = "unknown source"
+		}
+
+		l.logger.Printf("multiconfig: field '%s' is deprecated: %s (set by %s)", path, msg, source)
+
+		if err := migrate(s, path, field, source); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// migrate copies the value of the deprecated field at path to the field
+// named by its replacedBy tag, unless a source set the replacement too.
+func migrate(s interface{}, path string, field *structs.Field, source string) error {
+	replacement := field.Tag(replacedByTag)
+	if replacement == "" {
+		return nil
+	}
+
+	target, ok := fieldByPath(s, replacement)
+	if !ok {
+		return fmt.Errorf("multiconfig: field '%s' is replaced by unknown field '%s'", path, replacement)
+	}
+
+	if setBySource(s, replacement, target) {
+		trace(s, TraceEvent{
+			Kind:   TraceSkip,
+			Loader: source,
+			Path:   path,
+			Value:  redact(field),
+			Reason: fmt.Sprintf("not migrated to '%s', set by %s", replacement, loadedSources(s)[replacement]),
+		})
+		return nil
+	}
+
+	v := reflect.ValueOf(field.Value())
+	t := reflect.TypeOf(target.Value())
+	if !v.Type().ConvertibleTo(t) {
+		return fmt.Errorf("multiconfig: field '%s' of type %s can't replace field '%s' of type %s",
+			replacement, t, path, v.Type())
+	}
+
+	if err := target.Set(v.Convert(t).Interface()); err != nil {
+		return err
+	}
+
+	markLoaded(s, replacement, source)
+	return nil
+}
+
+// setBySource reports whether the field at path of s was set by a source
+// other than its default tag or a Defaulter during its current load. If the
+// load isn't tracked, a field is considered set if it's not zero.
+func setBySource(s interface{}, path string, field *structs.Field) bool {
+	if _, tracked := loadedField(s, path); !tracked {
+		return !field.IsZero()
+	}
+
+	for p, source := range loadedSources(s) {
+		if (p == path || strings.HasPrefix(p, path+".")) && source != defaultTagSource && source != defaulterSource {
+			return true
+		}
+	}
+
+	return false
+}
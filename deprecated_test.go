@@ -0,0 +1,113 @@
+package multiconfig
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+type LegacyDB struct {
+	Host    string `default:"localhost" deprecated:"use DSN instead" replacedBy:"DSN"`
+	Timeout int    `deprecated:"no longer used"`
+	DSN     string
+}
+
+func TestDeprecatedFields(t *testing.T) {
+	var buf bytes.Buffer
+
+	m := newDefaultLoader(&TagLoader{}, &EnvironmentLoader{getenv: testEnvironment{
+		"LEGACYDB_HOST": "db.example.com",
+	}.get})
+	m.Logger = log.New(&buf, "", 0)
+
+	d := &LegacyDB{}
+	if err := m.Load(d); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "multiconfig: field 'Host' is deprecated: use DSN instead (set by env LEGACYDB_HOST)\n"
+	if buf.String() != want {
+		t.Errorf("unexpected warnings: %q", buf.String())
+	}
+
+	if d.DSN != "db.example.com" {
+		t.Errorf("DSN is %q, want the value of Host", d.DSN)
+	}
+
+	if m.Sources(d)["DSN"] != "env LEGACYDB_HOST" {
+		t.Errorf("DSN source is %q", m.Sources(d)["DSN"])
+	}
+}
+
+func TestDeprecatedFieldsNotSet(t *testing.T) {
+	var buf bytes.Buffer
+
+	// the default tag of a deprecated field doesn't trigger any warning, and
+	// the replacement set by a source isn't overridden
+	m := newDefaultLoader(&TagLoader{}, &EnvironmentLoader{getenv: testEnvironment{
+		"LEGACYDB_DSN": "postgres://db",
+	}.get})
+	m.Logger = log.New(&buf, "", 0)
+
+	d := &LegacyDB{}
+	if err := m.Load(d); err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.Len() != 0 {
+		t.Errorf("unexpected warnings: %q", buf.String())
+	}
+
+	if d.DSN != "postgres://db" {
+		t.Errorf("DSN is %q", d.DSN)
+	}
+
+	err := m.LoadWithOverrides(d, map[string]interface{}{"Timeout": 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), "field 'Timeout' is deprecated: no longer used (set by override)") {
+		t.Errorf("unexpected warnings: %q", buf.String())
+	}
+}
+
+// ComputedDB computes the default of the replacement of a deprecated field.
+type ComputedDB struct {
+	URL string `deprecated:"use DSN instead" replacedBy:"DSN"`
+	DSN string
+}
+
+func (c *ComputedDB) SetDefaults() {
+	c.DSN = "postgres://localhost"
+}
+
+func TestDeprecatedFieldsComputedReplacement(t *testing.T) {
+	// a replacement set by a Defaulter is overridden by the deprecated field
+	m := newDefaultLoader(&JSONLoader{Reader: strings.NewReader(`{"url": "postgres://db"}`)})
+	m.Logger = log.New(&bytes.Buffer{}, "", 0)
+
+	c := &ComputedDB{}
+	if err := m.Load(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.DSN != "postgres://db" {
+		t.Errorf("DSN is %q, want the value of URL", c.DSN)
+	}
+}
+
+func TestDeprecatedFieldsUnknownReplacement(t *testing.T) {
+	type Invalid struct {
+		Host string `deprecated:"use DSN instead" replacedBy:"Postgres.DSN"`
+	}
+
+	m := newDefaultLoader(&EnvironmentLoader{getenv: testEnvironment{"INVALID_HOST": "db"}.get})
+	m.Logger = log.New(&bytes.Buffer{}, "", 0)
+
+	err := m.Load(&Invalid{})
+	if err == nil || err.Error() != "multiconfig: field 'Host' is replaced by unknown field 'Postgres.DSN'" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
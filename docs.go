@@ -0,0 +1,114 @@
+package multiconfig
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/fatih/structs"
+)
+
+// Docs returns the reference documentation of the options of the config
+// struct s as a Markdown table: the path, type, default value, allowed values
+// and description of each field, whether it's required, and its environment
+// variable and flag as named by an EnvironmentLoader and a FlagLoader
+// without options. The description is given by the desc tag, i.e:
+// `desc:"Port to listen on"`, and the allowed values by the oneof tag along
+// with their descriptions given by the oneofDoc tag, see OneOfValidator.
+func Docs(s interface{}) string {
+	flags := map[string]Option{}
+	for _, o := range (&FlagLoader{}).Options(s) {
+		flags[o.Path] = o
+	}
+
+	e := &EnvironmentLoader{}
+
+	var b strings.Builder
+	b.WriteString("| Option | Type | Default | Allowed values | Required | Environment variable | Flag | Description |\n")
+	b.WriteString("|--------|------|---------|----------------|----------|----------------------|------|-------------|\n")
+
+	walkLeaves("", structs.Fields(s), func(path string, field *structs.Field) {
+		def := field.Tag("default")
+		if def != "" {
+			def = "`" + fmt.Sprint(redactValue(field, def)) + "`"
+		}
+
+		var allowed []string
+		if oneof := field.Tag("oneof"); oneof != "" {
+			docs := oneOfDocs(field.Tag("oneofDoc"))
+			for _, value := range strings.Split(oneof, ",") {
+				value = strings.TrimSpace(value)
+				if doc, ok := docs[value]; ok {
+					allowed = append(allowed, "`"+value+"` ("+doc+")")
+				} else {
+					allowed = append(allowed, "`"+value+"`")
+				}
+			}
+		}
+
+		required := ""
+		if field.Tag("required") == "true" {
+			required = "yes"
+		}
+
+		env := e.envNameOf(s, path)
+		if env != "" {
+			env = "`" + env + "`"
+		}
+
+		flag := ""
+		if o, ok := flags[path]; ok {
+			flag = "`-" + o.Flag + "`"
+			if o.Shorthand != "" {
+				flag += ", `-" + o.Shorthand + "`"
+			}
+		}
+
+		cells := []string{
+			"`" + path + "`",
+			"`" + reflect.TypeOf(field.Value()).String() + "`",
+			def,
+			strings.Join(allowed, ", "),
+			required,
+			env,
+			flag,
+			field.Tag(descTag),
+		}
+
+		for i, cell := range cells {
+			cells[i] = markdownCell(cell)
+		}
+
+		b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	})
+
+	return b.String()
+}
+
+// walkLeaves calls fn with each exported field holding a value rather than
+// nested fields, found at its path. The fields of nil pointers to structs
+// are the ones of a zero struct.
+func walkLeaves(prefix string, fields []*structs.Field, fn func(path string, field *structs.Field)) {
+	for _, field := range fields {
+		if !field.IsExported() {
+			continue
+		}
+
+		path := prefix + field.Name()
+
+		switch {
+		case isNestedPtr(field) && field.IsZero():
+			walkLeaves(path+".", zeroStruct(field).Fields(), fn)
+		case isNestedPtr(field), field.Kind() == reflect.Struct && !isUnmarshaler(field):
+			walkLeaves(path+".", field.Fields(), fn)
+		default:
+			fn(path, field)
+		}
+	}
+}
+
+// markdownCell escapes the pipes and the line breaks of a table cell.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
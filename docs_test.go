@@ -0,0 +1,39 @@
+package multiconfig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDocs(t *testing.T) {
+	type Database struct {
+		URL  string `flag:"db-url,d" env:"DATABASE_URL" required:"true" desc:"URL of the database"`
+		Pool int    `default:"10" desc:"Size of the pool | per host"`
+	}
+
+	type App struct {
+		Port     int    `default:"8080"`
+		Scheme   string `oneof:"http,https,h2c" oneofDoc:"http=plaintext,https=TLS"`
+		Secret   string `flag:"-"`
+		Token    string `default:"dev-token" secret:"true"`
+		Database *Database
+	}
+
+	want := "| Option | Type | Default | Allowed values | Required | Environment variable | Flag | Description |\n" +
+		"|--------|------|---------|----------------|----------|----------------------|------|-------------|\n" +
+		"| `Port` | `int` | `8080` |  |  | `APP_PORT` | `-port` |  |\n" +
+		"| `Scheme` | `string` |  | `http` (plaintext), `https` (TLS), `h2c` |  | `APP_SCHEME` | `-scheme` |  |\n" +
+		"| `Secret` | `string` |  |  |  | `APP_SECRET` |  |  |\n" +
+		"| `Token` | `string` | `****` |  |  | `APP_TOKEN` | `-token` |  |\n" +
+		"| `Database.URL` | `string` |  |  | yes | `DATABASE_URL` | `-db-url`, `-d` | URL of the database |\n" +
+		"| `Database.Pool` | `int` | `10` |  |  | `APP_DATABASE_POOL` | `-database-pool` | Size of the pool \\| per host |\n"
+
+	if got := Docs(&App{}); got != want {
+		t.Errorf("docs are wrong:\n%s\nwant:\n%s", got, want)
+	}
+
+	// the fields of a non-nil pointer are documented the same way
+	if got := Docs(&App{Database: &Database{}}); !strings.Contains(got, "`Database.Pool`") {
+		t.Errorf("docs are wrong:\n%s", got)
+	}
+}
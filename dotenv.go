@@ -0,0 +1,159 @@
+package multiconfig
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// DotEnvLoader satisfies the loader interface. It loads the configuration
+// from the given .env file or Reader. Each line of a .env file is in the form
+// of KEY=VALUE, keys are mapped to the struct fields the same way the
+// EnvironmentLoader maps environment variables, i.e: STRUCTNAME_FIELDNAME.
+// Blank lines and lines starting with "#" are ignored, values may be quoted
+// with single or double quotes.
+type DotEnvLoader struct {
+	Path   string
+	Reader io.Reader
+
+	// FS, if set, is the file system Path is read from instead of the
+	// operating system's one, i.e: an embed.FS.
+	FS fs.FS
+
+	// Prefix prepends given string to every key
+	// {STRUCTNAME}_FIELDNAME will be {PREFIX}_FIELDNAME
+	Prefix string
+
+	// CamelCase adds a separator for field names in camelcase form. See
+	// EnvironmentLoader.CamelCase for details.
+	CamelCase bool
+
+	// SliceSeparator separates the elements of slice fields. The default is
+	// ",".
+	SliceSeparator string
+
+	// Strict rejects the keys starting with the prefix which don't match any
+	// field instead of ignoring them.
+	Strict bool
+
+	// NameTag, if set, is the tag whose names replace the names of the
+	// fields in the keys. See EnvironmentLoader.NameTag for details.
+	NameTag string
+}
+
+// Load loads the source into the config defined by struct s.
+// Defaults to using the Reader if provided, otherwise tries to read from the
+// file
+func (d *DotEnvLoader) Load(s interface{}) error {
+	var r io.Reader
+
+	name := "<reader>"
+	if d.Reader != nil {
+		r = d.Reader
+	} else if d.Path != "" {
+		file, err := openConfig(d.FS, d.Path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+		name = d.Path
+	} else {
+		return ErrSourceNotSet
+	}
+
+	vars, err := parseDotEnv(name, r)
+	if err != nil {
+		return err
+	}
+
+	e := &EnvironmentLoader{
+		Prefix:         d.Prefix,
+		CamelCase:      d.CamelCase,
+		SliceSeparator: d.SliceSeparator,
+		Strict:         d.Strict,
+		NameTag:        d.NameTag,
+		getenv:         func(key string) string { return vars[key] },
+		environ: func() []string {
+			environ := make([]string, 0, len(vars))
+			for key, val := range vars {
+				environ = append(environ, key+"="+val)
+			}
+			return environ
+		},
+		source: sourceName(d.Path, ".env"),
+	}
+
+	return e.Load(s)
+}
+
+// parseDotEnv parses the content of a .env file into a map of keys and
+// values. name is only used for error messages.
+func parseDotEnv(name string, r io.Reader) (map[string]string, error) {
+	vars := map[string]string{}
+
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+
+		kv := strings.SplitN(line, "=", 2)
+		key := strings.TrimSpace(kv[0])
+		if len(kv) != 2 || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("multiconfig: %s:%d: malformed line %q", name, lineNum, line)
+		}
+
+		val, err := parseDotEnvValue(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return nil, fmt.Errorf("multiconfig: %s:%d: %s", name, lineNum, err)
+		}
+
+		vars[key] = val
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return vars, nil
+}
+
+// parseDotEnvValue unquotes the given value. Double quoted values support the
+// \n, \t, \" and \\ escape sequences, single quoted values are taken
+// literally. An inline comment is stripped from unquoted values.
+func parseDotEnvValue(val string) (string, error) {
+	if val == "" {
+		return "", nil
+	}
+
+	switch quote := val[0]; quote {
+	case '"', '\'':
+		end := strings.LastIndexByte(val, quote)
+		if end == 0 {
+			return "", fmt.Errorf("unterminated quoted value %s", val)
+		}
+
+		if rest := strings.TrimSpace(val[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+			return "", fmt.Errorf("unexpected characters after quoted value %s", val)
+		}
+
+		val = val[1:end]
+		if quote == '"' {
+			val = strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\"`, `"`, `\\`, `\`).Replace(val)
+		}
+
+		return val, nil
+	}
+
+	if i := strings.Index(val, " #"); i >= 0 {
+		val = strings.TrimSpace(val[:i])
+	}
+
+	return val, nil
+}
@@ -0,0 +1,74 @@
+package multiconfig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDotEnv(t *testing.T) {
+	l := MultiLoader(&TagLoader{}, &DotEnvLoader{Path: testDotEnv})
+
+	s := &Server{}
+	if err := l.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	testStruct(t, s, getDefaultServer())
+}
+
+func TestDotEnvWithPath(t *testing.T) {
+	// real environment variables override the .env file
+	t.Setenv("SERVER_NAME", "env")
+
+	m := NewWithPath(testDotEnv)
+
+	s := new(Server)
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	want := getDefaultServer()
+	want.Name = "env"
+	testStruct(t, s, want)
+}
+
+func TestDotEnvMalformed(t *testing.T) {
+	tests := map[string]string{
+		"SERVER_NAME=koding\nSERVER_PORT\n":         "<reader>:2: malformed line",
+		"# comment\n\nSERVER NAME=koding\n":         "<reader>:3: malformed line",
+		"SERVER_NAME=\"koding\n":                    "<reader>:1: unterminated quoted value",
+		"SERVER_NAME='koding' trailing\n":           "<reader>:1: unexpected characters",
+		"SERVER_NAME=koding\n=value\nSERVER_ID=1\n": "<reader>:2: malformed line",
+	}
+
+	for content, want := range tests {
+		err := (&DotEnvLoader{Reader: strings.NewReader(content)}).Load(&Server{})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected error containing %q, got: %v", content, want, err)
+		}
+	}
+}
+
+func TestParseDotEnvValue(t *testing.T) {
+	tests := map[string]string{
+		``:                  "",
+		`plain`:             "plain",
+		`plain # comment`:   "plain",
+		`"quoted # value"`:  "quoted # value",
+		`"line\nbreak"`:     "line\nbreak",
+		`'single\nquoted'`:  `single\nquoted`,
+		`"escaped \"quote"`: `escaped "quote`,
+	}
+
+	for val, want := range tests {
+		got, err := parseDotEnvValue(val)
+		if err != nil {
+			t.Errorf("%q: %s", val, err)
+			continue
+		}
+
+		if got != want {
+			t.Errorf("%q: got %q, want %q", val, got, want)
+		}
+	}
+}
@@ -0,0 +1,455 @@
+package multiconfig
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DumpDefaults returns a config template of the given struct in the given
+// format: "toml", "json" or "yaml". Every field is filled with the value of
+// its default tag, or its zero value, so the result lists everything that can
+// be configured. The TOML and YAML templates annotate each field with its
+// required and default tags as a trailing comment, JSON doesn't support
+// comments. The description of a field given by its desc tag, i.e:
+// `desc:"Port to listen on"`, is written as a comment above it. Unexported
+// fields are skipped and the fields of embedded structs are rendered at the
+// level of the embedding struct, where the loaders look them up. The defaults
+// of the fields tagged with `secret:"true"` are redacted.
+func DumpDefaults(v interface{}, format string) ([]byte, error) {
+	typ := reflect.TypeOf(v)
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("multiconfig: DumpDefaults needs a struct, got %T", v)
+	}
+
+	s := reflect.New(typ).Interface()
+	if err := (&TagLoader{}).Load(s); err != nil {
+		return nil, err
+	}
+
+	return encodeNodes(format, dumpNodes(reflect.ValueOf(s).Elem(), format, defaultsComment, true))
+}
+
+// GenerateExample returns an example config file of the given struct in the
+// given format, the template of DumpDefaults, so example files are generated
+// rather than maintained by hand and can't drift from the struct:
+//
+//	data, err := multiconfig.GenerateExample(&Server{}, multiconfig.TOML)
+func GenerateExample(s interface{}, format Format) ([]byte, error) {
+	return DumpDefaults(s, string(format))
+}
+
+// Dump writes the effective configuration held by the struct s to w in the
+// given format, TOML, JSON or YAML, i.e: once loaded, with the values of
+// every source merged and the defaults applied. It answers which config a
+// process is actually running with, i.e: behind a -print-config flag:
+//
+//	if printConfig {
+//		multiconfig.Dump(conf, multiconfig.YAML, os.Stdout)
+//	}
+//
+// The values of the fields tagged with `secret:"true"` are redacted, see
+// DumpWithSecrets. The result is laid out like the templates of
+// DumpDefaults, so the loader of the format can read it back.
+func Dump(s interface{}, format Format, w io.Writer) error {
+	return dump("Dump", s, format, w, true)
+}
+
+// DumpWithSecrets is like Dump but writes the values of the secrets as well,
+// i.e: to copy the effective configuration to another host. Its output must
+// not be logged.
+func DumpWithSecrets(s interface{}, format Format, w io.Writer) error {
+	return dump("DumpWithSecrets", s, format, w, false)
+}
+
+// dump writes the struct s to w in the given format, the values of secrets
+// being redacted if redact is true. name is the name of the function called,
+// for the errors.
+func dump(name string, s interface{}, format Format, w io.Writer, redact bool) error {
+	v := reflect.ValueOf(s)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("multiconfig: %s needs a struct, got %T", name, s)
+	}
+
+	data, err := encodeNodes(string(format), dumpNodes(v, string(format), nil, redact))
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// defaultsComment describes the required and default tags of a field.
+func defaultsComment(field reflect.StructField) string {
+	var info []string
+	if field.Tag.Get("required") == "true" {
+		info = append(info, "required")
+	}
+
+	if def := field.Tag.Get("default"); def != "" {
+		if field.Tag.Get("secret") == "true" {
+			def = redacted
+		}
+
+		info = append(info, "default: "+def)
+	}
+
+	return strings.Join(info, ", ")
+}
+
+// node is a field of a struct prepared to be encoded.
+type node struct {
+	key     string
+	comment string
+
+	// doc describes the field, on the lines above it
+	doc string
+
+	// value holds the value of a leaf: a string, bool, number, slice or map
+	value interface{}
+
+	// children holds the fields of a nested struct, or the entries of a map
+	// of structs. A node is a leaf if children is nil.
+	children []*node
+}
+
+// dumpNodes returns the nodes of the exported fields of the struct v, named
+// for the given format. comment, if not nil, returns the comment of a field,
+// which is documented by its desc tag too. If redact is true, the values of
+// the fields tagged with `secret:"true"` are redacted.
+func dumpNodes(v reflect.Value, format string, comment func(reflect.StructField) string, redact bool) []*node {
+	nodes := []*node{}
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" || field.Tag.Get("config") == restTag {
+			continue
+		}
+
+		tag := strings.Split(field.Tag.Get(format), ",")
+		if tag[0] == "-" {
+			continue
+		}
+
+		fv := v.Field(i)
+		for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+
+		inline := len(tag) > 1 && tag[1] == "inline" || isFlattened(field.Type, field.Tag.Get("structs"))
+		if (field.Anonymous || inline) && fv.Kind() == reflect.Struct && !isTextType(fv) {
+			nodes = append(nodes, dumpNodes(fv, format, comment, redact)...)
+			continue
+		}
+
+		key := field.Name
+		switch {
+		case tag[0] != "":
+			key = tag[0]
+		case format == "yaml":
+			key = strings.ToLower(field.Name)
+		}
+
+		n := &node{key: key}
+		if comment != nil {
+			n.comment = comment(field)
+			n.doc = field.Tag.Get(descTag)
+		}
+
+		switch {
+		case redact && field.Tag.Get("secret") == "true":
+			n.value = redacted
+		case fv.Kind() == reflect.Struct && !isTextType(fv):
+			n.children = dumpNodes(fv, format, comment, redact)
+		case fv.Kind() == reflect.Map && fv.Type().Elem().Kind() == reflect.Struct:
+			n.children = []*node{}
+			for _, key := range sortedKeys(fv) {
+				n.children = append(n.children, &node{
+					key:      fmt.Sprint(key.Interface()),
+					children: dumpNodes(fv.MapIndex(key), format, comment, redact),
+				})
+			}
+		default:
+			n.value = plainValue(fv)
+		}
+
+		nodes = append(nodes, n)
+	}
+
+	return nodes
+}
+
+// isTextType reports whether the value is encoded as a single text value,
+// such as a time.Time.
+func isTextType(v reflect.Value) bool {
+	_, ok := v.Interface().(encoding.TextMarshaler)
+	return ok
+}
+
+func sortedKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+
+	return keys
+}
+
+// plainValue converts v into a value made of strings, bools, numbers,
+// slices and maps only. Durations and the types implementing
+// encoding.TextMarshaler are converted to their text form, which the loaders
+// parse back.
+func plainValue(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return ""
+		}
+
+		text, err := m.MarshalText()
+		if err != nil {
+			return fmt.Sprint(v.Interface())
+		}
+
+		return string(text)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+
+		return plainValue(v.Elem())
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	case reflect.Slice, reflect.Array:
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			list[i] = plainValue(v.Index(i))
+		}
+
+		return list
+	case reflect.Map:
+		m := make(map[string]interface{}, v.Len())
+		for _, key := range v.MapKeys() {
+			m[fmt.Sprint(key.Interface())] = plainValue(v.MapIndex(key))
+		}
+
+		return m
+	case reflect.Struct:
+		m := map[string]interface{}{}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" {
+				m[v.Type().Field(i).Name] = plainValue(v.Field(i))
+			}
+		}
+
+		return m
+	}
+
+	return fmt.Sprint(v.Interface())
+}
+
+// encodeNodes encodes the nodes in the given format.
+func encodeNodes(format string, nodes []*node) ([]byte, error) {
+	var buf bytes.Buffer
+
+	switch format {
+	case "toml":
+		encodeTOML(&buf, "", nodes)
+	case "json":
+		encodeJSON(&buf, "", nodes)
+		buf.WriteString("\n")
+	case "yaml", "yml":
+		encodeYAML(&buf, "", nodes)
+	default:
+		return nil, fmt.Errorf("multiconfig: unsupported format %q", format)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func encodeTOML(buf *bytes.Buffer, table string, nodes []*node) {
+	// the keys of a table must come before its sub tables
+	for _, n := range nodes {
+		if n.children == nil {
+			writeDoc(buf, "", n.doc)
+			buf.WriteString(n.key + " = " + tomlValue(n.value))
+			writeComment(buf, n.comment)
+		}
+	}
+
+	for _, n := range nodes {
+		if n.children == nil {
+			continue
+		}
+
+		name := tomlKey(n.key)
+		if table != "" {
+			name = table + "." + name
+		}
+
+		if buf.Len() > 0 {
+			buf.WriteString("\n")
+		}
+
+		writeDoc(buf, "", n.doc)
+		buf.WriteString("[" + name + "]")
+		writeComment(buf, n.comment)
+		encodeTOML(buf, name, n.children)
+	}
+}
+
+func tomlKey(key string) string {
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return jsonValue(key)
+		}
+	}
+
+	return key
+}
+
+// tomlValue encodes a value produced by plainValue in TOML. Strings, numbers
+// and booleans share their syntax with JSON.
+func tomlValue(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return `""`
+	case []interface{}:
+		elems := make([]string, len(t))
+		for i, elem := range t {
+			elems[i] = tomlValue(elem)
+		}
+
+		return "[" + strings.Join(elems, ", ") + "]"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for key := range t {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		elems := make([]string, len(keys))
+		for i, key := range keys {
+			elems[i] = tomlKey(key) + " = " + tomlValue(t[key])
+		}
+
+		return "{" + strings.Join(elems, ", ") + "}"
+	}
+
+	return jsonValue(v)
+}
+
+func encodeYAML(buf *bytes.Buffer, indent string, nodes []*node) {
+	for _, n := range nodes {
+		writeDoc(buf, indent, n.doc)
+		buf.WriteString(indent + yamlKey(n.key) + ":")
+
+		switch {
+		case n.children == nil:
+			// JSON is a subset of the YAML flow style
+			buf.WriteString(" " + jsonValue(n.value))
+		case len(n.children) == 0:
+			buf.WriteString(" {}")
+		}
+
+		writeComment(buf, n.comment)
+
+		if len(n.children) > 0 {
+			encodeYAML(buf, indent+"  ", n.children)
+		}
+	}
+}
+
+func yamlKey(key string) string {
+	if strings.ContainsAny(key, ":#{}[],&*!|>'\"%@`") || strings.TrimSpace(key) != key {
+		return jsonValue(key)
+	}
+
+	return key
+}
+
+func encodeJSON(buf *bytes.Buffer, indent string, nodes []*node) {
+	buf.WriteString("{\n")
+
+	for i, n := range nodes {
+		buf.WriteString(indent + "  " + jsonValue(n.key) + ": ")
+
+		if n.children != nil {
+			encodeJSON(buf, indent+"  ", n.children)
+		} else {
+			buf.WriteString(jsonValue(n.value))
+		}
+
+		if i < len(nodes)-1 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n")
+	}
+
+	buf.WriteString(indent + "}")
+}
+
+func jsonValue(v interface{}) string {
+	if v == nil {
+		return "null"
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return `""`
+	}
+
+	return string(b)
+}
+
+// writeDoc writes each line of doc as a comment, indented by indent.
+func writeDoc(buf *bytes.Buffer, indent, doc string) {
+	if doc == "" {
+		return
+	}
+
+	for _, line := range strings.Split(doc, "\n") {
+		buf.WriteString(indent + "# " + line + "\n")
+	}
+}
+
+func writeComment(buf *bytes.Buffer, comment string) {
+	if comment != "" {
+		buf.WriteString(" # " + comment)
+	}
+
+	buf.WriteString("\n")
+}
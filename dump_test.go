@@ -0,0 +1,155 @@
+package multiconfig
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestDumpDefaults(t *testing.T) {
+	for _, format := range []string{"toml", "json", "yaml"} {
+		got, err := DumpDefaults(&Server{}, format)
+		if err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+
+		want, err := ioutil.ReadFile("testdata/defaults." + format)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: template is wrong:\n%s\nwant:\n%s", format, got, want)
+		}
+
+		// the template can be loaded back
+		s := &Server{}
+		if err := readerLoader(format, bytes.NewReader(got)).Load(s); err != nil {
+			t.Errorf("%s: %s", format, err)
+		}
+
+		if s.Port != 6060 || s.Postgres.DBName != "configdb" {
+			t.Errorf("%s: defaults are not loaded back: %+v", format, s)
+		}
+	}
+}
+
+func TestDumpDefaultsUnsupportedFormat(t *testing.T) {
+	if _, err := DumpDefaults(&Server{}, "xml"); err == nil {
+		t.Error("xml should not be supported")
+	}
+}
+
+func TestDump(t *testing.T) {
+	s := &Server{}
+	if err := NewWithPath(testTOML).Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []Format{TOML, JSON, YAML} {
+		var buf bytes.Buffer
+		if err := Dump(s, format, &buf); err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+
+		// the effective configuration is loaded back as is
+		got := &Server{}
+		if err := readerLoader(string(format), &buf).Load(got); err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+
+		testStruct(t, got, getDefaultServer())
+	}
+}
+
+func TestDumpFlattened(t *testing.T) {
+	type Cache struct {
+		Addr string
+	}
+
+	type App struct {
+		Name  string
+		Cache Cache `structs:",flatten"`
+	}
+
+	for _, format := range []Format{TOML, JSON, YAML} {
+		var buf bytes.Buffer
+		if err := Dump(&App{Name: "app", Cache: Cache{Addr: "redis:6379"}}, format, &buf); err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+
+		if bytes.Contains(bytes.ToLower(buf.Bytes()), []byte("cache")) {
+			t.Errorf("%s: the flattened struct is dumped as a table:\n%s", format, buf.Bytes())
+		}
+
+		// the flattened keys are loaded back
+		got := &App{}
+		if err := readerLoader(string(format), &buf).Load(got); err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+
+		if got.Cache.Addr != "redis:6379" {
+			t.Errorf("%s: config is not loaded back: %+v", format, got)
+		}
+	}
+}
+
+func TestDumpSecrets(t *testing.T) {
+	type Database struct {
+		User     string
+		Password string `secret:"true"`
+	}
+
+	var buf bytes.Buffer
+	if err := Dump(&Database{User: "admin", Password: "s3cr3t"}, TOML, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "User = \"admin\"\nPassword = \"****\"\n"; buf.String() != want {
+		t.Errorf("unexpected dump:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := DumpWithSecrets(&Database{User: "admin", Password: "s3cr3t"}, TOML, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "User = \"admin\"\nPassword = \"s3cr3t\"\n"; buf.String() != want {
+		t.Errorf("unexpected dump with secrets:\n%s", buf.String())
+	}
+
+	if err := Dump("config", JSON, &buf); err == nil {
+		t.Error("dumping a string should fail")
+	}
+}
+
+func TestGenerateExample(t *testing.T) {
+	type Postgres struct {
+		Port int    `default:"5432" desc:"Port of the database"`
+		Name string `required:"true"`
+	}
+
+	type App struct {
+		Name     string   `default:"api" desc:"Name of the service\nused in the logs"`
+		Token    string   `default:"dev-token" secret:"true"`
+		Postgres Postgres `desc:"Connection to the database"`
+	}
+
+	want := map[Format]string{
+		TOML: "# Name of the service\n# used in the logs\nName = \"api\" # default: api\nToken = \"****\" # default: ****\n\n" +
+			"# Connection to the database\n[Postgres]\n# Port of the database\nPort = 5432 # default: 5432\nName = \"\" # required\n",
+		YAML: "# Name of the service\n# used in the logs\nname: \"api\" # default: api\ntoken: \"****\" # default: ****\n" +
+			"# Connection to the database\npostgres:\n  # Port of the database\n  port: 5432 # default: 5432\n  name: \"\" # required\n",
+	}
+
+	for format, example := range want {
+		got, err := GenerateExample(&App{}, format)
+		if err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+
+		if string(got) != example {
+			t.Errorf("%s: example is wrong:\n%s\nwant:\n%s", format, got, example)
+		}
+	}
+}
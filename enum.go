@@ -0,0 +1,78 @@
+package multiconfig
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// enums holds the registered enum types, mapping each name to its value.
+var enums = struct {
+	sync.RWMutex
+	m map[reflect.Type]map[string]reflect.Value
+}{m: make(map[reflect.Type]map[string]reflect.Value)}
+
+// RegisterEnum registers the names of the values of an enum type. names must
+// be a map of names to values of the enum type:
+//
+//	type Protocol int
+//
+//	const (
+//		ProtocolTCP Protocol = iota
+//		ProtocolUDP
+//	)
+//
+//	multiconfig.RegisterEnum(map[string]Protocol{
+//		"tcp": ProtocolTCP,
+//		"udp": ProtocolUDP,
+//	})
+//
+// Once registered, fields, slice elements and map keys of the enum type are
+// parsed from their names by every loader, i.e: a `map[Protocol]Handler`
+// field can be loaded from a TOML table keyed by "tcp" and "udp". Unknown
+// names are rejected with the list of valid ones. It panics if names is not a
+// map with string keys.
+func RegisterEnum(names interface{}) {
+	v := reflect.ValueOf(names)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		panic(fmt.Sprintf("multiconfig: RegisterEnum needs a map of names to values, got %T", names))
+	}
+
+	values := make(map[string]reflect.Value, v.Len())
+	for _, key := range v.MapKeys() {
+		values[key.String()] = v.MapIndex(key)
+	}
+
+	enums.Lock()
+	enums.m[v.Type().Elem()] = values
+	enums.Unlock()
+}
+
+// setEnum sets v to the value named by s if the type of v is a registered
+// enum. It reports whether the type is registered.
+func setEnum(v reflect.Value, s string) (bool, error) {
+	enums.RLock()
+	values, ok := enums.m[v.Type()]
+	enums.RUnlock()
+
+	if !ok {
+		return false, nil
+	}
+
+	val, ok := values[s]
+	if !ok {
+		names := make([]string, 0, len(values))
+		for name := range values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return true, fmt.Errorf("unknown %s %q, valid values are: %s",
+			v.Type(), s, strings.Join(names, ", "))
+	}
+
+	v.Set(val)
+	return true, nil
+}
@@ -0,0 +1,86 @@
+package multiconfig
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type Protocol int
+
+const (
+	ProtocolTCP Protocol = iota + 1
+	ProtocolUDP
+)
+
+type HandlerConfig struct {
+	Port    int
+	Workers int `default:"4"`
+}
+
+type Router struct {
+	Default  Protocol
+	Handlers map[Protocol]HandlerConfig
+}
+
+func init() {
+	RegisterEnum(map[string]Protocol{
+		"tcp": ProtocolTCP,
+		"udp": ProtocolUDP,
+	})
+}
+
+func TestEnumMapKeys(t *testing.T) {
+	want := &Router{
+		Default: ProtocolUDP,
+		Handlers: map[Protocol]HandlerConfig{
+			ProtocolTCP: {Port: 80},
+			ProtocolUDP: {Port: 53},
+		},
+	}
+
+	loaders := []Loader{
+		&TOMLLoader{Reader: strings.NewReader("Default = \"udp\"\n[Handlers.tcp]\nPort = 80\n[Handlers.udp]\nPort = 53\n")},
+		&JSONLoader{Reader: strings.NewReader(`{"Default": "udp", "Handlers": {"tcp": {"Port": 80}, "udp": {"Port": 53}}}`)},
+		&YAMLLoader{Reader: strings.NewReader("default: udp\nhandlers:\n  tcp:\n    port: 80\n  udp:\n    port: 53\n")},
+	}
+
+	for _, l := range loaders {
+		r := &Router{}
+		if err := l.Load(r); err != nil {
+			t.Fatalf("%T: %s", l, err)
+		}
+
+		if diff := cmp.Diff(want, r); diff != "" {
+			t.Errorf("%T: diff = %s", l, diff)
+		}
+	}
+}
+
+func TestEnumUnknownKey(t *testing.T) {
+	l := &JSONLoader{Reader: strings.NewReader(`{"Handlers": {"sctp": {"Port": 80}}}`)}
+
+	err := l.Load(&Router{})
+	if err == nil {
+		t.Fatal("sctp should not be a valid protocol")
+	}
+
+	errStr := `multiconfig: field 'Handlers': unknown multiconfig.Protocol "sctp", valid values are: tcp, udp`
+	if err.Error() != errStr {
+		t.Fatalf("Err string is wrong: expected %s, got: %s", errStr, err.Error())
+	}
+}
+
+func TestEnumEnv(t *testing.T) {
+	t.Setenv("ROUTER_DEFAULT", "tcp")
+
+	r := &Router{}
+	if err := (&EnvironmentLoader{}).Load(r); err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Default != ProtocolTCP {
+		t.Errorf("Default value is wrong: %v, want: %v", r.Default, ProtocolTCP)
+	}
+}
@@ -3,6 +3,8 @@ package multiconfig
 import (
 	"fmt"
 	"os"
+	pathpkg "path"
+	"reflect"
 	"sort"
 	"strings"
 
@@ -10,9 +12,26 @@ import (
 	"github.com/fatih/structs"
 )
 
+// fileEnvSuffix is the suffix of the environment variable naming a file
+// which holds the value of a field, i.e: SERVER_POSTGRES_PASSWORD_FILE.
+const fileEnvSuffix = "_FILE"
+
+// envTag is the tag naming the environment variable of a field.
+const envTag = "env"
+
 // EnvironmentLoader satisifies the loader interface. It loads the
 // configuration from the environment variables in the form of
 // STRUCTNAME_FIELDNAME.
+//
+// If a variable is not set but its _FILE variant is, i.e:
+// SERVER_POSTGRES_PASSWORD_FILE=/run/secrets/pg, the field is set to the
+// content of the named file, without its trailing newline. This is the
+// convention of Docker and Kubernetes secrets.
+//
+// The env tag binds a field to a variable of any name instead, regardless of
+// the prefix and of the position of the field in the struct, i.e:
+//
+//	URL string `env:"DATABASE_URL"`
 type EnvironmentLoader struct {
 	// Prefix prepends given string to every environment variable
 	// {STRUCTNAME}_FIELDNAME will be {PREFIX}_FIELDNAME
@@ -23,6 +42,43 @@ type EnvironmentLoader struct {
 	// "STRUCTNAME_ACCESSKEY". If CamelCase is enabled, the environment name
 	// will be generated in the form of "STRUCTNAME_ACCESS_KEY"
 	CamelCase bool
+
+	// Separator separates the prefix and the names of the nested fields,
+	// i.e: "__" for SERVER__POSTGRES__PORT. The words of camelcase names
+	// are still separated by "_". The default is "_".
+	Separator string
+
+	// PreserveCase keeps the case of the prefix and of the field names
+	// instead of upper casing them, i.e: Server_Postgres_Port. The keys of
+	// map fields keep their case too instead of being lower cased.
+	PreserveCase bool
+
+	// SliceSeparator separates the elements of slice fields, i.e:
+	// "ankara;istanbul" with a separator of ";". The default is ",".
+	SliceSeparator string
+
+	// Strict rejects the environment variables starting with the prefix
+	// which don't match any field, i.e: a misspelled SERVER_PROTGRES_PORT,
+	// instead of ignoring them.
+	Strict bool
+
+	// NameTag, if set, is the tag whose names replace the names of the
+	// fields in the names of the variables, i.e: "json" for
+	// SERVER_DB_NAME instead of SERVER_DBNAME for a field tagged
+	// `json:"db_name"`. The "-" and "." of a name are replaced by "_".
+	NameTag string
+
+	// getenv retrieves the value of the environment variable named by the
+	// key. If nil, os.Getenv is used.
+	getenv func(key string) string
+
+	// environ returns the environment variables in the form of "key=value".
+	// If nil, os.Environ is used.
+	environ func() []string
+
+	// source is the name of the source reported for the loaded fields,
+	// followed by the name of the variable. The default is "env".
+	source string
 }
 
 func (e *EnvironmentLoader) getPrefix(s *structs.Struct) string {
@@ -33,16 +89,35 @@ func (e *EnvironmentLoader) getPrefix(s *structs.Struct) string {
 	return s.Name()
 }
 
+func (e *EnvironmentLoader) lookup(key string) string {
+	if e.getenv != nil {
+		return e.getenv(key)
+	}
+
+	return os.Getenv(key)
+}
+
 // Load loads the source into the config defined by struct s
 func (e *EnvironmentLoader) Load(s interface{}) error {
-	strct := structs.New(s)
-	strctMap := strct.Map()
-	prefix := e.getPrefix(strct)
+	if isRaw(s) {
+		return nil
+	}
+
+	if e.Strict {
+		if unknown := e.unknownEnvs(s); len(unknown) > 0 {
+			return fmt.Errorf("multiconfig: unknown environment variables: %s", strings.Join(unknown, ", "))
+		}
+	}
 
-	for key, val := range strctMap {
-		field := strct.Field(key)
+	for _, field := range e.envFields(s) {
+		var err error
+		if field.typ.Kind() == reflect.Map {
+			err = e.setMapField(s, field)
+		} else {
+			err = e.setField(s, field.path, field.name)
+		}
 
-		if err := e.processField(prefix, field, key, val); err != nil {
+		if err != nil {
 			return err
 		}
 	}
@@ -50,80 +125,535 @@ func (e *EnvironmentLoader) Load(s interface{}) error {
 	return nil
 }
 
-// processField gets leading name for the env variable and combines the current
-// field's name and generates environment variable names recursively
-func (e *EnvironmentLoader) processField(prefix string, field *structs.Field, name string, strctMap interface{}) error {
-	fieldName := e.generateFieldName(prefix, name)
+// envField is a field set by an environment variable.
+type envField struct {
+	// path is the path of the field, i.e: "Postgres.Port".
+	path string
+
+	// name is the name of the environment variable of the field.
+	name string
+
+	// typ is the type of the field.
+	typ reflect.Type
+
+	// named is true if the name is given by an env tag, on the field or on
+	// a struct holding it, rather than following the prefix.
+	named bool
+}
+
+// envKey is the key of the cached environment variables of a struct type.
+type envKey struct {
+	typ          reflect.Type
+	camelCase    bool
+	separator    string
+	preserveCase bool
+	nameTag      string
+}
+
+// envFields returns the fields of the struct s set by an environment
+// variable, sorted by path. The fields of the nested structs are named after
+// the struct's, whether the pointers to them are nil or not. They are cached
+// per type and naming options, without the prefix, which may differ from one
+// load to another, i.e: per tenant.
+func (e *EnvironmentLoader) envFields(s interface{}) []envField {
+	key := envKey{
+		typ:          reflect.TypeOf(s),
+		camelCase:    e.CamelCase,
+		separator:    e.Separator,
+		preserveCase: e.PreserveCase,
+		nameTag:      e.NameTag,
+	}
+
+	unprefixed := cached(key, func() interface{} {
+		typ := key.typ
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+
+		return e.appendEnvFields(nil, typ, "", "", false)
+	}).([]envField)
+
+	prefix := e.getPrefix(structs.New(s))
+	if prefix == "" {
+		return unprefixed
+	}
+
+	fields := make([]envField, len(unprefixed))
+	for i, field := range unprefixed {
+		if !field.named {
+			field.name = e.generateFieldName(prefix, field.name)
+		}
+		fields[i] = field
+	}
+
+	return fields
+}
+
+// appendEnvFields appends the fields of the struct type typ to fields,
+// recursing into the nested structs. prefix is the name of the environment
+// variable of the struct and path its path within the loaded struct. named
+// is true if the name of the struct is given by an env tag.
+func (e *EnvironmentLoader) appendEnvFields(fields []envField, typ reflect.Type, prefix, path string, named bool) []envField {
+	for _, field := range flattenedFields(typ, path) {
+		tag := field.Tag.Get(envTag)
+		name := e.envName(prefix, e.fieldName(field.Name, field.Tag.Get(e.NameTag)), tag)
 
-	switch strctMap.(type) {
-	case map[string]interface{}:
-		for key, val := range strctMap.(map[string]interface{}) {
-			field := field.Field(key)
+		if field.Type.Kind() != reflect.Map && isNestedType(field.Type) {
+			elem := field.Type
+			if elem.Kind() == reflect.Ptr {
+				elem = elem.Elem()
+			}
 
-			if err := e.processField(fieldName, field, key, val); err != nil {
-				return err
+			// a struct without exported fields is set as a whole
+			if len(structFields(elem)) > 0 {
+				fields = e.appendEnvFields(fields, elem, name, field.path, named || tag != "")
+				continue
 			}
 		}
-	default:
-		v := os.Getenv(fieldName)
-		if v == "" {
+
+		fields = append(fields, envField{path: field.path, name: name, typ: field.Type, named: named || tag != ""})
+	}
+
+	return fields
+}
+
+// pathField is a field along with its path.
+type pathField struct {
+	reflect.StructField
+	path string
+}
+
+// flattenedFields returns the fields of the struct type typ, found at path,
+// sorted by name. The fields of the structs tagged with `structs:",flatten"`
+// take their place, as in the map of structs.Map.
+func flattenedFields(typ reflect.Type, path string) []pathField {
+	var fields []pathField
+	for _, field := range structFields(typ) {
+		fieldPath := joinPath(path, field.Name)
+
+		if isFlattened(field.Type, field.Tag.Get("structs")) {
+			elem := field.Type
+			if elem.Kind() == reflect.Ptr {
+				elem = elem.Elem()
+			}
+
+			fields = append(fields, flattenedFields(elem, fieldPath)...)
+			continue
+		}
+
+		fields = append(fields, pathField{field, fieldPath})
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Name < fields[j].Name
+	})
+
+	return fields
+}
+
+// setField sets the field at the given path of the struct s from the
+// environment variable named envName, if it's defined.
+func (e *EnvironmentLoader) setField(s interface{}, path, envName string) error {
+	v := e.lookup(envName)
+	if v == "" {
+		// the _FILE variant names a file holding the value, such as a
+		// Docker or Kubernetes secret
+		file := e.lookup(envName + fileEnvSuffix)
+		if file == "" {
 			return nil
 		}
 
-		if err := fieldSet(field, v); err != nil {
-			return err
+		var err error
+		if v, err = readSecretFile(file); err != nil {
+			return fmt.Errorf("multiconfig: %s%s: %s", envName, fileEnvSuffix, err)
 		}
+		envName += fileEnvSuffix
+	}
+
+	// the field of a nil struct pointer is reached by allocating it
+	field, ok := fieldByPath(s, path)
+	if !ok {
+		return fmt.Errorf("multiconfig: field '%s' not found", path)
 	}
 
+	if err := fieldSet(field, v, e.SliceSeparator, path); err != nil {
+		return withLoader(err, e.sourceName()+" "+envName)
+	}
+
+	markLoaded(s, path, e.sourceName()+" "+envName)
 	return nil
 }
 
 // PrintEnvs prints the generated environment variables to the std out.
 func (e *EnvironmentLoader) PrintEnvs(s interface{}) {
-	strct := structs.New(s)
-	strctMap := strct.Map()
-	prefix := e.getPrefix(strct)
+	for _, name := range e.envNames(s) {
+		fmt.Println("  ", name)
+	}
+}
 
-	keys := make([]string, 0, len(strctMap))
-	for key := range strctMap {
-		keys = append(keys, key)
+// envNames returns the names of the environment variables generated for the
+// fields of the struct s. The entries of map fields are given as patterns,
+// i.e: SERVER_LABELS_*.
+func (e *EnvironmentLoader) envNames(s interface{}) []string {
+	var names []string
+	for _, field := range e.envFields(s) {
+		names = append(names, field.name)
+
+		if field.typ.Kind() == reflect.Map {
+			for _, suffix := range sortedSuffixes(e.mapEnvSuffixes(field.typ.Elem())) {
+				names = append(names, field.name+e.separator()+"*"+suffix)
+			}
+		}
 	}
-	sort.Strings(keys)
 
-	for _, key := range keys {
-		field := strct.Field(key)
-		e.printField(prefix, field, key, strctMap[key])
+	return names
+}
+
+// unknownEnvs returns the sorted names of the environment variables starting
+// with the prefix of the struct s which don't match any field, each followed
+// by the closest variable matching one, if any.
+func (e *EnvironmentLoader) unknownEnvs(s interface{}) []string {
+	known := map[string]bool{}
+	var patterns []string
+	for _, name := range e.envNames(s) {
+		if strings.Contains(name, "*") {
+			patterns = append(patterns, name)
+			continue
+		}
+
+		known[name] = true
+		known[name+fileEnvSuffix] = true
+	}
+
+	prefix := e.toCase(e.getPrefix(structs.New(s))) + e.separator()
+
+	var candidates []string
+	for name := range known {
+		if strings.HasPrefix(name, prefix) {
+			candidates = append(candidates, strings.TrimPrefix(name, prefix))
+		}
+	}
+
+	var unknown []string
+	for _, name := range e.environNames() {
+		if strings.HasPrefix(name, prefix) && !known[name] && !matchAny(patterns, name) {
+			unknown = append(unknown, didYouMean(prefix, strings.TrimPrefix(name, prefix), candidates))
+		}
+	}
+	sort.Strings(unknown)
+
+	return unknown
+}
+
+// setMapField sets the map field from its environment variable, holding its
+// entries in the form of "key=value,key=value", and from the variables
+// setting an entry each. The entries of a map of scalars are named after
+// their key, i.e: SERVER_LABELS_FOO for the key "foo", and the ones of a map
+// of structs after their key and their field, i.e: SERVER_DATABASES_MAIN_PORT
+// for the port of the key "main". Keys are lower cased, unless PreserveCase
+// is set.
+func (e *EnvironmentLoader) setMapField(s interface{}, field envField) error {
+	if err := e.setField(s, field.path, field.name); err != nil {
+		return err
+	}
+
+	t := field.typ
+	suffixes := e.mapEnvSuffixes(t.Elem())
+	path := field.path
+	prefix := field.name + e.separator()
+
+	var target *structs.Field
+	var m reflect.Value
+	for _, name := range e.environNames() {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		val := e.lookup(name)
+		if val == "" {
+			continue
+		}
+
+		// the longest suffix matching wins, the key being what's left
+		rest := name[len(prefix):]
+		suffix, ok := "", false
+		for sfx := range suffixes {
+			if len(rest) > len(sfx) && strings.HasSuffix(rest, sfx) && (!ok || len(sfx) > len(suffix)) {
+				suffix, ok = sfx, true
+			}
+		}
+
+		if !ok {
+			continue
+		}
+
+		if !m.IsValid() {
+			// the field of a nil struct pointer is reached by allocating it
+			var found bool
+			if target, found = fieldByPath(s, path); !found {
+				return fmt.Errorf("multiconfig: field '%s' not found", path)
+			}
+
+			m = reflect.MakeMap(t)
+			iter := reflect.ValueOf(target.Value()).MapRange()
+			for iter.Next() {
+				m.SetMapIndex(iter.Key(), iter.Value())
+			}
+		}
+
+		keyName := rest[:len(rest)-len(suffix)]
+		if !e.PreserveCase {
+			keyName = strings.ToLower(keyName)
+		}
+		key := reflect.New(t.Key()).Elem()
+		if err := setString(key, keyName, "", path); err != nil {
+			return withLoader(err, e.sourceName()+" "+name)
+		}
+
+		elem := reflect.New(t.Elem()).Elem()
+		if existing := m.MapIndex(key); existing.IsValid() {
+			elem.Set(existing)
+		}
+
+		v := elem
+		elemPath := path + "." + keyName
+		for _, fieldName := range suffixes[suffix] {
+			if v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					v.Set(reflect.New(v.Type().Elem()))
+				}
+				v = v.Elem()
+			}
+
+			v = v.FieldByName(fieldName)
+			elemPath += "." + fieldName
+		}
+
+		if err := setString(v, val, e.SliceSeparator, elemPath); err != nil {
+			return withLoader(err, e.sourceName()+" "+name)
+		}
+
+		m.SetMapIndex(key, elem)
+	}
+
+	if !m.IsValid() {
+		return nil
+	}
+
+	if err := target.Set(m.Interface()); err != nil {
+		return err
+	}
+
+	markLoaded(s, path, e.sourceName()+" "+prefix+"*")
+	return nil
+}
+
+// sourceName returns the name of the source reported for the loaded fields.
+func (e *EnvironmentLoader) sourceName() string {
+	if e.source != "" {
+		return e.source
 	}
+
+	return "env"
+}
+
+// mapEnvSuffixes returns the suffixes following the key in the names of the
+// environment variables setting the elements of type t of a map, mapped to
+// the names of the fields they set within the element. A scalar element is
+// set by a single variable, with an empty suffix.
+func (e *EnvironmentLoader) mapEnvSuffixes(t reflect.Type) map[string][]string {
+	key := suffixesKey{typ: t, camelCase: e.CamelCase, separator: e.Separator, preserveCase: e.PreserveCase, nameTag: e.NameTag}
+	return cached(key, func() interface{} {
+		suffixes := map[string][]string{}
+		e.structEnvSuffixes(t, "", nil, suffixes)
+
+		if len(suffixes) == 0 {
+			suffixes[""] = nil
+		}
+
+		return suffixes
+	}).(map[string][]string)
 }
 
-// printField prints the field of the config struct for the flag.Usage
-func (e *EnvironmentLoader) printField(prefix string, field *structs.Field, name string, strctMap interface{}) {
-	fieldName := e.generateFieldName(prefix, name)
+// suffixesKey is the key of the cached suffixes of the environment variables
+// of map elements.
+type suffixesKey struct {
+	typ          reflect.Type
+	camelCase    bool
+	separator    string
+	preserveCase bool
+	nameTag      string
+}
+
+// structEnvSuffixes adds the suffixes of the fields of the struct type t to
+// suffixes. prefix and names are the suffix and the names of the field of
+// type t within the element.
+func (e *EnvironmentLoader) structEnvSuffixes(t reflect.Type, prefix string, names []string, suffixes map[string][]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || isUnmarshalerType(t) {
+		return
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := e.generateFieldName(prefix, e.fieldName(field.Name, field.Tag.Get(e.NameTag)))
+		fieldNames := append(append([]string(nil), names...), field.Name)
 
-	switch strctMap.(type) {
-	case map[string]interface{}:
-		smap := strctMap.(map[string]interface{})
-		keys := make([]string, 0, len(smap))
-		for key := range smap {
-			keys = append(keys, key)
+		nested := map[string][]string{}
+		e.structEnvSuffixes(field.Type, name, fieldNames, nested)
+		if len(nested) == 0 {
+			suffixes[name] = fieldNames
 		}
-		sort.Strings(keys)
-		for _, key := range keys {
-			field := field.Field(key)
-			e.printField(fieldName, field, key, smap[key])
+
+		for suffix, names := range nested {
+			suffixes[suffix] = names
 		}
-	default:
-		fmt.Println("  ", fieldName)
 	}
 }
 
+// environNames returns the sorted names of the environment variables.
+func (e *EnvironmentLoader) environNames() []string {
+	environ := os.Environ
+	if e.environ != nil {
+		environ = e.environ
+	}
+
+	var names []string
+	for _, env := range environ() {
+		names = append(names, strings.SplitN(env, "=", 2)[0])
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// matchAny reports whether name matches any of the patterns, in which "*"
+// matches any sequence of characters.
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := pathpkg.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sortedSuffixes returns the sorted keys of suffixes.
+func sortedSuffixes(suffixes map[string][]string) []string {
+	keys := make([]string, 0, len(suffixes))
+	for key := range suffixes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// envName returns the name of the environment variable of the field named
+// name: the name given by its env tag, i.e: `env:"DATABASE_URL"`, or else the
+// name generated from the prefix and the field's name. The variables of the
+// fields nested in a struct are named after the struct's.
+func (e *EnvironmentLoader) envName(prefix, name, tag string) string {
+	if tag != "" {
+		return tag
+	}
+
+	// the fields of an unnamed struct without a Prefix aren't prefixed
+	if prefix == "" {
+		return strings.TrimPrefix(e.generateFieldName("", name), e.separator())
+	}
+
+	return e.generateFieldName(prefix, name)
+}
+
 // generateFieldName generates the field name combined with the prefix and the
-// struct's field name
+// name of the field, as returned by fieldName
 func (e *EnvironmentLoader) generateFieldName(prefix string, name string) string {
-	fieldName := strings.ToUpper(name)
+	return e.toCase(prefix) + e.separator() + e.toCase(name)
+}
+
+// fieldName returns the name of the field named name in the names of the
+// variables: the name given by the value of its NameTag, if any, or else its
+// name, split in words if CamelCase is set.
+func (e *EnvironmentLoader) fieldName(name, nameTag string) string {
+	if e.NameTag != "" {
+		if tagged := nameFromTag(nameTag); tagged != "" {
+			return strings.NewReplacer("-", "_", ".", "_").Replace(tagged)
+		}
+	}
+
 	if e.CamelCase {
-		fieldName = strings.ToUpper(strings.Join(camelcase.Split(name), "_"))
+		return strings.Join(camelcase.Split(name), "_")
+	}
+
+	return name
+}
+
+// separator returns the separator of the prefix and the field names.
+func (e *EnvironmentLoader) separator() string {
+	if e.Separator != "" {
+		return e.Separator
+	}
+
+	return "_"
+}
+
+// toCase returns name upper cased, unless PreserveCase is set.
+func (e *EnvironmentLoader) toCase(name string) string {
+	if e.PreserveCase {
+		return name
+	}
+
+	return strings.ToUpper(name)
+}
+
+// envNameOf returns the name of the environment variable of the field at the
+// given path of the struct s, i.e: "Postgres.Port".
+func (e *EnvironmentLoader) envNameOf(s interface{}, path string) string {
+	strct := structs.New(s)
+	names := strings.Split(path, ".")
+
+	field, ok := strct.FieldOk(names[0])
+	if !ok {
+		return ""
+	}
+
+	name := e.envNameOfField(e.getPrefix(strct), field)
+	for _, n := range names[1:] {
+		var parent interface {
+			FieldOk(name string) (*structs.Field, bool)
+		} = field
+
+		// the fields of a nil struct are the ones of a zero struct
+		if isNestedPtr(field) && field.IsZero() {
+			parent = zeroStruct(field)
+		}
+
+		if field, ok = parent.FieldOk(n); !ok {
+			return ""
+		}
+
+		name = e.envNameOfField(name, field)
+	}
+
+	return name
+}
+
+// envNameOfField returns the name of the environment variable of the field
+// of the struct whose variables are named after prefix. A flattened struct is
+// named after the struct holding it.
+func (e *EnvironmentLoader) envNameOfField(prefix string, field *structs.Field) string {
+	if field.IsExported() && isFlattened(reflect.TypeOf(field.Value()), field.Tag("structs")) {
+		return prefix
 	}
 
-	return strings.ToUpper(prefix) + "_" + fieldName
+	return e.envName(prefix, e.fieldName(field.Name(), field.Tag(e.NameTag)), field.Tag(envTag))
 }
@@ -1,11 +1,14 @@
 package multiconfig
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/fatih/structs"
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestENV(t *testing.T) {
@@ -123,10 +126,7 @@ func setEnvVars(t *testing.T, structName, prefix string) {
 	prefix = strings.ToUpper(prefix)
 
 	for key, val := range env {
-		env := prefix + "_" + key
-		if err := os.Setenv(env, val); err != nil {
-			t.Fatal(err)
-		}
+		t.Setenv(prefix+"_"+key, val)
 	}
 }
 
@@ -148,3 +148,199 @@ func TestENVgetPrefix(t *testing.T) {
 		t.Errorf("Prefix is wrong: %s, want: %s", p, prefix)
 	}
 }
+
+func TestENVSliceSeparator(t *testing.T) {
+	type SeparatorServer struct {
+		Users  []string
+		Labels []int
+	}
+
+	os.Setenv("SEPARATORSERVER_USERS", "ankara;;istanbul")
+	os.Setenv("SEPARATORSERVER_LABELS", "123;456;")
+	defer os.Unsetenv("SEPARATORSERVER_USERS")
+	defer os.Unsetenv("SEPARATORSERVER_LABELS")
+
+	m := EnvironmentLoader{SliceSeparator: ";"}
+	s := &SeparatorServer{}
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	want := &SeparatorServer{
+		Users:  []string{"ankara", "istanbul"},
+		Labels: []int{123, 456},
+	}
+
+	if diff := cmp.Diff(want, s); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+}
+
+func TestENVStrict(t *testing.T) {
+	os.Setenv("STRICTENVSERVER_NAME", "koding")
+	os.Setenv("STRICTENVSERVER_PROTGRES_PORT", "5432")
+	os.Setenv("STRICTENVSERVER_POSTGRES_PROT", "5432")
+	defer os.Unsetenv("STRICTENVSERVER_NAME")
+	defer os.Unsetenv("STRICTENVSERVER_PROTGRES_PORT")
+	defer os.Unsetenv("STRICTENVSERVER_POSTGRES_PROT")
+
+	m := EnvironmentLoader{Prefix: "StrictEnvServer"}
+	if err := m.Load(&Server{}); err != nil {
+		t.Fatalf("unknown variables should be ignored by default: %s", err)
+	}
+
+	m.Strict = true
+	err := m.Load(&Server{})
+	want := "multiconfig: unknown environment variables: " +
+		`STRICTENVSERVER_POSTGRES_PROT (did you mean "STRICTENVSERVER_POSTGRES_PORT"?), ` +
+		`STRICTENVSERVER_PROTGRES_PORT (did you mean "STRICTENVSERVER_POSTGRES_PORT"?)`
+	if err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+}
+
+func TestENVFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "multiconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	secret := filepath.Join(dir, "pg")
+	if err := ioutil.WriteFile(secret, []byte("192.168.2.1,192.168.2.2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("SECRETSERVER_NAME", "koding")
+	os.Setenv("SECRETSERVER_NAME_FILE", filepath.Join(dir, "unused"))
+	os.Setenv("SECRETSERVER_POSTGRES_HOSTS_FILE", secret)
+	defer os.Unsetenv("SECRETSERVER_NAME")
+	defer os.Unsetenv("SECRETSERVER_NAME_FILE")
+	defer os.Unsetenv("SECRETSERVER_POSTGRES_HOSTS_FILE")
+
+	s := new(Server)
+	m := EnvironmentLoader{Prefix: "SecretServer", Strict: true}
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Name != "koding" {
+		t.Errorf("the variable should win over its _FILE variant, got name %q", s.Name)
+	}
+
+	if diff := cmp.Diff([]string{"192.168.2.1", "192.168.2.2"}, s.Postgres.Hosts); diff != "" {
+		t.Errorf("hosts are not read from the file: %s", diff)
+	}
+
+	os.Setenv("SECRETSERVER_POSTGRES_HOSTS_FILE", filepath.Join(dir, "missing"))
+	if err := m.Load(new(Server)); err == nil || !strings.Contains(err.Error(), "SECRETSERVER_POSTGRES_HOSTS_FILE") {
+		t.Errorf("a missing file should fail naming its variable, got: %v", err)
+	}
+}
+
+func TestENVNaming(t *testing.T) {
+	type Naming struct {
+		AccessKey string
+		Postgres  struct {
+			DBName string
+		}
+		Labels map[string]string
+	}
+
+	tests := map[string]struct {
+		loader EnvironmentLoader
+		env    testEnvironment
+	}{
+		"separator": {
+			loader: EnvironmentLoader{Prefix: "APP", Separator: "__"},
+			env: testEnvironment{
+				"APP__ACCESSKEY":        "key",
+				"APP__POSTGRES__DBNAME": "db",
+				"APP__LABELS__Team":     "core",
+				"APP__POSTGRES_DBNAME":  "ignored",
+				"APP_ACCESSKEY":         "ignored",
+			},
+		},
+		"camelcase separator": {
+			loader: EnvironmentLoader{Prefix: "APP", Separator: "__", CamelCase: true},
+			env: testEnvironment{
+				"APP__ACCESS_KEY":        "key",
+				"APP__POSTGRES__DB_NAME": "db",
+				"APP__LABELS__TEAM":      "core",
+			},
+		},
+		"preserve case": {
+			loader: EnvironmentLoader{PreserveCase: true},
+			env: testEnvironment{
+				"Naming_AccessKey":       "key",
+				"Naming_Postgres_DBName": "db",
+				"Naming_Labels_team":     "core",
+				"NAMING_ACCESSKEY":       "ignored",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test.loader.getenv = test.env.get
+		test.loader.environ = test.env.environ
+
+		s := &Naming{}
+		if err := test.loader.Load(s); err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+
+		if s.AccessKey != "key" || s.Postgres.DBName != "db" {
+			t.Errorf("%s: fields not loaded: %+v", name, s)
+		}
+
+		if s.Labels["team"] != "core" {
+			t.Errorf("%s: map entries not loaded: %v", name, s.Labels)
+		}
+	}
+}
+
+func TestENVTag(t *testing.T) {
+	type Cache struct {
+		URL string `env:"REDIS_URL"`
+		TTL int
+	}
+
+	type Platform struct {
+		Port     int `env:"PORT"`
+		Database struct {
+			URL string `env:"DATABASE_URL"`
+		}
+		Cache Cache `env:"CACHE"`
+	}
+
+	env := testEnvironment{
+		"PORT":                  "8080",
+		"DATABASE_URL":          "postgres://db",
+		"REDIS_URL":             "redis://cache",
+		"CACHE_TTL":             "60",
+		"PLATFORM_DATABASE_URL": "ignored",
+	}
+
+	m := &EnvironmentLoader{getenv: env.get, environ: env.environ, Strict: true}
+
+	p := &Platform{}
+	err := m.Load(p)
+	if err == nil || err.Error() != "multiconfig: unknown environment variables: PLATFORM_DATABASE_URL" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	m.Strict = false
+	if err := m.Load(p); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Port != 8080 || p.Database.URL != "postgres://db" || p.Cache.URL != "redis://cache" || p.Cache.TTL != 60 {
+		t.Errorf("fields not loaded: %+v", p)
+	}
+
+	want := []string{"CACHE_TTL", "REDIS_URL", "DATABASE_URL", "PORT"}
+	if diff := cmp.Diff(want, m.envNames(p)); diff != "" {
+		t.Errorf("unexpected names (-want +got):\n%s", diff)
+	}
+}
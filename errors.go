@@ -0,0 +1,99 @@
+package multiconfig
+
+import (
+	"errors"
+	"fmt"
+)
+
+// FieldError is the error of a field of the loaded struct, such as a value
+// of a source which can't be converted to the type of the field or a field
+// failing validation. The failures are mapped back to their field with
+// errors.As, which also finds each of the ValidationErrors:
+//
+//	var fe *multiconfig.FieldError
+//	if errors.As(err, &fe) {
+//		failures.WithLabelValues(fe.Path).Inc()
+//	}
+type FieldError struct {
+	// Path is the path of the field, i.e: "Postgres.Port".
+	Path string
+
+	// Loader is the source of the value which failed, i.e: "env SERVER_PORT",
+	// "flag -port" or "config.toml". It's empty for validation errors.
+	Loader string
+
+	// Value is the value which failed, if known.
+	Value interface{}
+
+	// Err is the cause of the failure.
+	Err error
+
+	// msg is the message of the error, if not the default one.
+	msg string
+}
+
+// Error returns the message of the error, naming the field by its path.
+func (e *FieldError) Error() string {
+	if e.msg != "" {
+		return e.msg
+	}
+
+	return fmt.Sprintf("multiconfig: field '%s': %s", e.Path, e.Err)
+}
+
+// Unwrap returns the cause of the error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// fieldErr returns the error of the value of the field at path which can't
+// be loaded, because of err.
+func fieldErr(path string, value interface{}, err error) *FieldError {
+	return &FieldError{Path: path, Value: value, Err: err}
+}
+
+// fieldErrorf returns the error of the field at path holding value, the
+// formatted text following the path in the message, i.e: "is required".
+func fieldErrorf(path string, value interface{}, format string, args ...interface{}) *FieldError {
+	err := fmt.Errorf(format, args...)
+	return &FieldError{
+		Path:  path,
+		Value: value,
+		Err:   err,
+		msg:   fmt.Sprintf("multiconfig: field '%s' %s", path, err),
+	}
+}
+
+// withLoader sets the loader of err, if it's a FieldError whose loader is
+// unknown, and returns it.
+func withLoader(err error, loader string) error {
+	var fe *FieldError
+	if errors.As(err, &fe) && fe.Loader == "" {
+		fe.Loader = loader
+	}
+
+	return err
+}
+
+// DecodeError is the error of a source which can't be decoded at all, such
+// as a file with a syntax error.
+type DecodeError struct {
+	// Format is the format of the source, i.e: "toml".
+	Format string
+
+	// Source is the name of the source, i.e: the path of the file.
+	Source string
+
+	// Err is the error of the decoder of the format.
+	Err error
+}
+
+// Error returns the message of the error, naming the source.
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("multiconfig: cannot decode %s: %s", e.Source, e.Err)
+}
+
+// Unwrap returns the error of the decoder.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,77 @@
+package multiconfig
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFieldError(t *testing.T) {
+	m := newDefaultLoader(&TagLoader{}, &EnvironmentLoader{getenv: testEnvironment{
+		"SERVER_POSTGRES_PORT": "http",
+	}.get})
+
+	err := m.Load(new(Server))
+
+	var fe *FieldError
+	if !errors.As(err, &fe) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if fe.Path != "Postgres.Port" || fe.Loader != "env SERVER_POSTGRES_PORT" || fe.Value != "http" || fe.Err == nil {
+		t.Errorf("unexpected field error: %+v", fe)
+	}
+
+	if !strings.HasPrefix(err.Error(), "multiconfig: field 'Postgres.Port': ") {
+		t.Errorf("unexpected message: %s", err)
+	}
+
+	err = (&TOMLLoader{Reader: strings.NewReader("name = 1")}).Load(new(Server))
+	if !errors.As(err, &fe) || fe.Path != "Name" || fe.Loader != "toml" || fe.Value != int64(1) {
+		t.Errorf("unexpected error: %#v", err)
+	}
+}
+
+func TestValidationFieldErrors(t *testing.T) {
+	type Listener struct {
+		Host string `required:"true"`
+		Port int    `max:"65535"`
+	}
+
+	err := newDefaultLoader().Validate(&Listener{Port: 70000})
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	paths := map[string]interface{}{}
+	for _, err := range errs {
+		var fe *FieldError
+		if !errors.As(err, &fe) {
+			t.Fatalf("not a field error: %v", err)
+		}
+		paths[fe.Path] = fe.Value
+	}
+
+	if len(paths) != 2 || paths["Host"] != "" || paths["Port"] != 70000 {
+		t.Errorf("unexpected field errors: %v", paths)
+	}
+
+	if err.Error() != "multiconfig: field 'Host' is required\nmulticonfig: field 'Port' must be at most 65535, got 70000" {
+		t.Errorf("unexpected message: %s", err)
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	err := (&JSONLoader{Path: "config.json", Reader: strings.NewReader("{")}).Load(new(Server))
+
+	var de *DecodeError
+	if !errors.As(err, &de) || de.Format != "json" || de.Source != "config.json" {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+
+	if !strings.HasPrefix(err.Error(), "multiconfig: cannot decode config.json: ") {
+		t.Errorf("unexpected message: %s", err)
+	}
+}
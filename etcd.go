@@ -0,0 +1,211 @@
+package multiconfig
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// DefaultEtcdEndpoint is the etcd endpoint used if neither the Endpoints of
+// an EtcdLoader nor ETCDCTL_ENDPOINTS are set.
+const DefaultEtcdEndpoint = "http://127.0.0.1:2379"
+
+// EtcdLoader satisfies the loader interface. It loads the configuration from
+// the keys of an etcd v3 cluster starting with Prefix, through its JSON
+// gateway. The rest of each key is split on "/" into nested fields, so with a
+// prefix of "/myapp/config/" the key "/myapp/config/postgres/port" sets
+// Postgres.Port. Values are converted the same way environment variables
+// are.
+type EtcdLoader struct {
+	// Prefix is the prefix of the keys, i.e: "/myapp/config/".
+	Prefix string
+
+	// Endpoints are the addresses of the etcd members, tried in order. If
+	// empty, the comma separated ETCDCTL_ENDPOINTS is used, then
+	// DefaultEtcdEndpoint.
+	Endpoints []string
+
+	// Username and Password authenticate the requests, if set.
+	Username string
+	Password string
+
+	// Client is used to perform the requests. If nil, http.DefaultClient is
+	// used.
+	Client *http.Client
+
+	// Strict rejects the keys which don't match any field instead of
+	// ignoring them.
+	Strict bool
+
+	// Watch makes DefaultLoader.Watch poll the keys for changes, every
+	// RemoteWatchInterval.
+	Watch bool
+}
+
+// Load loads the source into the config defined by struct s
+func (e *EtcdLoader) Load(s interface{}) error {
+	return e.LoadContext(context.Background(), s)
+}
+
+// LoadContext is like Load but the requests are bound to the given context,
+// so they honor its cancellation and deadline.
+func (e *EtcdLoader) LoadContext(ctx context.Context, s interface{}) error {
+	pairs, err := e.pairs(ctx)
+	if err != nil {
+		return err
+	}
+
+	return decodeKV("etcd "+e.Prefix, e.Prefix, "/", pairs, s, e.Strict)
+}
+
+// revision returns the checksum of the keys, for Watch.
+func (e *EtcdLoader) revision(ctx context.Context) ([]byte, error) {
+	pairs, err := e.pairs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return kvSum(pairs), nil
+}
+
+// pairs returns the keys starting with the prefix and their values, from the
+// first endpoint answering.
+func (e *EtcdLoader) pairs(ctx context.Context) (map[string]string, error) {
+	if e.Prefix == "" {
+		return nil, ErrSourceNotSet
+	}
+
+	endpoints := e.Endpoints
+	if len(endpoints) == 0 {
+		if env := os.Getenv("ETCDCTL_ENDPOINTS"); env != "" {
+			endpoints = strings.Split(env, ",")
+		} else {
+			endpoints = []string{DefaultEtcdEndpoint}
+		}
+	}
+
+	var err error
+	for _, endpoint := range endpoints {
+		var pairs map[string]string
+		if pairs, err = e.rangePrefix(ctx, withScheme(strings.TrimSpace(endpoint))); err == nil {
+			return pairs, nil
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return nil, err
+}
+
+// rangePrefix reads the keys starting with the prefix from the given
+// endpoint.
+func (e *EtcdLoader) rangePrefix(ctx context.Context, endpoint string) (map[string]string, error) {
+	token := ""
+	if e.Username != "" {
+		var auth struct {
+			Token string `json:"token"`
+		}
+		err := e.post(ctx, endpoint+"/v3/auth/authenticate", "", map[string]string{
+			"name":     e.Username,
+			"password": e.Password,
+		}, &auth)
+		if err != nil {
+			return nil, err
+		}
+		token = auth.Token
+	}
+
+	var resp struct {
+		KVs []struct {
+			Key   []byte `json:"key"`
+			Value []byte `json:"value"`
+		} `json:"kvs"`
+	}
+	err := e.post(ctx, endpoint+"/v3/kv/range", token, map[string][]byte{
+		"key":       []byte(e.Prefix),
+		"range_end": prefixEnd([]byte(e.Prefix)),
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make(map[string]string, len(resp.KVs))
+	for _, kv := range resp.KVs {
+		pairs[string(kv.Key)] = string(kv.Value)
+	}
+
+	return pairs, nil
+}
+
+// post sends the request to the etcd JSON gateway and decodes the response
+// into out. Byte slices are base64 encoded both ways, as the gateway
+// expects.
+func (e *EtcdLoader) post(ctx context.Context, url, token string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("multiconfig: etcd: POST %s: unexpected status %s", url, resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("multiconfig: etcd: POST %s: %s", url, err)
+	}
+
+	return nil
+}
+
+// prefixEnd returns the end of the range of the keys starting with prefix:
+// the prefix with its last byte below 0xff incremented.
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte(nil), prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+
+	// every byte is 0xff, the range runs to the last key
+	return []byte{0}
+}
+
+// withScheme prepends "http://" to the address if it has no scheme, i.e:
+// "127.0.0.1:2379".
+func withScheme(addr string) string {
+	addr = strings.TrimSuffix(addr, "/")
+	if strings.Contains(addr, "://") {
+		return addr
+	}
+
+	return "http://" + addr
+}
@@ -0,0 +1,165 @@
+package multiconfig
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeEtcd serves the range requests of the etcd JSON gateway from kvs.
+type fakeEtcd struct {
+	sync.Mutex
+	kvs map[string]string
+
+	// token is required by the range requests if set, it's returned for
+	// the user "root" with the password "secret"
+	token string
+}
+
+func (f *fakeEtcd) set(key, value string) {
+	f.Lock()
+	defer f.Unlock()
+	f.kvs[key] = value
+}
+
+func (f *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	switch r.URL.Path {
+	case "/v3/auth/authenticate":
+		var req struct{ Name, Password string }
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name != "root" || req.Password != "secret" {
+			http.Error(w, "authentication failed", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"token": f.token})
+	case "/v3/kv/range":
+		if f.token != "" && r.Header.Get("Authorization") != f.token {
+			http.Error(w, "user name is empty", http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			Key      []byte `json:"key"`
+			RangeEnd []byte `json:"range_end"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		type kv struct {
+			Key   []byte `json:"key"`
+			Value []byte `json:"value"`
+		}
+		var kvs []kv
+		for key, val := range f.kvs {
+			if key >= string(req.Key) && key < string(req.RangeEnd) {
+				kvs = append(kvs, kv{[]byte(key), []byte(val)})
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"kvs": kvs})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newFakeEtcd() *fakeEtcd {
+	return &fakeEtcd{kvs: map[string]string{
+		"/myapp/config/name":                       "koding",
+		"/myapp/config/port":                       "6060",
+		"/myapp/config/enabled":                    "true",
+		"/myapp/config/id":                         "1234567890",
+		"/myapp/config/labels":                     "123,456",
+		"/myapp/config/users":                      "ankara,istanbul",
+		"/myapp/config/interval":                   "10s",
+		"/myapp/config/postgres/enabled":           "true",
+		"/myapp/config/postgres/port":              "5432",
+		"/myapp/config/postgres/hosts":             "192.168.2.1,192.168.2.2,192.168.2.3",
+		"/myapp/config/postgres/dbname":            "configdb",
+		"/myapp/config/postgres/availabilityratio": "8.23",
+		"/myapp/configuration/name":                "other",
+		"/other/name":                              "other",
+	}}
+}
+
+func TestEtcdLoader(t *testing.T) {
+	srv := httptest.NewServer(newFakeEtcd())
+	defer srv.Close()
+
+	s := &Server{}
+	l := &EtcdLoader{Prefix: "/myapp/config/", Endpoints: []string{srv.URL}}
+	if err := l.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	testStruct(t, s, getDefaultServer())
+}
+
+func TestEtcdLoaderAuth(t *testing.T) {
+	etcd := newFakeEtcd()
+	etcd.token = "token"
+	srv := httptest.NewServer(etcd)
+	defer srv.Close()
+
+	// the first endpoint is down, the second one is used
+	l := &EtcdLoader{
+		Prefix:    "/myapp/config/",
+		Endpoints: []string{"http://127.0.0.1:1", strings.TrimPrefix(srv.URL, "http://")},
+		Username:  "root",
+		Password:  "secret",
+	}
+
+	s := &Server{}
+	if err := l.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	testStruct(t, s, getDefaultServer())
+
+	l.Password = "wrong"
+	if err := l.Load(&Server{}); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected an authentication error, got: %v", err)
+	}
+}
+
+func TestEtcdLoaderStrict(t *testing.T) {
+	etcd := newFakeEtcd()
+	etcd.set("/myapp/config/postgres/protgres", "1")
+	srv := httptest.NewServer(etcd)
+	defer srv.Close()
+
+	l := &EtcdLoader{Prefix: "/myapp/config/", Endpoints: []string{srv.URL}, Strict: true}
+	err := l.Load(&Server{})
+	if err == nil || err.Error() != "multiconfig: unknown keys in etcd /myapp/config/: Postgres.protgres" {
+		t.Errorf("expected an unknown keys error, got: %v", err)
+	}
+}
+
+func TestKVTreeConflict(t *testing.T) {
+	_, err := kvTree("etcd /app/", "/app/", "/", map[string]string{
+		"/app/postgres":      "1",
+		"/app/postgres/port": "5432",
+	})
+	if err == nil || !strings.Contains(err.Error(), "holds both a value and other keys") {
+		t.Errorf("expected a conflict error, got: %v", err)
+	}
+}
+
+func TestPrefixEnd(t *testing.T) {
+	tests := []struct{ prefix, end string }{
+		{"/app/", "/app0"},
+		{"a\xff", "b"},
+		{"\xff\xff", "\x00"},
+	}
+
+	for _, test := range tests {
+		if end := string(prefixEnd([]byte(test.prefix))); end != test.end {
+			t.Errorf("prefixEnd(%q) = %q, want %q", test.prefix, end, test.end)
+		}
+	}
+}
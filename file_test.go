@@ -2,7 +2,11 @@ package multiconfig
 
 import (
 	"os"
+	"strings"
 	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestYAML(t *testing.T) {
@@ -31,6 +35,92 @@ func TestYAML_Reader(t *testing.T) {
 
 	testStruct(t, s, getDefaultServer())
 }
+
+func TestYAMLDocuments(t *testing.T) {
+	data := `
+base: &base
+  enabled: true
+  port: 5432
+  hosts: [db1, db2]
+name: koding
+postgres:
+  <<: *base
+  port: 5433
+---
+---
+port: 7070
+postgres:
+  dbname: prod
+`
+
+	s := &Server{}
+	if err := (&YAMLLoader{Reader: strings.NewReader(data)}).Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Server{
+		Name: "koding",
+		Port: 7070,
+		Postgres: Postgres{
+			Enabled: true,
+			Port:    5433,
+			Hosts:   []string{"db1", "db2"},
+			DBName:  "prod",
+		},
+	}
+	if diff := cmp.Diff(want, s, cmp.AllowUnexported(Server{}, Postgres{})); diff != "" {
+		t.Errorf("unexpected config (-want +got):\n%s", diff)
+	}
+
+	err := (&YAMLLoader{Reader: strings.NewReader("name: koding\n--- [a]\n")}).Load(&Server{})
+	if err == nil || err.Error() != "multiconfig: cannot load the top-level sequence of yaml into *multiconfig.Server, a pointer to a slice is required" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err = (&YAMLLoader{Reader: strings.NewReader("--- koding\n")}).Load(&Server{})
+	if err == nil || err.Error() != "multiconfig: cannot decode yaml: document 1 is neither a mapping nor a sequence" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestYAMLSequence(t *testing.T) {
+	data := `
+- &web
+  scheme: http
+  host: web1
+  port: 80
+- <<: *web
+  host: web2
+- host: ${API_HOST}
+`
+
+	t.Setenv("API_HOST", "api")
+
+	var servers []AppServer
+	if err := (&YAMLLoader{Reader: strings.NewReader(data), FileOptions: FileOptions{ExpandEnv: true}}).Load(&servers); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []AppServer{
+		{Scheme: "http", Host: "web1", Port: 80},
+		{Scheme: "http", Host: "web2", Port: 80},
+		{Host: "api"},
+	}
+	if diff := cmp.Diff(want, servers); diff != "" {
+		t.Errorf("unexpected servers (-want +got):\n%s", diff)
+	}
+
+	err := (&YAMLLoader{Reader: strings.NewReader("- hots: web1\n"), FileOptions: FileOptions{Strict: true}}).Load(&servers)
+	if err == nil || !strings.HasPrefix(err.Error(), "multiconfig: unknown keys in yaml: ") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err = (&YAMLLoader{Reader: strings.NewReader("host: web1\n")}).Load(&servers)
+	if err == nil || err.Error() != "multiconfig: cannot load yaml into *[]multiconfig.AppServer, a top-level sequence is required" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
 func TestToml(t *testing.T) {
 	m := NewWithPath(testTOML)
 
@@ -85,7 +175,286 @@ func TestJSON_Reader(t *testing.T) {
 	testStruct(t, s, getDefaultServer())
 }
 
+func TestHCL(t *testing.T) {
+	m := NewWithPath(testHCL)
+
+	s := &Server{}
+	if err := m.Load(s); err != nil {
+		t.Error(err)
+	}
+
+	testStruct(t, s, getDefaultServer())
+}
+
+func TestHCL_Reader(t *testing.T) {
+	f, err := os.Open(testHCL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	l := MultiLoader(&TagLoader{}, &HCLLoader{Reader: f})
+	s := &Server{}
+	if err := l.Load(s); err != nil {
+		t.Error(err)
+	}
+
+	testStruct(t, s, getDefaultServer())
+}
+
+func TestHCL_Syntax(t *testing.T) {
+	data := `
+// labels are nested keys
+service "web" {
+  port = 80
+  motd = <<-EOF
+    welcome
+      home
+    EOF
+}
+
+service "api" { port = 0x1F90, tags = ["a", "b",] }
+
+upstream { host = "a" }
+upstream { host = "b" }
+escaped = "say \"hi\"\n"
+unicode = "caf\u00e9 \U0001F600"
+template = "$${name} %%{if}"
+ratio: -1.5e3
+`
+
+	got, err := decodeHCL([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"service": map[string]interface{}{
+			"web": map[string]interface{}{"port": int64(80), "motd": "welcome\n  home\n"},
+			"api": map[string]interface{}{"port": int64(8080), "tags": []interface{}{"a", "b"}},
+		},
+		"upstream": []interface{}{
+			map[string]interface{}{"host": "a"},
+			map[string]interface{}{"host": "b"},
+		},
+		"escaped":  "say \"hi\"\n",
+		"unicode":  "café \U0001F600",
+		"template": "${name} %{if}",
+		"ratio":    -1500.0,
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("decoded hcl is wrong (-want +got):\n%s", diff)
+	}
+}
+
+func TestHCL_SyntaxError(t *testing.T) {
+	_, err := decodeHCL([]byte("name = \"koding\"\nport = \n}"))
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("got error %v, want it to report line 3", err)
+	}
+
+	err = (&HCLLoader{Reader: strings.NewReader("name = \"a\\qb\"")}).Load(&Server{})
+	if err == nil || err.Error() != "multiconfig: cannot decode hcl: line 1: invalid escape sequence \\q in string" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	for _, data := range []string{`name = "\u00"`, `name = "\u00zz"`, `name = "\UFFFFFFFF"`} {
+		if _, err := decodeHCL([]byte(data)); err == nil || !strings.Contains(err.Error(), "invalid escape sequence") {
+			t.Errorf("decoding %s: got error %v, want an invalid escape sequence", data, err)
+		}
+	}
+}
+
+func TestHCL_Expressions(t *testing.T) {
+	tests := map[string]string{
+		"name = \"${var.name}\"":         "unsupported template ${var.name}",
+		"name = \"%{if true}a%{endif}\"": "unsupported template %{if true}",
+		"name = <<EOF\n${var.x}\nEOF\n":  "unsupported template ${var.x}",
+		"name = var.name":                "unsupported expression var.name",
+		"port = 80 + 1":                  "expected a key",
+		"name = upper(\"koding\")":       "unsupported expression upper",
+		"port = local.port * 2":          "unsupported expression local.port",
+		"name = \"koding\"\nport = (80)": "line 2: unexpected character '('",
+	}
+
+	for data, want := range tests {
+		_, err := decodeHCL([]byte(data))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("decoding %q: got error %v, want %q", data, err, want)
+		}
+	}
+}
+
+func TestINI(t *testing.T) {
+	m := NewWithPath(testINI)
+
+	s := &Server{}
+	if err := m.Load(s); err != nil {
+		t.Error(err)
+	}
+
+	testStruct(t, s, getDefaultServer())
+}
+
+func TestINI_Reader(t *testing.T) {
+	f, err := os.Open(testINI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	l := MultiLoader(&TagLoader{}, &INILoader{Reader: f})
+	s := &Server{}
+	if err := l.Load(s); err != nil {
+		t.Error(err)
+	}
+
+	testStruct(t, s, getDefaultServer())
+}
+
+func TestINI_Syntax(t *testing.T) {
+	data := `
+root = 1
+[a.b]
+quoted = "x ; y"
+single: 'z'
+[a]
+c = d # comment
+`
+
+	got, err := decodeINI([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"root": "1",
+		"a": map[string]interface{}{
+			"b": map[string]interface{}{"quoted": "x ; y", "single": "z"},
+			"c": "d",
+		},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("decoded ini is wrong (-want +got):\n%s", diff)
+	}
+
+	for _, data := range []string{"[postgres", "[a..b]", "port"} {
+		if _, err := decodeINI([]byte(data)); err == nil {
+			t.Errorf("decoding %q should fail", data)
+		}
+	}
+
+	err = (&INILoader{Reader: strings.NewReader("name = koding\nport")}).Load(&Server{})
+	if err == nil || err.Error() != "multiconfig: cannot decode ini: line 2: expected key = value, got port" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestNewWithFS(t *testing.T) {
+	fsys := os.DirFS("testdata")
+	for _, path := range []string{"config.toml", "config.json", "config.yaml", "config.hcl", "config.ini"} {
+		s := &Server{}
+		if err := NewWithFS(fsys, path).Load(s); err != nil {
+			t.Errorf("%s: %s", path, err)
+			continue
+		}
+
+		testStruct(t, s, getDefaultServer())
+	}
+
+	fsys = fstest.MapFS{"config.yaml": {Data: []byte("name: embedded")}}
+	s := &Server{}
+	if err := MultiLoader(&YAMLLoader{Path: "config.yaml", FileOptions: FileOptions{FS: fsys}}).Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Name != "embedded" {
+		t.Errorf("Name is %q, want embedded", s.Name)
+	}
+
+	if err := (&TOMLLoader{Path: "config.toml", FileOptions: FileOptions{FS: fsys}}).Load(s); err != ErrFileNotFound {
+		t.Errorf("loading a missing file should fail with ErrFileNotFound, got: %v", err)
+	}
+}
+
+func TestStrict(t *testing.T) {
+	data := `
+Name = "koding"
+schemaVersion = 1
+Nmae = "typo"
+
+[protgres]
+port = 5432
+
+[Postgres]
+prot = 5432
+`
+
+	err := (&TOMLLoader{Reader: strings.NewReader(data)}).Load(&Server{})
+	if err != nil {
+		t.Fatalf("unknown keys should be ignored by default: %s", err)
+	}
+
+	err = (&TOMLLoader{Reader: strings.NewReader(data), FileOptions: FileOptions{Strict: true}}).Load(&Server{})
+	want := "multiconfig: unknown keys in toml: " +
+		`Nmae (did you mean "Name"?), Postgres.prot (did you mean "Postgres.Port"?), protgres (did you mean "Postgres"?)`
+	if err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+
+	// the TOML file also holds the App configuration
+	for _, path := range []string{testJSON, testYAML, testHCL, testINI} {
+		if err := NewStrictWithPath(path).Load(&Server{}); err != nil {
+			t.Errorf("%s: %s", path, err)
+		}
+	}
+}
+
 // func TestJSON2(t *testing.T) {
 // 	ExampleEnvironmentLoader()
 // 	ExampleTOMLLoader()
 // }
+
+func TestInclude(t *testing.T) {
+	s := new(Server)
+	if err := NewWithPath("testdata/include/main.toml").Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Server{
+		Name:  "koding",
+		Port:  6060,
+		Users: []string{"ankara", "istanbul"},
+		Postgres: Postgres{
+			Port:   5432,
+			Hosts:  []string{"192.168.2.1"},
+			DBName: "configdb",
+		},
+	}
+	testStruct(t, s, want)
+
+	s = new(Server)
+	if err := NewWithFS(os.DirFS("testdata"), "include/main.toml").Load(s); err != nil {
+		t.Fatal(err)
+	}
+	testStruct(t, s, want)
+
+	err := (&TOMLLoader{Path: "testdata/include/cycle.toml"}).Load(new(Server))
+	if err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Errorf("got error %v, want an include cycle", err)
+	}
+
+	// only config files may include other files
+	err = (&TOMLLoader{Reader: strings.NewReader("include = [\"/etc/passwd\"]\n")}).Load(new(Server))
+	if err == nil || err.Error() != "multiconfig: toml: include is only supported in config files" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// a struct with an Include field loads the key as any other
+	var c struct{ Include string }
+	if err := (&TOMLLoader{Path: "testdata/include/cycle.toml"}).Load(&c); err != nil || c.Include != "cycle.yaml" {
+		t.Errorf("Include is %q, err: %v", c.Include, err)
+	}
+}
@@ -1,19 +1,37 @@
 package multiconfig
 
 import (
+	"encoding"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/fatih/camelcase"
 	"github.com/fatih/structs"
 )
 
+// flagTag is the tag giving the name of the flag of a field and its short
+// alias, i.e: `flag:"db-url,d"`, or hiding the field from the flags with
+// `flag:"-"`.
+const flagTag = "flag"
+
 // FlagLoader satisfies the loader interface. It creates on the fly flags based
 // on the field names and parses them to load into the given pointer of struct
 // s.
+//
+// The flag tag replaces the name of the flag of a field, or the prefix of the
+// flags of a nested struct, and may add a short alias, i.e:
+//
+//	DBURL  string `flag:"db-url,d"`
+//	Secret string `flag:"-"`
+//
+// Each load parses the arguments with a flag set of its own, so a FlagLoader
+// may be used by several goroutines at once. Command then returns the
+// command of the load which completed last.
 type FlagLoader struct {
 	// Prefix prepends the prefix to each flag name i.e:
 	// --foo is converted to --prefix-foo.
@@ -34,6 +52,11 @@ type FlagLoader struct {
 	// "--access-key"
 	CamelCase bool
 
+	// NameTag, if set, is the tag whose names replace the names of the
+	// fields in the names of the flags, i.e: "json" for -db_name instead of
+	// -dbname for a field tagged `json:"db_name"`.
+	NameTag string
+
 	// EnvPrefix is just a placeholder to print the correct usages when an
 	// EnvLoader is used
 	EnvPrefix string
@@ -44,6 +67,10 @@ type FlagLoader struct {
 	// By default it's flag.ContinueOnError.
 	ErrorHandling flag.ErrorHandling
 
+	// SliceSeparator separates the elements of slice flags, i.e:
+	// -users "ankara;istanbul" with a separator of ";". The default is ",".
+	SliceSeparator string
+
 	// Args defines a custom argument list. If nil, os.Args[1:] is used.
 	Args []string
 
@@ -52,40 +79,240 @@ type FlagLoader struct {
 	// that will used in passed into the flag for Usage.
 	FlagUsageFunc func(name string) string
 
-	// only exists for testing.  This is the raw flagset that is to parse
+	// mu guards the flag set and the command of the last load.
+	mu sync.Mutex
+
+	// only exists for testing.  This is the raw flagset of the last load
+	flagSet *flag.FlagSet
+
+	// newValue, if set, creates the FlagValue of each field instead of a
+	// value setting the field as soon as the flag is parsed.
+	newValue func(s interface{}, path, name string, field *structs.Field) FlagValue
+
+	// UsageFunc, if set, prints the help of the flags instead of
+	// PrintOptions, i.e: on -help.
+	UsageFunc func(w io.Writer, options []Option)
+
+	// CommandFieldTag, if set, is the tag naming the subcommands held by
+	// nested structs, i.e: `cmd:"serve"` with a CommandFieldTag of "cmd".
+	// The arguments following the flags of the struct start with the name
+	// of a command, whose flags are loaded into its struct only, i.e:
+	// "myapp -debug serve -port 80" sets Debug and Serve.Port. A command
+	// struct may have subcommands too, and a nil pointer to a command struct
+	// is allocated once the command is given. See Command.
+	CommandFieldTag string
+
+	// SetFlag, if set, is the name of a repeatable flag setting any field by
+	// its dotted path, i.e: -set postgres.port=5433, like an OverrideLoader.
+	// The overrides are set once all the other flags are loaded.
+	SetFlag string
+
+	// command is the command given by the arguments of the last load.
+	command string
+}
+
+// flagDefs defines the flags of a struct for a FlagLoader and parses them.
+// It holds the state of a single load, so that a FlagLoader can load
+// several structs concurrently.
+type flagDefs struct {
+	*FlagLoader
+
+	// flagSet is the flag set the flags are defined on.
 	flagSet *flag.FlagSet
+
+	// register, if set, is given the flag of each field, found at path,
+	// instead of defining it on the flag set.
+	register func(path string, field *structs.Field, fl *Flag)
+
+	// command is the command given by the parsed arguments.
+	command string
 }
 
 // Load loads the source into the config defined by struct s
 func (f *FlagLoader) Load(s interface{}) error {
+	if isRaw(s) {
+		return nil
+	}
+
 	strct := structs.New(s)
 	structName := strct.Name()
 
 	flagSet := flag.NewFlagSet(structName, f.ErrorHandling)
-	f.flagSet = flagSet
+	d := &flagDefs{FlagLoader: f, flagSet: flagSet}
+	defer func() {
+		f.mu.Lock()
+		f.flagSet, f.command = d.flagSet, d.command
+		f.mu.Unlock()
+	}()
+
+	fields := strct.Fields()
+	d.processFields(s, "", fields)
+	flagSet.Usage = d.usage(flagSet, os.Args[0], s, "", fields)
+
+	var overrides overrideFlag
+	if f.SetFlag != "" {
+		flagSet.Var(&overrides, f.SetFlag, "Set a field by its path, i.e: postgres.port=5433. Repeatable.")
+	}
 
-	for _, field := range strct.Fields() {
-		f.processField(field.Name(), field)
+	args := filterArgs(os.Args[1:])
+	if f.Args != nil {
+		args = f.Args
 	}
 
-	flagSet.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
-		flagSet.PrintDefaults()
-		fmt.Fprintf(os.Stderr, "\nGenerated environment variables:\n")
-		e := &EnvironmentLoader{
-			Prefix:    f.EnvPrefix,
-			CamelCase: f.CamelCase,
+	if err := parseFlags(flagSet, args); err != nil {
+		return err
+	}
+
+	if err := d.loadCommand(s, "", fields, flagSet.Args()); err != nil {
+		return err
+	}
+
+	if len(overrides) == 0 {
+		return nil
+	}
+
+	return (&OverrideLoader{Overrides: overrides}).Load(s)
+}
+
+// Command returns the command given by the arguments of the last load, i.e:
+// "serve", or "db migrate" for the subcommand migrate of the command db. It
+// returns an empty string if no command was given. See CommandFieldTag.
+func (f *FlagLoader) Command() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.command
+}
+
+// processFields generates the flags of the given fields, found at prefix in
+// the struct s, except for the command structs.
+func (f *flagDefs) processFields(s interface{}, prefix string, fields []*structs.Field) {
+	for _, field := range fields {
+		if f.isCommand(field) {
+			continue
 		}
-		e.PrintEnvs(s)
-		fmt.Println("")
+
+		f.processField(s, prefix+field.Name(), f.fieldName(field), field)
 	}
+}
 
-	args := filterArgs(os.Args[1:])
-	if f.Args != nil {
-		args = f.Args
+// fieldName returns the name of the field in the names of the flags: the
+// name given by the value of its NameTag, if any, or else its name. It's
+// empty for a flattened struct, whose flags are named after the struct
+// holding it.
+func (f *FlagLoader) fieldName(field *structs.Field) string {
+	if field.IsExported() && isFlattened(reflect.TypeOf(field.Value()), field.Tag("structs")) {
+		return ""
 	}
 
-	return flagSet.Parse(args)
+	if f.NameTag != "" {
+		if name := nameFromTag(field.Tag(f.NameTag)); name != "" {
+			return name
+		}
+	}
+
+	return field.Name()
+}
+
+// joinFlagName joins the name of a struct and the name of one of its fields
+// into the name of the flag of the latter, i.e: "postgres-port".
+func joinFlagName(prefix, name string) string {
+	if prefix == "" || name == "" {
+		return prefix + name
+	}
+
+	return prefix + "-" + name
+}
+
+// isCommand reports whether the field holds the flags of a command.
+func (f *FlagLoader) isCommand(field *structs.Field) bool {
+	return f.CommandFieldTag != "" && field.Tag(f.CommandFieldTag) != ""
+}
+
+// loadCommand loads the flags of the command named by the first of args,
+// among the given fields found at prefix in the struct s, and then the ones
+// of its subcommand. The arguments are left alone if there are no commands.
+func (f *flagDefs) loadCommand(s interface{}, prefix string, fields []*structs.Field, args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+
+	hasCommands := false
+	for _, field := range fields {
+		if !f.isCommand(field) {
+			continue
+		}
+		hasCommands = true
+
+		if field.Tag(f.CommandFieldTag) != args[0] {
+			continue
+		}
+
+		if field.Kind() != reflect.Struct && !isStructPtr(field) {
+			return fmt.Errorf("multiconfig: field '%s' of command '%s' is not a struct", prefix+field.Name(), args[0])
+		}
+
+		if isStructPtr(field) && field.IsZero() {
+			if err := field.Set(reflect.New(reflect.TypeOf(field.Value()).Elem()).Interface()); err != nil {
+				return err
+			}
+		}
+
+		f.command = strings.TrimSpace(f.command + " " + args[0])
+
+		prefix += field.Name() + "."
+		fields = field.Fields()
+
+		flagSet := flag.NewFlagSet(args[0], f.ErrorHandling)
+		f.flagSet = flagSet
+		f.processFields(s, prefix, fields)
+		flagSet.Usage = f.usage(flagSet, os.Args[0]+" "+f.command, s, prefix, fields)
+
+		if err := parseFlags(flagSet, args[1:]); err != nil {
+			return err
+		}
+
+		return f.loadCommand(s, prefix, fields, flagSet.Args())
+	}
+
+	if !hasCommands {
+		return nil
+	}
+
+	return fmt.Errorf("multiconfig: unknown command '%s'", args[0])
+}
+
+// parseFlags parses args into the flags of flagSet. The error of an undefined
+// flag suggests the closest defined one, i.e: -prot for -port.
+func parseFlags(flagSet *flag.FlagSet, args []string) error {
+	err := flagSet.Parse(args)
+	if err == nil {
+		return nil
+	}
+
+	name := strings.TrimPrefix(err.Error(), "flag provided but not defined: -")
+	if name == err.Error() {
+		return err
+	}
+
+	var names []string
+	flagSet.VisitAll(func(fl *flag.Flag) { names = append(names, fl.Name) })
+
+	return fmt.Errorf("flag provided but not defined: %s", didYouMean("-", name, names))
+}
+
+// usage returns the usage function of the flag set of the given fields,
+// found at prefix in the struct s, printing the help of name.
+func (f *flagDefs) usage(flagSet *flag.FlagSet, name string, s interface{}, prefix string, fields []*structs.Field) func() {
+	return func() {
+		fmt.Fprintf(flagSet.Output(), "Usage of %s:\n", name)
+
+		usage := f.UsageFunc
+		if usage == nil {
+			usage = PrintOptions
+		}
+		usage(flagSet.Output(), f.options(s, prefix, fields))
+	}
 }
 
 func filterArgs(args []string) []string {
@@ -105,51 +332,125 @@ func filterArgs(args []string) []string {
 
 // processField generates a flag based on the given field and fieldName. If a
 // nested struct is detected, a flag for each field of that nested struct is
-// generated too.
-func (f *FlagLoader) processField(fieldName string, field *structs.Field) error {
+// generated too. path is the path of the field within the struct s.
+func (f *flagDefs) processField(s interface{}, path, fieldName string, field *structs.Field) error {
+	// the flag tag holds the name of the flag and its short alias
+	tag := strings.Split(field.Tag(flagTag), ",")
+	if tag[0] == "-" {
+		return nil
+	}
+
 	if f.CamelCase {
-		fieldName = strings.Join(camelcase.Split(fieldName), "-")
-		fieldName = strings.Replace(fieldName, "---", "-", -1)
+		fieldName = camelCaseFlagName(fieldName)
 	}
 
-	switch field.Kind() {
-	case reflect.Struct:
-		for _, ff := range field.Fields() {
-			flagName := fieldName + "-" + ff.Name()
+	if tag[0] != "" {
+		fieldName = tag[0]
+	}
+
+	switch {
+	case field.Kind() == reflect.Struct && !isUnmarshaler(field) || isNestedPtr(field):
+		var fields []*structs.Field
+		if isStructPtr(field) && field.IsZero() {
+			// the flags of a nil struct are registered for a zero struct,
+			// the pointer being allocated once one of them is set
+			fields = zeroStruct(field).Fields()
+		} else {
+			fields = field.Fields()
+		}
+
+		for _, ff := range fields {
+			flagName := joinFlagName(fieldName, f.fieldName(ff))
 
 			if f.Flatten {
 				// first check if it's set or not, because if we have duplicate
 				// we don't want to break the flag. Panic by giving a readable
 				// output
-				f.flagSet.VisitAll(func(fl *flag.Flag) {
-					if strings.ToLower(ff.Name()) == fl.Name {
+				f.visitAll(func(fl *flag.Flag) {
+					if strings.ToLower(f.fieldName(ff)) == fl.Name {
 						// already defined
 						panic(fmt.Sprintf("flag '%s' is already defined in outer struct", fl.Name))
 					}
 				})
 
-				flagName = ff.Name()
+				flagName = f.fieldName(ff)
 			}
 
-			if err := f.processField(flagName, ff); err != nil {
+			if err := f.processField(s, path+"."+ff.Name(), flagName, ff); err != nil {
 				return err
 			}
 		}
 	default:
-		// Add custom prefix to the flag if it's set
-		if f.Prefix != "" {
+		// Add custom prefix to the flag if it's set, the name given by the
+		// tag being used as is
+		if f.Prefix != "" && tag[0] == "" {
 			fieldName = f.Prefix + "-" + fieldName
 		}
 
 		// we only can get the value from expored fields, unexported fields panics
 		if field.IsExported() {
-			f.flagSet.Var(newFieldValue(field), flagName(fieldName), f.flagUsage(fieldName, field))
+			name := fieldName
+			if tag[0] == "" {
+				name = flagName(fieldName)
+			}
+
+			fl := &Flag{
+				Name:  name,
+				Usage: f.flagUsage(fieldName, field),
+				Value: f.value(s, path, name, field),
+			}
+
+			if len(tag) > 1 {
+				fl.Shorthand = tag[1]
+			}
+
+			if field.Kind() == reflect.Bool {
+				fl.NoOptDefVal = "true"
+			}
+
+			f.define(path, field, fl)
 		}
 	}
 
 	return nil
 }
 
+// define defines the flag, along with its shorthand, on the flag set, unless
+// the flags are handed to the register hook.
+func (f *flagDefs) define(path string, field *structs.Field, fl *Flag) {
+	if f.register != nil {
+		f.register(path, field, fl)
+		return
+	}
+
+	f.flagSet.Var(fl.Value, fl.Name, fl.Usage)
+	if fl.Shorthand != "" {
+		f.flagSet.Var(fl.Value, fl.Shorthand, fmt.Sprintf("Shorthand for -%s.", fl.Name))
+	}
+}
+
+// visitAll visits the flags already defined on the flag set, if any.
+func (f *flagDefs) visitAll(fn func(*flag.Flag)) {
+	if f.flagSet != nil {
+		f.flagSet.VisitAll(fn)
+	}
+}
+
+// value returns the FlagValue of the flag with the given name, setting the
+// field at the given path of the struct s.
+func (f *flagDefs) value(s interface{}, path, name string, field *structs.Field) FlagValue {
+	if f.newValue != nil {
+		return f.newValue(s, path, name, field)
+	}
+
+	v := newFieldValue(field)
+	v.sep = f.SliceSeparator
+	v.path = path
+	v.target = s
+	v.onSet = func() { markLoaded(s, path, "flag -"+name) }
+	return v
+}
+
 func (f *FlagLoader) flagUsage(fieldName string, field *structs.Field) string {
 	if f.FlagUsageFunc != nil {
 		return f.FlagUsageFunc(fieldName)
@@ -160,12 +461,29 @@ func (f *FlagLoader) flagUsage(fieldName string, field *structs.Field) string {
 		return usage
 	}
 
+	if desc := field.Tag(descTag); desc != "" {
+		return desc
+	}
+
 	return fmt.Sprintf("Change value of %s.", fieldName)
 }
 
 // fieldValue satisfies the flag.Value and flag.Getter interfaces
 type fieldValue struct {
 	field *structs.Field
+
+	// sep separates the elements of slice values
+	sep string
+
+	// path is the path of the field within the loaded struct
+	path string
+
+	// target, if set, is the loaded struct the field is looked up in when
+	// the value is set, so the field of a nil struct pointer is reached
+	target interface{}
+
+	// onSet is called when the value is set successfully
+	onSet func()
 }
 
 func newFieldValue(f *structs.Field) *fieldValue {
@@ -175,7 +493,22 @@ func newFieldValue(f *structs.Field) *fieldValue {
 }
 
 func (f *fieldValue) Set(val string) error {
-	return fieldSet(f.field, val)
+	field := f.field
+	if f.target != nil {
+		if target, ok := fieldByPath(f.target, f.path); ok {
+			field = target
+		}
+	}
+
+	if err := fieldSet(field, val, f.sep, f.path); err != nil {
+		return err
+	}
+
+	if f.onSet != nil {
+		f.onSet()
+	}
+
+	return nil
 }
 
 func (f *fieldValue) String() string {
@@ -183,6 +516,22 @@ func (f *fieldValue) String() string {
 		return ""
 	}
 
+	// types parsed by encoding.TextUnmarshaler are shown the same way
+	if m, ok := f.field.Value().(encoding.TextMarshaler); ok && !f.field.IsZero() {
+		if text, err := m.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+
+	// such as url.URL, whose String method has a pointer receiver
+	if v := reflect.ValueOf(f.field.Value()); v.Kind() == reflect.Struct && hasDecoder(v.Type()) {
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		if s, ok := p.Interface().(fmt.Stringer); ok {
+			return s.String()
+		}
+	}
+
 	return fmt.Sprintf("%v", f.field.Value())
 }
 
@@ -198,6 +547,11 @@ func (f *fieldValue) IsZero() bool {
 	return f.field == nil
 }
 
+// Type returns the type of the field, as needed by pflag.
+func (f *fieldValue) Type() string {
+	return reflect.TypeOf(f.field.Value()).String()
+}
+
 // This is an unexported interface, be careful about it.
 // https://code.google.com/p/go/source/browse/src/pkg/flag/flag.go?name=release#101
 func (f *fieldValue) IsBoolFlag() bool {
@@ -205,3 +559,20 @@ func (f *fieldValue) IsBoolFlag() bool {
 }
 
 func flagName(name string) string { return strings.ToLower(name) }
+
+// camelCaseKey is the key of the cached flag names split in words.
+type camelCaseKey struct {
+	name string
+}
+
+// camelCaseFlagName splits the words of the flag name given by the names of
+// a field and of the structs holding it, i.e: "Postgres-DBName" is
+// "Postgres-DB-Name". The names are cached, the fields of a type being named
+// the same way on each load.
+func camelCaseFlagName(name string) string {
+	return cached(camelCaseKey{name}, func() interface{} {
+		name := strings.Join(camelcase.Split(name), "-")
+		name = strings.Replace(name, "---", "-", -1)
+		return strings.Replace(name, "-_-", "_", -1)
+	}).(string)
+}
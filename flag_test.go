@@ -3,6 +3,7 @@ package multiconfig
 import (
 	"flag"
 	"net/url"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -250,3 +251,147 @@ func getFlags(t *testing.T, structName, prefix string) []string {
 
 	return args
 }
+
+func TestFlagSliceSeparator(t *testing.T) {
+	m := &FlagLoader{
+		SliceSeparator: ";",
+		Args:           []string{"-users", "ankara;istanbul", "-labels", "123;456"},
+	}
+
+	s := &Server{}
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	d := getDefaultServer()
+	if !reflect.DeepEqual(s.Users, d.Users) {
+		t.Errorf("Users value is wrong: %v, want: %v", s.Users, d.Users)
+	}
+
+	if !reflect.DeepEqual(s.Labels, d.Labels) {
+		t.Errorf("Labels value is wrong: %v, want: %v", s.Labels, d.Labels)
+	}
+}
+
+func TestFlagTag(t *testing.T) {
+	type Database struct {
+		URL  string `flag:"db-url,d"`
+		Pool int
+	}
+
+	type Cache struct {
+		URL  string
+		Pool int
+	}
+
+	type App struct {
+		Name     string
+		Secret   string `flag:"-"`
+		Database Database
+		Store    Cache `flag:"cache"`
+	}
+
+	m := &FlagLoader{
+		Prefix: "app",
+		Args:   []string{"-d", "postgres://db", "-app-cache-pool", "4", "-app-cache-url", "redis://cache", "-app-name", "api"},
+	}
+
+	s := &App{}
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Database.URL != "postgres://db" || s.Store.URL != "redis://cache" {
+		t.Errorf("URL values are wrong: %q, %q", s.Database.URL, s.Store.URL)
+	}
+
+	if s.Store.Pool != 4 || s.Name != "api" {
+		t.Errorf("Store.Pool or Name value is wrong: %d, %q", s.Store.Pool, s.Name)
+	}
+
+	// the long name and the alias set the same field, the tagged name being
+	// used as is
+	m.Args = []string{"-db-url", "postgres://other"}
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Database.URL != "postgres://other" {
+		t.Errorf("URL value is wrong: %q", s.Database.URL)
+	}
+
+	m.Args = []string{"-app-secret", "s3cr3t"}
+	if err := m.Load(s); err == nil {
+		t.Error("hidden field should not have a flag")
+	}
+}
+
+type CLI struct {
+	Debug bool
+	Serve struct {
+		Port int
+	} `cmd:"serve"`
+	DB *struct {
+		URL     string
+		Migrate struct {
+			Steps int
+		} `cmd:"migrate"`
+	} `cmd:"db"`
+}
+
+func TestFlagCommands(t *testing.T) {
+	m := &FlagLoader{
+		CommandFieldTag: "cmd",
+		Args:            []string{"-debug", "serve", "-port", "80"},
+	}
+
+	c := &CLI{}
+	if err := m.Load(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if !c.Debug || c.Serve.Port != 80 || c.DB != nil {
+		t.Errorf("unexpected config: %+v", c)
+	}
+
+	if m.Command() != "serve" {
+		t.Errorf("command is %q, want serve", m.Command())
+	}
+
+	// the command struct is allocated, its subcommand loaded after its flags
+	m.Args = []string{"db", "-url", "postgres://db", "migrate", "-steps", "3"}
+
+	c = &CLI{}
+	if err := m.Load(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.DB == nil || c.DB.URL != "postgres://db" || c.DB.Migrate.Steps != 3 || c.Serve.Port != 0 {
+		t.Errorf("unexpected config: %+v", c)
+	}
+
+	if m.Command() != "db migrate" {
+		t.Errorf("command is %q, want db migrate", m.Command())
+	}
+
+	// the flags of a command are unknown to the others
+	m.Args = []string{"-port", "80"}
+	if err := m.Load(&CLI{}); err == nil {
+		t.Error("flag of a command should be unknown globally")
+	}
+
+	m.Args = []string{"deploy"}
+	if err := m.Load(&CLI{}); err == nil || err.Error() != "multiconfig: unknown command 'deploy'" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestFlagUnknownSuggestion(t *testing.T) {
+	m := &FlagLoader{Args: []string{"-postgres-prot", "5433"}}
+
+	err := m.Load(&Server{})
+	want := `flag provided but not defined: -postgres-prot (did you mean "-postgres-port"?)`
+	if err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+}
@@ -0,0 +1,337 @@
+package multiconfig
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// gcpScope is the OAuth scope of the access tokens requested.
+	gcpScope = "https://www.googleapis.com/auth/cloud-platform"
+
+	// gcpTokenURI is the endpoint exchanging the credentials of a user for
+	// an access token.
+	gcpTokenURI = "https://oauth2.googleapis.com/token"
+)
+
+var (
+	// gcpMetadataEndpoint is the address of the metadata server of Compute
+	// Engine, GKE and Cloud Run.
+	gcpMetadataEndpoint = "http://metadata.google.internal"
+
+	// gcpNow returns the time the tokens are issued at.
+	gcpNow = time.Now
+
+	// gcpTokens caches the access tokens until they expire, keyed by the
+	// source of the credentials.
+	gcpTokens = struct {
+		sync.Mutex
+		m map[string]gcpToken
+	}{m: make(map[string]gcpToken)}
+)
+
+// gcpToken is an access token to the Google Cloud APIs.
+type gcpToken struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+
+	expiry time.Time
+}
+
+// gcpCredentials are the Application Default Credentials of a service
+// account key or of a user logged in with gcloud.
+type gcpCredentials struct {
+	Type string `json:"type"`
+
+	// the fields of a service account key
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	TokenURI     string `json:"token_uri"`
+
+	// the fields of a user
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// gcpGet performs an authorized GET request to a Google Cloud API and
+// returns the response body. service names the API in the errors, i.e:
+// "secretmanager".
+func gcpGet(ctx context.Context, client *http.Client, service, url string) ([]byte, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	token, err := gcpAccessToken(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("multiconfig: %s: %s", service, err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.Unmarshal(data, &apiErr)
+
+		err := fmt.Errorf("multiconfig: %s: GET %s: unexpected status %s", service, url, resp.Status)
+		if apiErr.Error.Message != "" {
+			err = fmt.Errorf("%s: %s", err, apiErr.Error.Message)
+		}
+
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// gcpAccessToken returns an access token for the Application Default
+// Credentials: the file named by GOOGLE_APPLICATION_CREDENTIALS, or else the
+// credentials of gcloud auth application-default login, or else the service
+// account of the instance, given by the metadata server.
+func gcpAccessToken(ctx context.Context, client *http.Client) (string, error) {
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		if p := gcloudCredentialsPath(); p != "" {
+			if _, err := os.Stat(p); err == nil {
+				path = p
+			}
+		}
+	}
+
+	key := path
+	if key == "" {
+		key = gcpMetadataEndpoint
+	}
+
+	gcpTokens.Lock()
+	token, ok := gcpTokens.m[key]
+	gcpTokens.Unlock()
+
+	// the token is renewed a minute before it expires, so it doesn't expire
+	// during a request
+	if ok && gcpNow().Add(time.Minute).Before(token.expiry) {
+		return token.AccessToken, nil
+	}
+
+	var err error
+	if path != "" {
+		token, err = gcpFileToken(ctx, client, path)
+	} else {
+		token, err = gcpMetadataToken(ctx, client)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	token.expiry = gcpNow().Add(time.Duration(token.ExpiresIn) * time.Second)
+
+	gcpTokens.Lock()
+	gcpTokens.m[key] = token
+	gcpTokens.Unlock()
+
+	return token.AccessToken, nil
+}
+
+// gcloudCredentialsPath returns the path of the credentials written by
+// gcloud auth application-default login.
+func gcloudCredentialsPath() string {
+	const name = "application_default_credentials.json"
+
+	if dir := os.Getenv("CLOUDSDK_CONFIG"); dir != "" {
+		return filepath.Join(dir, name)
+	}
+
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("APPDATA"), "gcloud", name)
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".config", "gcloud", name)
+}
+
+// gcpFileToken exchanges the credentials of the given file for an access
+// token.
+func gcpFileToken(ctx context.Context, client *http.Client, path string) (gcpToken, error) {
+	var creds gcpCredentials
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return gcpToken{}, fmt.Errorf("reading the credentials: %s", err)
+	}
+
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return gcpToken{}, fmt.Errorf("reading the credentials %s: %s", path, err)
+	}
+
+	form := url.Values{}
+	tokenURI := gcpTokenURI
+
+	switch creds.Type {
+	case "service_account":
+		if creds.TokenURI != "" {
+			tokenURI = creds.TokenURI
+		}
+
+		assertion, err := creds.assertion(tokenURI)
+		if err != nil {
+			return gcpToken{}, fmt.Errorf("signing the token request of %s: %s", creds.ClientEmail, err)
+		}
+
+		form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+		form.Set("assertion", assertion)
+	case "authorized_user":
+		form.Set("grant_type", "refresh_token")
+		form.Set("client_id", creds.ClientID)
+		form.Set("client_secret", creds.ClientSecret)
+		form.Set("refresh_token", creds.RefreshToken)
+	default:
+		return gcpToken{}, fmt.Errorf("unsupported credentials type %q in %s", creds.Type, path)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return gcpToken{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return gcpTokenResponse(client, req.WithContext(ctx))
+}
+
+// assertion returns the JWT, signed with the key of the service account,
+// requesting an access token from tokenURI.
+func (c gcpCredentials) assertion(tokenURI string) (string, error) {
+	block, _ := pem.Decode([]byte(c.PrivateKey))
+	if block == nil {
+		return "", errors.New("invalid private key")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return "", err
+		}
+	}
+
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", errors.New("the private key is not an RSA key")
+	}
+
+	now := gcpNow().Unix()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": c.PrivateKeyID})
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   c.ClientEmail,
+		"scope": gcpScope,
+		"aud":   tokenURI,
+		"iat":   now,
+		"exp":   now + 3600,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+// gcpMetadataToken returns an access token of the service account of the
+// instance.
+func gcpMetadataToken(ctx context.Context, client *http.Client) (gcpToken, error) {
+	// the metadata server answers quickly or isn't there at all, i.e: when
+	// not running on Google Cloud
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodGet,
+		gcpMetadataEndpoint+"/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	if err != nil {
+		return gcpToken{}, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	token, err := gcpTokenResponse(client, req.WithContext(ctx))
+	if err != nil {
+		return token, fmt.Errorf("no credentials found in the environment nor in the instance metadata: %s", err)
+	}
+
+	return token, nil
+}
+
+// gcpTokenResponse performs the request of an access token.
+func gcpTokenResponse(client *http.Client, req *http.Request) (gcpToken, error) {
+	var token gcpToken
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return token, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return token, fmt.Errorf("%s %s: unexpected status %s", req.Method, req.URL, resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return token, fmt.Errorf("%s %s: %s", req.Method, req.URL, err)
+	}
+
+	if token.AccessToken == "" {
+		return token, fmt.Errorf("%s %s: no access token returned", req.Method, req.URL)
+	}
+
+	return token, nil
+}
@@ -0,0 +1,266 @@
+package multiconfig
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// setGCPEnv sets the given Google Cloud environment variables, and unsets
+// the other ones, for the duration of a test. gcloud credentials are looked
+// for in an empty directory.
+func setGCPEnv(t *testing.T, env map[string]string) {
+	if _, ok := env["CLOUDSDK_CONFIG"]; !ok {
+		env["CLOUDSDK_CONFIG"] = t.TempDir()
+	}
+
+	for _, key := range []string{"GOOGLE_APPLICATION_CREDENTIALS", "GOOGLE_CLOUD_PROJECT", "CLOUDSDK_CONFIG"} {
+		old, ok := os.LookupEnv(key)
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(key, old)
+			} else {
+				os.Unsetenv(key)
+			}
+		})
+
+		if val, set := env[key]; set {
+			os.Setenv(key, val)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+// serviceAccountKey writes the key of a service account requesting its
+// tokens from tokenURI, and returns its path and its public key.
+func serviceAccountKey(t *testing.T, tokenURI string) (string, *rsa.PublicKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"client_email":   "app@my-project.iam.gserviceaccount.com",
+		"private_key_id": "key1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":      tokenURI,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "key.json")
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path, &key.PublicKey
+}
+
+// gcpTokenHandler answers the token requests signed by the key of the
+// service account with the access token "token".
+func gcpTokenHandler(t *testing.T, pub *rsa.PublicKey, requests *int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+
+		parts := strings.Split(r.FormValue("assertion"), ".")
+		if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || len(parts) != 3 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], sig); err != nil {
+			t.Errorf("invalid assertion signature: %s", err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var claims struct{ Iss, Scope string }
+		data, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		json.Unmarshal(data, &claims)
+		if claims.Iss != "app@my-project.iam.gserviceaccount.com" || claims.Scope != gcpScope {
+			t.Errorf("unexpected claims: %+v", claims)
+		}
+
+		w.Write([]byte(`{"access_token": "token", "expires_in": 3600, "token_type": "Bearer"}`))
+	}
+}
+
+func TestGCPSecretManagerLoader(t *testing.T) {
+	var tokens int32
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	path, pub := serviceAccountKey(t, srv.URL+"/token")
+	setGCPEnv(t, map[string]string{
+		"GOOGLE_APPLICATION_CREDENTIALS": path,
+		"GOOGLE_CLOUD_PROJECT":           "my-project",
+	})
+
+	secrets := map[string]string{
+		"/v1/projects/my-project/secrets/app-name/versions/latest:access": "koding",
+		"/v1/projects/other/secrets/db-port/versions/3:access":            "5432",
+	}
+
+	mux.HandleFunc("/token", gcpTokenHandler(t, pub, &tokens))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		value, ok := secrets[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"code": 404, "message": "Secret not found", "status": "NOT_FOUND"}}`))
+			return
+		}
+
+		w.Write([]byte(`{"payload": {"data": "` + base64.StdEncoding.EncodeToString([]byte(value)) + `"}}`))
+	})
+
+	l := &GCPSecretManagerLoader{
+		Secrets: map[string]string{
+			"Name":          "app-name",
+			"Postgres.Port": "projects/other/secrets/db-port/versions/3",
+		},
+		Endpoint: srv.URL,
+	}
+
+	s := &Server{}
+	if err := l.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Name != "koding" || s.Postgres.Port != 5432 {
+		t.Errorf("secrets not loaded: %+v", s)
+	}
+
+	// the access token is reused until it expires
+	if err := l.Load(&Server{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if tokens != 1 {
+		t.Errorf("%d access tokens requested, want 1", tokens)
+	}
+
+	if sources := watchedSources(&GCPSecretManagerLoader{Secrets: l.Secrets, Watch: true}); len(sources) != 1 {
+		t.Errorf("the secrets should be watched")
+	}
+
+	errs := map[string]*GCPSecretManagerLoader{
+		"multiconfig: secretmanager: GET " + srv.URL + "/v1/projects/my-project/secrets/missing/versions/latest:access: unexpected status 404 Not Found: Secret not found": {
+			Secrets:  map[string]string{"Name": "missing"},
+			Endpoint: srv.URL,
+		},
+		"multiconfig: unknown keys in secretmanager my-project: Nmae (did you mean \"Name\"?)": {
+			Secrets:  map[string]string{"Nmae": "app-name"},
+			Endpoint: srv.URL,
+		},
+		ErrSourceNotSet.Error(): {},
+	}
+
+	for want, l := range errs {
+		if err := l.Load(&Server{}); err == nil || err.Error() != want {
+			t.Errorf("unexpected error:\n%v\nwant\n%s", err, want)
+		}
+	}
+}
+
+func TestGCSLoader(t *testing.T) {
+	config := []byte("name: koding\npostgres:\n  port: 5432\n")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/computeMetadata/v1/instance/service-accounts/default/token":
+			if r.Header.Get("Metadata-Flavor") != "Google" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			w.Write([]byte(`{"access_token": "metadata-token", "expires_in": 3600}`))
+		case "/storage/v1/b/my-bucket/o/app/config.yaml":
+			if r.Header.Get("Authorization") != "Bearer metadata-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			if r.URL.Query().Get("alt") == "media" {
+				w.Write(config)
+				return
+			}
+
+			w.Write([]byte(`{"name": "app/config.yaml", "generation": "1700000000", "metageneration": "1"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	setGCPEnv(t, map[string]string{})
+
+	defer func(endpoint string) { gcpMetadataEndpoint = endpoint }(gcpMetadataEndpoint)
+	gcpMetadataEndpoint = srv.URL
+
+	l := &GCSLoader{URL: "gs://my-bucket/app/config.yaml", Endpoint: srv.URL, Watch: true}
+
+	s := &Server{}
+	if err := l.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Name != "koding" || s.Postgres.Port != 5432 {
+		t.Errorf("config not loaded: %+v", s)
+	}
+
+	sources := watchedSources(l)
+	if len(sources) != 1 {
+		t.Fatalf("the file should be watched")
+	}
+
+	rev, err := sources[0].revision(context.Background())
+	if err != nil || string(rev) != "1700000000/1" {
+		t.Errorf("unexpected revision %q: %v", rev, err)
+	}
+
+	errs := map[string]*GCSLoader{
+		"multiconfig: gcs: invalid URL \"https://my-bucket/config.yaml\", gs://bucket/path is expected": {URL: "https://my-bucket/config.yaml"},
+		"multiconfig: gcs: unable to determine the config format of gs://my-bucket/config":              {URL: "gs://my-bucket/config"},
+		"multiconfig: gcs: GET " + srv.URL + "/storage/v1/b/my-bucket/o/missing.yaml?alt=media: unexpected status 404 Not Found": {
+			URL:      "gs://my-bucket/missing.yaml",
+			Endpoint: srv.URL,
+		},
+		ErrSourceNotSet.Error(): {},
+	}
+
+	for want, l := range errs {
+		if err := l.Load(&Server{}); err == nil || err.Error() != want {
+			t.Errorf("unexpected error:\n%v\nwant\n%s", err, want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package multiconfig
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// GCPSecretManagerLoader satisfies the loader interface. It loads the fields
+// of the config from the secrets of Google Cloud Secret Manager, each secret
+// setting the field it's mapped to:
+//
+//	&multiconfig.GCPSecretManagerLoader{
+//		Project: "my-project",
+//		Secrets: map[string]string{
+//			"Postgres.Password": "db-password",
+//			"API.Key":           "api-key",
+//		},
+//	}
+//
+// Values are converted the same way environment variables are. The requests
+// are authorized with the Application Default Credentials: the key of the
+// service account named by GOOGLE_APPLICATION_CREDENTIALS, or else the
+// credentials of gcloud auth application-default login, or else the service
+// account of the Compute Engine instance, GKE pod or Cloud Run service.
+type GCPSecretManagerLoader struct {
+	// Project is the ID of the project holding the secrets. If empty,
+	// GOOGLE_CLOUD_PROJECT is used.
+	Project string
+
+	// Secrets maps the path of each field, i.e: "Postgres.Password", to the
+	// name of the secret setting it. The latest version of the secret is
+	// read, unless the name is the full name of a version, i.e:
+	// "projects/my-project/secrets/db-password/versions/3".
+	Secrets map[string]string
+
+	// Endpoint is the address of the service. The default is
+	// https://secretmanager.googleapis.com.
+	Endpoint string
+
+	// Client is used to perform the requests. If nil, http.DefaultClient is
+	// used.
+	Client *http.Client
+
+	// Watch makes DefaultLoader.Watch poll the secrets for changes, such as
+	// a new version of a secret, every RemoteWatchInterval. Each poll
+	// accesses every secret, which is billed.
+	Watch bool
+}
+
+// Load loads the source into the config defined by struct s
+func (l *GCPSecretManagerLoader) Load(s interface{}) error {
+	return l.LoadContext(context.Background(), s)
+}
+
+// LoadContext is like Load but the requests are bound to the given context,
+// so they honor its cancellation and deadline.
+func (l *GCPSecretManagerLoader) LoadContext(ctx context.Context, s interface{}) error {
+	pairs, err := l.pairs(ctx)
+	if err != nil {
+		return err
+	}
+
+	// a path is given for each secret, one matching no field is a mistake
+	return decodeKV("secretmanager "+envOr(l.Project, "GOOGLE_CLOUD_PROJECT"), "", ".", pairs, s, true)
+}
+
+// revision returns the checksum of the secrets, for Watch.
+func (l *GCPSecretManagerLoader) revision(ctx context.Context) ([]byte, error) {
+	pairs, err := l.pairs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return kvSum(pairs), nil
+}
+
+// pairs returns the value of each secret, keyed by the path of its field.
+func (l *GCPSecretManagerLoader) pairs(ctx context.Context) (map[string]string, error) {
+	if len(l.Secrets) == 0 {
+		return nil, ErrSourceNotSet
+	}
+
+	project := envOr(l.Project, "GOOGLE_CLOUD_PROJECT")
+
+	endpoint := l.Endpoint
+	if endpoint == "" {
+		endpoint = "https://secretmanager.googleapis.com"
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+
+	// the secrets are read in the order of their fields, for the errors to
+	// be the same from one load to another
+	paths := make([]string, 0, len(l.Secrets))
+	for path := range l.Secrets {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	pairs := make(map[string]string, len(paths))
+	for _, path := range paths {
+		name := l.Secrets[path]
+		if !strings.HasPrefix(name, "projects/") {
+			if project == "" {
+				return nil, fmt.Errorf("multiconfig: secretmanager: project of secret %s is not set", name)
+			}
+
+			name = "projects/" + project + "/secrets/" + name + "/versions/latest"
+		}
+
+		data, err := gcpGet(ctx, l.Client, "secretmanager", endpoint+"/v1/"+name+":access")
+		if err != nil {
+			return nil, err
+		}
+
+		var resp struct {
+			Payload struct {
+				Data string `json:"data"`
+			} `json:"payload"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return nil, fmt.Errorf("multiconfig: secretmanager: %s: %s", name, err)
+		}
+
+		value, err := base64.StdEncoding.DecodeString(resp.Payload.Data)
+		if err != nil {
+			return nil, fmt.Errorf("multiconfig: secretmanager: %s: %s", name, err)
+		}
+
+		pairs[path] = string(value)
+	}
+
+	return pairs, nil
+}
@@ -0,0 +1,107 @@
+package multiconfig
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// GCSLoader satisfies the loader interface. It loads the configuration from
+// a file stored in a Google Cloud Storage bucket, whose format is determined
+// by its extension. The requests are authorized with the Application Default
+// Credentials, as the ones of the GCPSecretManagerLoader are.
+type GCSLoader struct {
+	// URL is the address of the file, i.e: "gs://bucket/config.yaml".
+	URL string
+
+	// Endpoint is the address of the service. The default is
+	// https://storage.googleapis.com.
+	Endpoint string
+
+	// Client is used to perform the requests. If nil, http.DefaultClient is
+	// used.
+	Client *http.Client
+
+	// Watch makes DefaultLoader.Watch poll the file for changes, every
+	// RemoteWatchInterval. Only the metadata of the file is read to know
+	// whether it changed.
+	Watch bool
+}
+
+// Load loads the source into the config defined by struct s
+func (g *GCSLoader) Load(s interface{}) error {
+	return g.LoadContext(context.Background(), s)
+}
+
+// LoadContext is like Load but the requests are bound to the given context,
+// so they honor its cancellation and deadline.
+func (g *GCSLoader) LoadContext(ctx context.Context, s interface{}) error {
+	object, err := g.object()
+	if err != nil {
+		return err
+	}
+
+	format := formatOf(path.Ext(g.URL))
+	if format == "" {
+		return fmt.Errorf("multiconfig: gcs: unable to determine the config format of %s", g.URL)
+	}
+
+	data, err := gcpGet(ctx, g.Client, "gcs", object+"?alt=media")
+	if err != nil {
+		return err
+	}
+
+	return readerLoader(format, bytes.NewReader(data)).Load(s)
+}
+
+// revision returns the generation of the file, for Watch.
+func (g *GCSLoader) revision(ctx context.Context) ([]byte, error) {
+	object, err := g.object()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := gcpGet(ctx, g.Client, "gcs", object)
+	if err != nil {
+		return nil, err
+	}
+
+	var meta struct {
+		Generation     string `json:"generation"`
+		Metageneration string `json:"metageneration"`
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("multiconfig: gcs: %s: %s", g.URL, err)
+	}
+
+	return []byte(meta.Generation + "/" + meta.Metageneration), nil
+}
+
+// object returns the address of the file in the JSON API of Cloud Storage.
+func (g *GCSLoader) object() (string, error) {
+	if g.URL == "" {
+		return "", ErrSourceNotSet
+	}
+
+	u, err := url.Parse(g.URL)
+	if err != nil {
+		return "", fmt.Errorf("multiconfig: gcs: %s", err)
+	}
+
+	name := strings.TrimPrefix(u.Path, "/")
+	if u.Scheme != "gs" || u.Host == "" || name == "" {
+		return "", fmt.Errorf("multiconfig: gcs: invalid URL %q, gs://bucket/path is expected", g.URL)
+	}
+
+	endpoint := g.Endpoint
+	if endpoint == "" {
+		endpoint = "https://storage.googleapis.com"
+	}
+
+	return strings.TrimSuffix(endpoint, "/") + "/storage/v1/b/" + url.PathEscape(u.Host) + "/o/" + url.PathEscape(name), nil
+}
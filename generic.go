@@ -0,0 +1,43 @@
+package multiconfig
+
+import (
+	"fmt"
+	"os"
+)
+
+// Load allocates a T, loads it from the given configuration files with the
+// DefaultLoader settings, as NewWithPaths does, validates it and returns it:
+//
+//	conf, err := multiconfig.Load[Server]("config.toml")
+//
+// Without paths, only the default tags, the environment variables and the
+// flags are loaded.
+func Load[T any](paths ...string) (*T, error) {
+	return LoadWith[T](NewWithPaths(paths...))
+}
+
+// LoadWith is like Load but uses the given loader.
+func LoadWith[T any](d *DefaultLoader) (*T, error) {
+	conf := new(T)
+	if err := d.LoadAndValidate(conf); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+// Must returns the configuration returned by Load or LoadWith. It exits if
+// the config cannot be loaded, like the MustLoad functions do:
+//
+//	conf := multiconfig.Must(multiconfig.Load[Server]("config.toml"))
+//
+// It stands for a generic MustLoad[T], the name MustLoad being taken by the
+// function loading into a given struct.
+func Must[T any](conf *T, err error) *T {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	return conf
+}
@@ -0,0 +1,33 @@
+package multiconfig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadGeneric(t *testing.T) {
+	s, err := Load[Server](testTOML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testStruct(t, s, getDefaultServer())
+}
+
+func TestLoadGenericValidation(t *testing.T) {
+	s, err := Load[Server]()
+	if err == nil || !strings.Contains(err.Error(), "required") {
+		t.Errorf("expected a required field error, got: %v", err)
+	}
+
+	if s != nil {
+		t.Errorf("no config should be returned on error, got %+v", s)
+	}
+}
+
+func TestLoadWith(t *testing.T) {
+	s := Must(LoadWith[Server](NewWithPaths(testJSON)))
+	if s.Name != "koding" || s.Postgres.Port != 5432 {
+		t.Errorf("config not loaded: %+v", s)
+	}
+}
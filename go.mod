@@ -1,6 +1,6 @@
 module github.com/ecochain-tech/multiconfig
 
-go 1.16
+go 1.18
 
 require (
 	github.com/BurntSushi/toml v1.0.0
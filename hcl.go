@@ -0,0 +1,519 @@
+package multiconfig
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// HCLLoader satisifies the loader interface. It loads the configuration from
+// the given hcl file or Reader.
+//
+// Attributes are assigned to the fields of the same name and blocks are
+// decoded into nested structs, the same way TOML tables are:
+//
+//	name = "koding"
+//
+//	postgres {
+//	  port  = 5432
+//	  hosts = ["192.168.2.1", "192.168.2.2"]
+//	}
+//
+// The labels of a block are nested keys, i.e: `service "web" { ... }` is the
+// "web" entry of a Service map. Repeating a block without labels makes a
+// list of objects.
+//
+// Only the literal subset of HCL is supported: strings, heredocs, numbers,
+// booleans, lists, objects and blocks. Expressions, such as references to
+// variables, function calls or operators, and the ${...} and %{...}
+// templates of strings are rejected, as there is nothing to evaluate them
+// against. "$${" and "%%{" are a literal "${" and "%{", so an environment
+// variable is referenced as "$${VAR}" along with ExpandEnv.
+type HCLLoader struct {
+	Path   string
+	Reader io.Reader
+
+	FileOptions
+}
+
+// Load loads the source into the config defined by struct s.
+// Defaults to using the Reader if provided, otherwise tries to read from the
+// file
+func (h *HCLLoader) Load(s interface{}) error {
+	data, err := readSource(h.FS, h.Path, h.Reader)
+	if err != nil {
+		return err
+	}
+
+	return decodeSource("hcl", data, s, h.decodeOptions("hcl", h.Path))
+}
+
+// hclToken kinds
+const (
+	hclEOF = iota
+	hclIdent
+	hclString
+	hclNumber
+	hclPunct
+)
+
+type hclToken struct {
+	kind int
+	text string
+	line int
+}
+
+func (t hclToken) String() string {
+	switch t.kind {
+	case hclEOF:
+		return "end of file"
+	case hclString:
+		return strconv.Quote(t.text)
+	}
+
+	return fmt.Sprintf("%q", t.text)
+}
+
+// hclParser parses the HCL syntax into a generic key tree, as returned by
+// decodeRaw.
+type hclParser struct {
+	src  []rune
+	pos  int
+	line int
+
+	tok hclToken
+}
+
+// decodeHCL decodes the HCL document data into a generic key tree.
+func decodeHCL(data []byte) (map[string]interface{}, error) {
+	p := &hclParser{src: []rune(string(data)), line: 1}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	return p.body(false)
+}
+
+// body parses attributes and blocks until the end of the document, or the
+// closing brace of a nested body, which is consumed.
+func (p *hclParser) body(nested bool) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+
+	for {
+		if p.isPunct(",") {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if !nested && p.tok.kind == hclEOF || nested && p.isPunct("}") {
+			return m, p.next()
+		}
+
+		if p.tok.kind != hclIdent && p.tok.kind != hclString {
+			return nil, p.errorf("expected a key, got %s", p.tok)
+		}
+
+		key := p.tok.text
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+
+		if p.isPunct("=") || p.isPunct(":") {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+
+			m[key] = v
+			continue
+		}
+
+		if err := p.block(m, key); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// block parses the labels and the body of the block named key and adds it
+// to m.
+func (p *hclParser) block(m map[string]interface{}, key string) error {
+	keys := []string{key}
+	for p.tok.kind == hclString {
+		keys = append(keys, p.tok.text)
+		if err := p.next(); err != nil {
+			return err
+		}
+	}
+
+	if !p.isPunct("{") {
+		return p.errorf("expected '=' or '{' after %q, got %s", key, p.tok)
+	}
+
+	if err := p.next(); err != nil {
+		return err
+	}
+
+	b, err := p.body(true)
+	if err != nil {
+		return err
+	}
+
+	// labels are nested keys, created or merged along the way
+	for _, k := range keys[:len(keys)-1] {
+		sub, ok := m[k].(map[string]interface{})
+		if !ok {
+			sub = make(map[string]interface{})
+			m[k] = sub
+		}
+		m = sub
+	}
+
+	last := keys[len(keys)-1]
+	switch prev := m[last].(type) {
+	case map[string]interface{}:
+		m[last] = []interface{}{prev, b}
+	case []interface{}:
+		m[last] = append(prev, b)
+	default:
+		m[last] = b
+	}
+
+	return nil
+}
+
+// value parses an attribute value.
+func (p *hclParser) value() (interface{}, error) {
+	tok := p.tok
+
+	switch {
+	case tok.kind == hclString:
+		return tok.text, p.next()
+	case tok.kind == hclNumber:
+		v, err := parseHCLNumber(tok.text)
+		if err != nil {
+			return nil, p.errorf("invalid number %s", tok.text)
+		}
+		return v, p.next()
+	case tok.kind == hclIdent && (tok.text == "true" || tok.text == "false"):
+		return tok.text == "true", p.next()
+	case p.isPunct("["):
+		return p.list()
+	case p.isPunct("{"):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		return p.body(true)
+	case tok.kind == hclIdent:
+		return nil, p.errorf("unsupported expression %s, only literal values are supported", tok.text)
+	}
+
+	return nil, p.errorf("expected a value, got %s", tok)
+}
+
+// list parses a list value, the opening bracket being the current token.
+func (p *hclParser) list() (interface{}, error) {
+	list := []interface{}{}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	for !p.isPunct("]") {
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+
+		if p.isPunct(",") {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		} else if !p.isPunct("]") {
+			return nil, p.errorf("expected ',' or ']', got %s", p.tok)
+		}
+	}
+
+	return list, p.next()
+}
+
+func parseHCLNumber(s string) (interface{}, error) {
+	if i, err := strconv.ParseInt(s, 0, 64); err == nil {
+		return i, nil
+	}
+
+	return strconv.ParseFloat(s, 64)
+}
+
+func (p *hclParser) isPunct(s string) bool {
+	return p.tok.kind == hclPunct && p.tok.text == s
+}
+
+func (p *hclParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", p.tok.line, fmt.Sprintf(format, args...))
+}
+
+// next reads the next token, skipping whitespaces and comments.
+func (p *hclParser) next() error {
+	if err := p.skip(); err != nil {
+		return err
+	}
+
+	p.tok = hclToken{line: p.line}
+	if p.pos == len(p.src) {
+		p.tok.kind = hclEOF
+		return nil
+	}
+
+	c := p.src[p.pos]
+	switch {
+	case c == '"':
+		return p.quoted()
+	case p.hasPrefix("<<"):
+		return p.heredoc()
+	case strings.ContainsRune("={}[],:", c):
+		p.pos++
+		p.tok.kind = hclPunct
+		p.tok.text = string(c)
+	case c == '-' || c == '+' || unicode.IsDigit(c):
+		p.tok.kind = hclNumber
+		p.tok.text = p.scan(func(r rune) bool {
+			return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("+-._", r)
+		})
+	case unicode.IsLetter(c) || c == '_':
+		p.tok.kind = hclIdent
+		p.tok.text = p.scan(func(r rune) bool {
+			return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_-.", r)
+		})
+	case strings.ContainsRune("()*/%<>!?&|", c):
+		return fmt.Errorf("line %d: unexpected character %q, expressions are not supported", p.line, c)
+	default:
+		return fmt.Errorf("line %d: unexpected character %q", p.line, c)
+	}
+
+	return nil
+}
+
+// skip skips whitespaces and comments.
+func (p *hclParser) skip() error {
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == '\n':
+			p.line++
+			p.pos++
+		case unicode.IsSpace(c):
+			p.pos++
+		case c == '#' || p.hasPrefix("//"):
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		case p.hasPrefix("/*"):
+			line := p.line
+			for p.pos += 2; !p.hasPrefix("*/"); p.pos++ {
+				if p.pos == len(p.src) {
+					return fmt.Errorf("line %d: unterminated comment", line)
+				}
+
+				if p.src[p.pos] == '\n' {
+					p.line++
+				}
+			}
+			p.pos += 2
+		default:
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// hasPrefix reports whether the source continues with the given prefix.
+func (p *hclParser) hasPrefix(prefix string) bool {
+	i := p.pos
+	for _, r := range prefix {
+		if i == len(p.src) || p.src[i] != r {
+			return false
+		}
+		i++
+	}
+
+	return true
+}
+
+// scan consumes the runes matching the given function.
+func (p *hclParser) scan(match func(rune) bool) string {
+	start := p.pos
+	for p.pos < len(p.src) && match(p.src[p.pos]) {
+		p.pos++
+	}
+
+	return string(p.src[start:p.pos])
+}
+
+// quoted reads a double quoted string.
+func (p *hclParser) quoted() error {
+	var b strings.Builder
+	for p.pos++; p.pos < len(p.src); p.pos++ {
+		c := p.src[p.pos]
+		switch c {
+		case '"':
+			p.pos++
+			p.tok.kind = hclString
+			return p.literal(b.String())
+		case '\n':
+			return fmt.Errorf("line %d: unterminated string", p.line)
+		case '\\':
+			p.pos++
+			if p.pos == len(p.src) {
+				break
+			}
+
+			switch e := p.src[p.pos]; e {
+			case 'n':
+				b.WriteRune('\n')
+			case 't':
+				b.WriteRune('\t')
+			case 'r':
+				b.WriteRune('\r')
+			case '"', '\\':
+				b.WriteRune(e)
+			case 'u', 'U':
+				n := 4
+				if e == 'U' {
+					n = 8
+				}
+
+				r, ok := p.hexRune(n)
+				if !ok {
+					return fmt.Errorf("line %d: invalid escape sequence \\%c in string", p.line, e)
+				}
+
+				b.WriteRune(r)
+			default:
+				return fmt.Errorf("line %d: invalid escape sequence \\%c in string", p.line, e)
+			}
+		default:
+			b.WriteRune(c)
+		}
+	}
+
+	return fmt.Errorf("line %d: unterminated string", p.line)
+}
+
+// hexRune decodes the code point of a \u or \U escape, given by the n
+// hexadecimal digits following the current position, and moves past them.
+func (p *hclParser) hexRune(n int) (rune, bool) {
+	if p.pos+n >= len(p.src) {
+		return 0, false
+	}
+
+	r, err := strconv.ParseUint(string(p.src[p.pos+1:p.pos+1+n]), 16, 32)
+	if err != nil || !utf8.ValidRune(rune(r)) {
+		return 0, false
+	}
+
+	p.pos += n
+	return rune(r), true
+}
+
+// literal sets the text of the current string token to s, which must not
+// hold any template: ${...} interpolations and %{...} directives are
+// rejected, "$${" and "%%{" being their escaped forms.
+func (p *hclParser) literal(s string) error {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "$${"), strings.HasPrefix(s[i:], "%%{"):
+			b.WriteString(s[i+1 : i+3])
+			i += 2
+		case strings.HasPrefix(s[i:], "${"), strings.HasPrefix(s[i:], "%{"):
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				end = len(s) - i - 1
+			}
+
+			return p.errorf("unsupported template %s, only literal strings are supported", s[i:i+end+1])
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	p.tok.text = b.String()
+	return nil
+}
+
+// heredoc reads a heredoc string, i.e:
+//
+//	motd = <<EOF
+//	welcome
+//	EOF
+//
+// With the <<- form, the indentation common to all lines is removed.
+func (p *hclParser) heredoc() error {
+	p.pos += 2
+	indent := false
+	if p.pos < len(p.src) && p.src[p.pos] == '-' {
+		indent = true
+		p.pos++
+	}
+
+	marker := p.scan(func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' })
+	if marker == "" || p.pos == len(p.src) || p.src[p.pos] != '\n' {
+		return fmt.Errorf("line %d: invalid heredoc", p.line)
+	}
+	p.pos++
+	p.line++
+
+	var lines []string
+	for p.pos < len(p.src) {
+		line := p.scan(func(r rune) bool { return r != '\n' })
+		if p.pos < len(p.src) {
+			p.pos++
+			p.line++
+		}
+
+		if strings.TrimSpace(line) == marker {
+			if indent {
+				lines = dedent(lines)
+			}
+
+			p.tok.kind = hclString
+			return p.literal(strings.Join(append(lines, ""), "\n"))
+		}
+
+		lines = append(lines, line)
+	}
+
+	return fmt.Errorf("line %d: unterminated heredoc %s", p.tok.line, marker)
+}
+
+// dedent removes the indentation common to all non blank lines.
+func dedent(lines []string) []string {
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+
+	for i, line := range lines {
+		if len(line) >= indent && indent > 0 {
+			lines[i] = line[indent:]
+		}
+	}
+
+	return lines
+}
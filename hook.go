@@ -0,0 +1,136 @@
+package multiconfig
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// DecodeFunc parses the raw value of a source, such as the value of an
+// environment variable, a flag or a key of a config file, into a value of
+// the type it's registered for.
+type DecodeFunc func(string) (interface{}, error)
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[reflect.Type]DecodeFunc{
+		reflect.TypeOf(url.URL{}):   decodeURL,
+		reflect.TypeOf(net.IPNet{}): decodeCIDR,
+	}
+)
+
+// decodeURL is the decoder of url.URL. net.IP needs none as it implements
+// encoding.TextUnmarshaler.
+func decodeURL(s string) (interface{}, error) {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return nil, err
+	}
+
+	return *u, nil
+}
+
+// decodeCIDR is the decoder of net.IPNet, parsing a CIDR notation such as
+// "192.168.0.0/16".
+func decodeCIDR(s string) (interface{}, error) {
+	_, n, err := net.ParseCIDR(strings.TrimSpace(s))
+	if err != nil {
+		return nil, err
+	}
+
+	return *n, nil
+}
+
+// RegisterDecoder registers fn to parse the fields of type t, or of pointer
+// to t, from every source. It takes precedence over the other ways a field
+// is parsed, such as encoding.TextUnmarshaler, and lets types multiconfig
+// doesn't know about be used in the config:
+//
+//	multiconfig.RegisterDecoder(reflect.TypeOf(decimal.Decimal{}), func(s string) (interface{}, error) {
+//		return decimal.NewFromString(s)
+//	})
+//
+// The value returned by fn must be assignable to t. Registering a nil fn
+// removes the decoder of t. Decoders of url.URL and net.IPNet are
+// registered by default.
+func RegisterDecoder(t reflect.Type, fn DecodeFunc) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	defer resetTypeCache()
+
+	if fn == nil {
+		delete(decoders, t)
+		return
+	}
+
+	decoders[t] = fn
+}
+
+// registeredDecoder returns the decoder registered for the type t.
+func registeredDecoder(t reflect.Type) (DecodeFunc, bool) {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+
+	fn, ok := decoders[t]
+	return fn, ok
+}
+
+// hasDecoder reports whether a decoder is registered for the type t or, if t
+// is a pointer, for the type it points to.
+func hasDecoder(t reflect.Type) bool {
+	if _, ok := registeredDecoder(t); ok {
+		return true
+	}
+
+	if t.Kind() == reflect.Ptr {
+		_, ok := registeredDecoder(t.Elem())
+		return ok
+	}
+
+	return false
+}
+
+// decodeHook sets v from the raw value s with the decoder registered for the
+// type of v, if any. A nil pointer to a registered type is allocated. It
+// reports whether a decoder is registered.
+func decodeHook(v reflect.Value, s string) (bool, error) {
+	fn, ok := registeredDecoder(v.Type())
+	if !ok && v.Kind() == reflect.Ptr {
+		if fn, ok = registeredDecoder(v.Type().Elem()); ok {
+			// a new value is allocated, so a value shared with another
+			// pointer isn't modified
+			elem := reflect.New(v.Type().Elem())
+			if _, err := decodeHook(elem.Elem(), s); err != nil {
+				return true, err
+			}
+
+			v.Set(elem)
+			return true, nil
+		}
+	}
+
+	if !ok {
+		return false, nil
+	}
+
+	val, err := fn(s)
+	if err != nil {
+		return true, err
+	}
+
+	rv := reflect.ValueOf(val)
+	if !rv.IsValid() {
+		v.Set(reflect.Zero(v.Type()))
+		return true, nil
+	}
+
+	if !rv.Type().AssignableTo(v.Type()) {
+		return true, fmt.Errorf("decoder of %s returned a %s", v.Type(), rv.Type())
+	}
+
+	v.Set(rv)
+	return true, nil
+}
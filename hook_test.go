@@ -0,0 +1,167 @@
+package multiconfig
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Money is a struct parsed from a string by a registered decoder.
+type Money struct {
+	Cents int64
+}
+
+func parseMoney(s string) (interface{}, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, errors.New("invalid amount " + s)
+	}
+
+	return Money{Cents: int64(f*100 + 0.5)}, nil
+}
+
+type Billing struct {
+	Price    Money `default:"9.99"`
+	Discount *Money
+	Fees     []Money
+	Zone     *time.Location
+}
+
+func TestRegisterDecoder(t *testing.T) {
+	RegisterDecoder(reflect.TypeOf(Money{}), parseMoney)
+	RegisterDecoder(reflect.TypeOf((*time.Location)(nil)), func(s string) (interface{}, error) {
+		return time.LoadLocation(s)
+	})
+	defer RegisterDecoder(reflect.TypeOf(Money{}), nil)
+	defer RegisterDecoder(reflect.TypeOf((*time.Location)(nil)), nil)
+
+	sources := map[string]Loader{
+		"json": &JSONLoader{Reader: strings.NewReader(`{"Discount": 1.5, "Fees": ["0.25", "0.5"], "Zone": "UTC"}`)},
+		"yaml": &YAMLLoader{Reader: strings.NewReader("discount: 1.5\nfees: [0.25, 0.5]\nzone: UTC\n")},
+		"env": &EnvironmentLoader{getenv: testEnvironment{
+			"BILLING_DISCOUNT": "1.5",
+			"BILLING_FEES":     "0.25,0.5",
+			"BILLING_ZONE":     "UTC",
+		}.get},
+		"flags": &FlagLoader{Args: []string{"-discount", "1.5", "-fees", "0.25,0.5", "-zone", "UTC"}},
+	}
+
+	for name, loader := range sources {
+		b := &Billing{}
+		if err := MultiLoader(&TagLoader{}, loader).Load(b); err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+
+		if b.Price.Cents != 999 {
+			t.Errorf("%s: price is %d cents, want 999", name, b.Price.Cents)
+		}
+
+		if b.Discount == nil || b.Discount.Cents != 150 {
+			t.Errorf("%s: discount is %v, want 150 cents", name, b.Discount)
+		}
+
+		if !reflect.DeepEqual(b.Fees, []Money{{25}, {50}}) {
+			t.Errorf("%s: fees are %v", name, b.Fees)
+		}
+
+		if b.Zone != time.UTC {
+			t.Errorf("%s: zone is %v, want UTC", name, b.Zone)
+		}
+	}
+
+	err := (&EnvironmentLoader{getenv: testEnvironment{"BILLING_PRICE": "free"}.get}).Load(&Billing{})
+	if err == nil || err.Error() != "multiconfig: field 'Price': invalid amount free" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err = (&JSONLoader{Reader: strings.NewReader(`{"Zone": "Nowhere/City"}`)}).Load(&Billing{})
+	if err == nil || !strings.HasPrefix(err.Error(), "multiconfig: field 'Zone': ") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRegisterDecoderType(t *testing.T) {
+	RegisterDecoder(reflect.TypeOf(Money{}), func(s string) (interface{}, error) {
+		return s, nil
+	})
+	defer RegisterDecoder(reflect.TypeOf(Money{}), nil)
+
+	err := (&FlagLoader{Args: []string{"-price", "1"}}).Load(&Billing{})
+	if err == nil || !strings.Contains(err.Error(), "decoder of multiconfig.Money returned a string") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+type Service struct {
+	Endpoint url.URL `default:"https://example.com/api"`
+	Proxy    *url.URL
+	Subnet   net.IPNet
+	Allowed  []net.IPNet
+	Gateway  net.IP
+}
+
+func TestBuiltinDecoders(t *testing.T) {
+	sources := map[string]Loader{
+		"json": &JSONLoader{Reader: strings.NewReader(
+			`{"Proxy": "http://proxy:3128", "Subnet": "10.0.0.0/8", "Allowed": ["192.168.0.0/16", "fd00::/8"], "Gateway": "10.0.0.1"}`)},
+		"yaml": &YAMLLoader{Reader: strings.NewReader(
+			"proxy: http://proxy:3128\nsubnet: 10.0.0.0/8\nallowed: [192.168.0.0/16, fd00::/8]\ngateway: 10.0.0.1\n")},
+		"env": &EnvironmentLoader{getenv: testEnvironment{
+			"SERVICE_PROXY":   "http://proxy:3128",
+			"SERVICE_SUBNET":  "10.0.0.0/8",
+			"SERVICE_ALLOWED": "192.168.0.0/16,fd00::/8",
+			"SERVICE_GATEWAY": "10.0.0.1",
+		}.get},
+		"flags": &FlagLoader{Args: []string{
+			"-proxy", "http://proxy:3128", "-subnet", "10.0.0.0/8",
+			"-allowed", "192.168.0.0/16,fd00::/8", "-gateway", "10.0.0.1",
+		}},
+	}
+
+	for name, loader := range sources {
+		s := &Service{}
+		if err := MultiLoader(&TagLoader{}, loader).Load(s); err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+
+		if s.Endpoint.Host != "example.com" || s.Endpoint.Path != "/api" {
+			t.Errorf("%s: endpoint is %s", name, s.Endpoint.String())
+		}
+
+		if s.Proxy == nil || s.Proxy.Port() != "3128" {
+			t.Errorf("%s: proxy is %v", name, s.Proxy)
+		}
+
+		if s.Subnet.String() != "10.0.0.0/8" || !s.Subnet.Contains(net.IPv4(10, 1, 2, 3)) {
+			t.Errorf("%s: subnet is %s", name, s.Subnet.String())
+		}
+
+		if len(s.Allowed) != 2 || s.Allowed[1].String() != "fd00::/8" {
+			t.Errorf("%s: allowed networks are %v", name, s.Allowed)
+		}
+
+		if !s.Gateway.Equal(net.IPv4(10, 0, 0, 1)) {
+			t.Errorf("%s: gateway is %s", name, s.Gateway)
+		}
+	}
+
+	tests := map[string]string{
+		"-subnet=10.0.0.0":   "multiconfig: field 'Subnet': invalid CIDR address: 10.0.0.0",
+		"-gateway=10.0.0":    "multiconfig: field 'Gateway': invalid IP address: 10.0.0",
+		"-endpoint=10.0.0.%": "multiconfig: field 'Endpoint': parse \"10.0.0.%\": invalid URL escape \"%\"",
+	}
+
+	for arg, want := range tests {
+		err := (&FlagLoader{Args: []string{arg}}).Load(&Service{})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: unexpected error: %v", arg, err)
+		}
+	}
+}
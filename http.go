@@ -0,0 +1,228 @@
+package multiconfig
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxBodySize is the maximum size of a response body read by the
+// HTTPLoader if its MaxBodySize is not set.
+const DefaultMaxBodySize = 10 << 20 // 10 MiB
+
+// HTTPLoader satisfies the loader interface. It loads the configuration from
+// the given URL with a GET request. The format of the response is determined
+// by its Content-Type header and, if it's not conclusive, by the extension of
+// the URL path.
+//
+// The last response is kept, and revalidated by the following requests with
+// its ETag and Last-Modified headers, so an unchanged configuration isn't
+// downloaded again.
+type HTTPLoader struct {
+	// URL is the address of the configuration.
+	URL string
+
+	// Headers are added to the request, i.e: an Authorization header.
+	Headers http.Header
+
+	// Timeout limits the time the request takes, if set.
+	Timeout time.Duration
+
+	// Client is used to perform the request. If nil, http.DefaultClient is
+	// used.
+	Client *http.Client
+
+	// MaxBodySize limits the size of the response body in bytes, bigger
+	// responses are rejected. If zero, DefaultMaxBodySize is used.
+	MaxBodySize int64
+
+	// Watch makes DefaultLoader.Watch poll the URL for changes, every
+	// RemoteWatchInterval.
+	Watch bool
+
+	mu    sync.Mutex
+	cache *httpResponse
+}
+
+// httpResponse is a response of the server of an HTTPLoader.
+type httpResponse struct {
+	data        []byte
+	contentType string
+
+	// etag and lastModified validate the response
+	etag         string
+	lastModified string
+}
+
+// NewWithURL returns a new instance of Loader to read from the configuration
+// served at the given URL.
+func NewWithURL(url string) *DefaultLoader {
+	return newDefaultLoader(
+		&TagLoader{},
+		&HTTPLoader{URL: url},
+		&EnvironmentLoader{},
+		&FlagLoader{},
+	)
+}
+
+// Load loads the source into the config defined by struct s
+func (h *HTTPLoader) Load(s interface{}) error {
+	return h.LoadContext(context.Background(), s)
+}
+
+// LoadContext is like Load but the request is bound to the given context, so
+// it honors its cancellation and deadline.
+func (h *HTTPLoader) LoadContext(ctx context.Context, s interface{}) error {
+	resp, err := h.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	format := contentTypeFormat(resp.contentType)
+	if format == "" {
+		format = urlFormat(h.URL)
+	}
+
+	loader := readerLoader(format, bytes.NewReader(resp.data))
+	if loader == nil {
+		return fmt.Errorf("multiconfig: GET %s: unable to determine the config format (Content-Type: %q)",
+			h.URL, resp.contentType)
+	}
+
+	return loader.Load(s)
+}
+
+// revision returns the checksum of the configuration, for Watch.
+func (h *HTTPLoader) revision(ctx context.Context) ([]byte, error) {
+	resp, err := h.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(resp.data)
+	return sum[:], nil
+}
+
+// fetch gets the configuration, or the last response if the server states
+// it's still valid.
+func (h *HTTPLoader) fetch(ctx context.Context) (*httpResponse, error) {
+	if h.URL == "" {
+		return nil, ErrSourceNotSet
+	}
+
+	req, err := http.NewRequest(http.MethodGet, h.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range h.Headers {
+		req.Header[key] = values
+	}
+
+	h.mu.Lock()
+	cache := h.cache
+	h.mu.Unlock()
+
+	if cache != nil {
+		if cache.etag != "" {
+			req.Header.Set("If-None-Match", cache.etag)
+		}
+		if cache.lastModified != "" {
+			req.Header.Set("If-Modified-Since", cache.lastModified)
+		}
+	}
+
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && cache != nil {
+		return cache, nil
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("multiconfig: GET %s: unexpected status %s", h.URL, resp.Status)
+	}
+
+	maxSize := h.MaxBodySize
+	if maxSize == 0 {
+		maxSize = DefaultMaxBodySize
+	}
+
+	// read one more byte than allowed to detect bodies exceeding the limit
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("multiconfig: GET %s: response body exceeds %d bytes", h.URL, maxSize)
+	}
+
+	cache = &httpResponse{
+		data:         data,
+		contentType:  resp.Header.Get("Content-Type"),
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}
+
+	h.mu.Lock()
+	h.cache = cache
+	h.mu.Unlock()
+
+	return cache, nil
+}
+
+// contentTypeFormat returns the format of the given Content-Type header
+// value. It returns an empty string if the media type is not recognized.
+func contentTypeFormat(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+
+	switch {
+	case mediaType == "application/toml", strings.HasSuffix(mediaType, "/x-toml"):
+		return "toml"
+	case mediaType == "application/json", strings.HasSuffix(mediaType, "+json"):
+		return "json"
+	case strings.HasSuffix(mediaType, "/yaml"), strings.HasSuffix(mediaType, "/x-yaml"):
+		return "yaml"
+	}
+
+	return ""
+}
+
+// urlFormat returns the format of the config based on the extension of the
+// URL path.
+func urlFormat(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return ""
+	}
+
+	return formatOf(path.Ext(u.Path))
+}
@@ -0,0 +1,222 @@
+package multiconfig
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newConfigServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/config":
+			w.Header().Set("Content-Type", "application/toml")
+			http.ServeFile(w, r, testTOML)
+		case "/config.json":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			http.ServeFile(w, r, testJSON)
+		case "/config.yaml":
+			// no Content-Type, the format is guessed from the extension
+			w.Header()["Content-Type"] = nil
+			http.ServeFile(w, r, testYAML)
+		case "/slow":
+			time.Sleep(100 * time.Millisecond)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestHTTPLoader(t *testing.T) {
+	srv := newConfigServer(t)
+	defer srv.Close()
+
+	for _, p := range []string{"/config", "/config.json", "/config.yaml"} {
+		l := MultiLoader(&TagLoader{}, &HTTPLoader{URL: srv.URL + p})
+
+		s := &Server{}
+		if err := l.Load(s); err != nil {
+			t.Fatalf("%s: %s", p, err)
+		}
+
+		testStruct(t, s, getDefaultServer())
+	}
+}
+
+func TestHTTPLoaderErrors(t *testing.T) {
+	srv := newConfigServer(t)
+	defer srv.Close()
+
+	err := (&HTTPLoader{URL: srv.URL + "/missing.toml"}).Load(&Server{})
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected an error with the 404 status, got: %v", err)
+	}
+
+	err = (&HTTPLoader{URL: srv.URL + "/config", MaxBodySize: 10}).Load(&Server{})
+	if err == nil || !strings.Contains(err.Error(), "exceeds 10 bytes") {
+		t.Errorf("expected a body size error, got: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err = (&HTTPLoader{URL: srv.URL + "/slow"}).LoadContext(ctx, &Server{})
+	if err == nil || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+		t.Errorf("expected a deadline error, got: %v", err)
+	}
+}
+
+func TestHTTPLoaderCache(t *testing.T) {
+	var requests, downloads int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		downloads++
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"Name": "koding"}`))
+	}))
+	defer srv.Close()
+
+	l := &HTTPLoader{URL: srv.URL, Headers: http.Header{"Authorization": {"Bearer token"}}}
+	for i := 0; i < 2; i++ {
+		s := &Server{}
+		if err := l.Load(s); err != nil {
+			t.Fatal(err)
+		}
+
+		if s.Name != "koding" {
+			t.Errorf("load %d: name is %q, want koding", i, s.Name)
+		}
+	}
+
+	if requests != 2 || downloads != 1 {
+		t.Errorf("got %d requests and %d downloads, want 2 requests and 1 download", requests, downloads)
+	}
+}
+
+func TestHTTPLoaderTimeout(t *testing.T) {
+	srv := newConfigServer(t)
+	defer srv.Close()
+
+	err := (&HTTPLoader{URL: srv.URL + "/slow", Timeout: 10 * time.Millisecond}).Load(&Server{})
+	if err == nil || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+		t.Errorf("expected a deadline error, got: %v", err)
+	}
+}
+
+func TestHTTPLoaderWatch(t *testing.T) {
+	var mu sync.Mutex
+	name := "koding"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		w.Header().Set("Last-Modified", name)
+		if r.Header.Get("If-Modified-Since") == name {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/toml")
+		fmt.Fprintf(w, "Name = %q\n[Postgres]\nPort = 5432\nHosts = [\"localhost\"]\n", name)
+	}))
+	defer srv.Close()
+
+	m := newDefaultLoader(&TagLoader{}, &HTTPLoader{URL: srv.URL, Watch: true})
+	m.RemoteWatchInterval = 10 * time.Millisecond
+
+	s := new(Server)
+	m.MustLoad(s)
+
+	changes := make(chan *Server, 1)
+	stop, err := m.Watch(s, func(old, new interface{}) {
+		changes <- new.(*Server)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	mu.Lock()
+	name = "reloaded"
+	mu.Unlock()
+
+	select {
+	case c := <-changes:
+		if c.Name != "reloaded" {
+			t.Errorf("reloaded name is %q", c.Name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("config change not notified")
+	}
+}
+
+func TestHTTPLoaderWatchStop(t *testing.T) {
+	hanging := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) > 1 {
+			select {
+			case hanging <- struct{}{}:
+			default:
+			}
+
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/toml")
+		fmt.Fprint(w, "Name = \"koding\"\n[Postgres]\nPort = 5432\nHosts = [\"localhost\"]\n")
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	m := newDefaultLoader(&TagLoader{}, &HTTPLoader{URL: srv.URL})
+	m.RefreshEvery(10 * time.Millisecond)
+
+	s := new(Server)
+	m.MustLoad(s)
+
+	stop, err := m.Watch(s, func(old, new interface{}) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-hanging:
+	case <-time.After(5 * time.Second):
+		t.Fatal("config not refreshed")
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("stop is blocked by the pending request")
+	}
+}
@@ -0,0 +1,110 @@
+package multiconfig
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// IncludeKey is the key of a config file listing other files to load, i.e:
+//
+//	include = ["db.toml", "secrets/*.yaml"]
+//
+// Relative paths are resolved against the directory of the including file
+// and may be glob patterns, matching files loaded in lexical order. The
+// included files are loaded first, so the keys of the including file
+// override theirs, and may include other files themselves. The key is matched
+// case insensitively and is only a directive if the struct has no field for
+// it. Included files are not watched by Watch. The key is rejected in the
+// sources which aren't files, such as readers and HTTP or remote sources.
+const IncludeKey = "include"
+
+// loadIncludes loads the files included by the decoded source raw into the
+// struct s, and removes the include directive from raw.
+func loadIncludes(format string, raw map[string]interface{}, s interface{}, opts decodeOptions) error {
+	t := reflect.TypeOf(s)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	if _, ok := keyFields(t.Elem(), format, opts.nameTag)[IncludeKey]; ok {
+		return nil
+	}
+
+	var patterns []string
+	for key, val := range raw {
+		if !strings.EqualFold(key, IncludeKey) {
+			continue
+		}
+		delete(raw, key)
+
+		switch val := val.(type) {
+		case string:
+			patterns = append(patterns, val)
+		case []interface{}:
+			for _, v := range val {
+				p, ok := v.(string)
+				if !ok {
+					return fmt.Errorf("multiconfig: %s: %s must list file paths, got %v", opts.source, key, v)
+				}
+				patterns = append(patterns, p)
+			}
+		default:
+			return fmt.Errorf("multiconfig: %s: %s must list file paths, got %v", opts.source, key, val)
+		}
+	}
+
+	// the sources which aren't files, such as a config served over HTTP,
+	// can't make the process read local files
+	if len(patterns) != 0 && opts.path == "" {
+		return fmt.Errorf("multiconfig: %s: %s is only supported in config files", opts.source, IncludeKey)
+	}
+
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			if opts.fsys != nil {
+				pattern = path.Join(path.Dir(opts.path), pattern)
+			} else {
+				pattern = filepath.Join(filepath.Dir(opts.path), pattern)
+			}
+		}
+
+		for _, file := range expandPath(opts.fsys, pattern) {
+			if err := includeFile(file, s, opts); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// includeFile loads the file included by the source described by opts into
+// the struct s.
+func includeFile(file string, s interface{}, opts decodeOptions) error {
+	for _, p := range append(opts.including, opts.path) {
+		if filepath.Clean(p) == filepath.Clean(file) {
+			return fmt.Errorf("multiconfig: %s: include cycle, %s is already being loaded", opts.source, file)
+		}
+	}
+
+	format := formatOf(file)
+	switch format {
+	case "", "env":
+		return fmt.Errorf("multiconfig: %s: cannot include %s, unsupported format", opts.source, file)
+	}
+
+	data, err := readSource(opts.fsys, file, nil)
+	if err != nil {
+		return fmt.Errorf("multiconfig: %s: include %s: %s", opts.source, file, err)
+	}
+
+	included := opts
+	included.source = file
+	included.path = file
+	included.including = append(append([]string(nil), opts.including...), opts.path)
+
+	return decodeSource(format, data, s, included)
+}
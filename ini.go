@@ -0,0 +1,107 @@
+package multiconfig
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// INILoader satisifies the loader interface. It loads the configuration from
+// the given ini file or Reader.
+//
+// Keys are assigned to the fields of the same name and sections are decoded
+// into nested structs, i.e: the keys of a [postgres] section are loaded into
+// Server.Postgres. Dots nest sections further, [postgres.replica] being the
+// Replica struct of Postgres. Values are converted the same way environment
+// variables are, so a slice is given as a comma separated list:
+//
+//	name = koding
+//
+//	[postgres]
+//	port  = 5432
+//	hosts = 192.168.2.1,192.168.2.2
+type INILoader struct {
+	Path   string
+	Reader io.Reader
+
+	FileOptions
+}
+
+// Load loads the source into the config defined by struct s.
+// Defaults to using the Reader if provided, otherwise tries to read from the
+// file
+func (i *INILoader) Load(s interface{}) error {
+	data, err := readSource(i.FS, i.Path, i.Reader)
+	if err != nil {
+		return err
+	}
+
+	return decodeSource("ini", data, s, i.decodeOptions("ini", i.Path))
+}
+
+// decodeINI decodes the INI document data into a generic key tree. All the
+// values are strings.
+func decodeINI(data []byte) (map[string]interface{}, error) {
+	raw := make(map[string]interface{})
+	section := raw
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: unterminated section %s", n, line)
+			}
+
+			section = raw
+			for _, name := range strings.Split(line[1:len(line)-1], ".") {
+				name = strings.TrimSpace(name)
+				if name == "" {
+					return nil, fmt.Errorf("line %d: invalid section %s", n, line)
+				}
+
+				sub, ok := section[name].(map[string]interface{})
+				if !ok {
+					sub = make(map[string]interface{})
+					section[name] = sub
+				}
+				section = sub
+			}
+			continue
+		}
+
+		i := strings.IndexAny(line, "=:")
+		if i <= 0 {
+			return nil, fmt.Errorf("line %d: expected key = value, got %s", n, line)
+		}
+
+		key := strings.TrimSpace(line[:i])
+		section[key] = parseINIValue(strings.TrimSpace(line[i+1:]))
+	}
+
+	return raw, scanner.Err()
+}
+
+// parseINIValue returns the value of a key, without its quotes or its inline
+// comment starting with " ;" or " #".
+func parseINIValue(v string) string {
+	if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') {
+		if end := strings.IndexByte(v[1:], v[0]); end >= 0 {
+			return v[1 : end+1]
+		}
+	}
+
+	for _, comment := range []string{" ;", " #", "\t;", "\t#"} {
+		if i := strings.Index(v, comment); i >= 0 {
+			v = v[:i]
+		}
+	}
+
+	return strings.TrimSpace(v)
+}
@@ -0,0 +1,152 @@
+package multiconfig
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// expandTree replaces the string values of the decoded source raw by their
+// expansion. source names the source in error messages.
+func expandTree(source string, raw map[string]interface{}, expand func(string) (string, error)) error {
+	for key, val := range raw {
+		v, err := expandValue(source, key, val, expand)
+		if err != nil {
+			return err
+		}
+		raw[key] = v
+	}
+
+	return nil
+}
+
+func expandValue(source, path string, val interface{}, expand func(string) (string, error)) (interface{}, error) {
+	switch val := val.(type) {
+	case string:
+		s, err := expand(val)
+		if err != nil {
+			return nil, fmt.Errorf("multiconfig: %s: key '%s': %s", source, path, err)
+		}
+		return s, nil
+	case map[string]interface{}:
+		for key, v := range val {
+			v, err := expandValue(source, path+"."+key, v, expand)
+			if err != nil {
+				return nil, err
+			}
+			val[key] = v
+		}
+	case []interface{}:
+		for i, v := range val {
+			v, err := expandValue(source, fmt.Sprintf("%s[%d]", path, i), v, expand)
+			if err != nil {
+				return nil, err
+			}
+			val[i] = v
+		}
+	}
+
+	return val, nil
+}
+
+// expandEnv replaces the ${VAR} placeholders of s with the value of the
+// environment variable VAR. The forms are:
+//
+//	${VAR}          the value of VAR, which must be set
+//	${VAR:-default} the value of VAR, or default if VAR is unset or empty
+//	${VAR:?message} the value of VAR, which must be set, failing with message
+//
+// A dollar sign is escaped by doubling it, i.e: "$${VAR}" is kept as "${VAR}".
+// A dollar sign not followed by a brace is kept as is.
+func expandEnv(s string) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] != '$':
+			b.WriteByte(s[i])
+		case strings.HasPrefix(s[i:], "$$"):
+			b.WriteByte('$')
+			i++
+		case strings.HasPrefix(s[i:], "${"):
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated placeholder %s", s[i:])
+			}
+
+			v, err := expandPlaceholder(s[i+2 : i+end])
+			if err != nil {
+				return "", err
+			}
+
+			b.WriteString(v)
+			i += end
+		default:
+			b.WriteByte('$')
+		}
+	}
+
+	return b.String(), nil
+}
+
+// expandPlaceholder returns the value of the placeholder expr, the content of
+// ${...}.
+func expandPlaceholder(expr string) (string, error) {
+	name, op, arg := expr, "", ""
+	if i := strings.Index(expr, ":"); i >= 0 {
+		if i+2 > len(expr) {
+			return "", fmt.Errorf("invalid placeholder ${%s}", expr)
+		}
+
+		name, op, arg = expr[:i], expr[i:i+2], expr[i+2:]
+		if op != ":-" && op != ":?" {
+			return "", fmt.Errorf("invalid placeholder ${%s}", expr)
+		}
+	}
+
+	if name == "" {
+		return "", fmt.Errorf("invalid placeholder ${%s}", expr)
+	}
+
+	v, ok := os.LookupEnv(name)
+	switch {
+	case op == ":-" && v == "":
+		return arg, nil
+	case op == ":?" && v == "" && arg != "":
+		return "", fmt.Errorf("environment variable %s: %s", name, arg)
+	case !ok || op == ":?" && v == "":
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+
+	return v, nil
+}
+
+// fileRefPrefix is the prefix of a string value referencing a file whose
+// content is the actual value, i.e: "file:/run/secrets/pg".
+const fileRefPrefix = "file:"
+
+// expandFileRef returns the content of the file referenced by s, if it's
+// prefixed with "file:", or s itself otherwise. A trailing newline is
+// removed from the content.
+func expandFileRef(s string) (string, error) {
+	if !strings.HasPrefix(s, fileRefPrefix) {
+		return s, nil
+	}
+
+	return readSecretFile(strings.TrimPrefix(s, fileRefPrefix))
+}
+
+// readSecretFile returns the content of the file at the given path, without
+// its trailing newline.
+func readSecretFile(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
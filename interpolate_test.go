@@ -0,0 +1,124 @@
+package multiconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("MULTICONFIG_PASSWORD", "secret")
+	t.Setenv("MULTICONFIG_EMPTY", "")
+
+	tests := map[string]string{
+		"plain":                            "plain",
+		"user:${MULTICONFIG_PASSWORD}@db":  "user:secret@db",
+		"${MULTICONFIG_UNSET:-fallback}":   "fallback",
+		"${MULTICONFIG_EMPTY:-fallback}":   "fallback",
+		"${MULTICONFIG_PASSWORD:-unused}":  "secret",
+		"${MULTICONFIG_PASSWORD:?needed}":  "secret",
+		"${MULTICONFIG_EMPTY}":             "",
+		"$${MULTICONFIG_PASSWORD} costs $": "${MULTICONFIG_PASSWORD} costs $",
+	}
+
+	for s, want := range tests {
+		got, err := expandEnv(s)
+		if err != nil {
+			t.Errorf("%q: %s", s, err)
+			continue
+		}
+
+		if got != want {
+			t.Errorf("%q is expanded to %q, want %q", s, got, want)
+		}
+	}
+
+	failures := map[string]string{
+		"${MULTICONFIG_UNSET}":              "environment variable MULTICONFIG_UNSET is not set",
+		"${MULTICONFIG_EMPTY:?is required}": "environment variable MULTICONFIG_EMPTY: is required",
+		"${MULTICONFIG_PASSWORD":            "unterminated placeholder",
+		"${MULTICONFIG_PASSWORD:}":          "invalid placeholder",
+		"${:-default}":                      "invalid placeholder",
+	}
+
+	for s, want := range failures {
+		if _, err := expandEnv(s); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: got error %v, want %q", s, err, want)
+		}
+	}
+}
+
+func TestLoaderExpandEnv(t *testing.T) {
+	t.Setenv("MULTICONFIG_PG_HOST", "192.168.2.1")
+
+	data := `
+name: ${MULTICONFIG_NAME:-koding}
+postgres:
+    port: ${MULTICONFIG_PG_PORT:-5432}
+    hosts:
+        - ${MULTICONFIG_PG_HOST}
+`
+
+	s := new(Server)
+	if err := (&YAMLLoader{Reader: strings.NewReader(data), FileOptions: FileOptions{ExpandEnv: true}}).Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Name != "koding" || s.Postgres.Port != 5432 || s.Postgres.Hosts[0] != "192.168.2.1" {
+		t.Errorf("placeholders are not expanded: %+v", s)
+	}
+
+	err := (&YAMLLoader{Reader: strings.NewReader("name: ${MULTICONFIG_NAME}"), FileOptions: FileOptions{ExpandEnv: true}}).Load(new(Server))
+	want := "multiconfig: yaml: key 'name': environment variable MULTICONFIG_NAME is not set"
+	if err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+}
+
+func TestHCLExpandEnv(t *testing.T) {
+	t.Setenv("MULTICONFIG_NAME", "koding")
+
+	s := new(Server)
+	l := &HCLLoader{Reader: strings.NewReader(`name = "$${MULTICONFIG_NAME}"`), FileOptions: FileOptions{ExpandEnv: true}}
+	if err := l.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Name != "koding" {
+		t.Errorf("got name %q, want koding", s.Name)
+	}
+}
+
+func TestLoaderFileRefs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "multiconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	secret := filepath.Join(dir, "name")
+	if err := ioutil.WriteFile(secret, []byte("koding\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	data := `{"name": "file:` + filepath.ToSlash(secret) + `"}`
+
+	s := new(Server)
+	if err := (&JSONLoader{Reader: strings.NewReader(data)}).Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Name != "file:"+filepath.ToSlash(secret) {
+		t.Errorf("file references should be kept by default, got %q", s.Name)
+	}
+
+	if err := (&JSONLoader{Reader: strings.NewReader(data), FileOptions: FileOptions{FileRefs: true}}).Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Name != "koding" {
+		t.Errorf("name is %q, want the content of the file", s.Name)
+	}
+}
@@ -0,0 +1,253 @@
+package multiconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// serviceAccountDir is the directory the Kubernetes service account token,
+// namespace and CA certificate are mounted at in a pod.
+var serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// KubernetesLoader satisfies the loader interface. It loads the
+// configuration from the keys of a ConfigMap or a Secret, either mounted as a
+// directory, each file holding a key, or read from the API server with the
+// credentials of the pod's service account.
+//
+// Keys are split on "." into nested fields, so the key "postgres.port" sets
+// Postgres.Port. Values are converted the same way environment variables
+// are, without their trailing newline.
+type KubernetesLoader struct {
+	// Dir is the directory a ConfigMap or a Secret is mounted at. If set,
+	// the API server isn't used.
+	Dir string
+
+	// ConfigMap and Secret are the names of the objects read from the API
+	// server. If both are set, the keys of the Secret override the ones of
+	// the ConfigMap.
+	ConfigMap string
+	Secret    string
+
+	// Namespace is the namespace of the objects. The default is the one of
+	// the pod.
+	Namespace string
+
+	// Client is used to perform the requests to the API server. If nil, a
+	// client trusting the CA certificate of the service account is used.
+	Client *http.Client
+
+	// Strict rejects the keys which don't match any field instead of
+	// ignoring them.
+	Strict bool
+
+	// Watch makes DefaultLoader.Watch poll the keys for changes, such as
+	// the update of a mounted ConfigMap. A mounted directory is polled every
+	// WatchInterval, like a file, and the API server every
+	// RemoteWatchInterval.
+	Watch bool
+}
+
+// Load loads the source into the config defined by struct s
+func (k *KubernetesLoader) Load(s interface{}) error {
+	return k.LoadContext(context.Background(), s)
+}
+
+// LoadContext is like Load but the requests are bound to the given context,
+// so they honor its cancellation and deadline.
+func (k *KubernetesLoader) LoadContext(ctx context.Context, s interface{}) error {
+	source, pairs, err := k.pairs(ctx)
+	if err != nil {
+		return err
+	}
+
+	return decodeKV(source, "", ".", pairs, s, k.Strict)
+}
+
+// revision returns the checksum of the keys, for Watch.
+func (k *KubernetesLoader) revision(ctx context.Context) ([]byte, error) {
+	_, pairs, err := k.pairs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return kvSum(pairs), nil
+}
+
+// pairs returns the keys of the mounted directory or of the objects, and the
+// name of their source.
+func (k *KubernetesLoader) pairs(ctx context.Context) (string, map[string]string, error) {
+	if k.Dir != "" {
+		pairs, err := readKeyDir(k.Dir)
+		return k.Dir, pairs, err
+	}
+
+	if k.ConfigMap == "" && k.Secret == "" {
+		return "", nil, ErrSourceNotSet
+	}
+
+	namespace := k.Namespace
+	if namespace == "" {
+		data, err := ioutil.ReadFile(filepath.Join(serviceAccountDir, "namespace"))
+		if err != nil {
+			return "", nil, fmt.Errorf("multiconfig: kubernetes: reading the namespace: %s", err)
+		}
+		namespace = strings.TrimSpace(string(data))
+	}
+
+	var sources []string
+	pairs := map[string]string{}
+
+	if k.ConfigMap != "" {
+		var cm struct {
+			Data       map[string]string
+			BinaryData map[string][]byte
+		}
+		if err := k.get(ctx, namespace, "configmaps", k.ConfigMap, &cm); err != nil {
+			return "", nil, err
+		}
+
+		for key, val := range cm.BinaryData {
+			pairs[key] = trimNewline(string(val))
+		}
+		for key, val := range cm.Data {
+			pairs[key] = trimNewline(val)
+		}
+		sources = append(sources, "configmap "+namespace+"/"+k.ConfigMap)
+	}
+
+	if k.Secret != "" {
+		var secret struct {
+			Data map[string][]byte
+		}
+		if err := k.get(ctx, namespace, "secrets", k.Secret, &secret); err != nil {
+			return "", nil, err
+		}
+
+		for key, val := range secret.Data {
+			pairs[key] = trimNewline(string(val))
+		}
+		sources = append(sources, "secret "+namespace+"/"+k.Secret)
+	}
+
+	return strings.Join(sources, ", "), pairs, nil
+}
+
+// get reads the object of the given kind and name from the API server into
+// out.
+func (k *KubernetesLoader) get(ctx context.Context, namespace, kind, name string, out interface{}) error {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return fmt.Errorf("multiconfig: kubernetes: not running in a cluster, KUBERNETES_SERVICE_HOST is not set")
+	}
+
+	token, err := ioutil.ReadFile(filepath.Join(serviceAccountDir, "token"))
+	if err != nil {
+		return fmt.Errorf("multiconfig: kubernetes: reading the service account token: %s", err)
+	}
+
+	client := k.Client
+	if client == nil {
+		if client, err = serviceAccountClient(); err != nil {
+			return err
+		}
+	}
+
+	u := "https://" + net.JoinHostPort(host, port) + "/api/v1/namespaces/" +
+		url.PathEscape(namespace) + "/" + kind + "/" + url.PathEscape(name)
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("multiconfig: kubernetes: GET %s: unexpected status %s", u, resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("multiconfig: kubernetes: GET %s: %s", u, err)
+	}
+
+	return nil
+}
+
+// serviceAccountClient returns an HTTP client trusting the CA certificate of
+// the service account, which signs the certificate of the API server.
+func serviceAccountClient() (*http.Client, error) {
+	ca, err := ioutil.ReadFile(filepath.Join(serviceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("multiconfig: kubernetes: reading the CA certificate: %s", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("multiconfig: kubernetes: no certificate found in %s", filepath.Join(serviceAccountDir, "ca.crt"))
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		},
+	}, nil
+}
+
+// readKeyDir reads the keys of a mounted ConfigMap or Secret: each file of
+// the directory holds the value of the key named after it. The hidden
+// entries, such as the "..data" link Kubernetes updates atomically, are
+// skipped.
+func readKeyDir(dir string) (map[string]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := map[string]string{}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+
+		// keys are links to the files of the current "..data" directory
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if info.IsDir() {
+			continue
+		}
+
+		if pairs[entry.Name()], err = readSecretFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return pairs, nil
+}
+
+// trimNewline removes the trailing newline of a value, which files created
+// by editors usually end with.
+func trimNewline(val string) string {
+	return strings.TrimRight(val, "\r\n")
+}
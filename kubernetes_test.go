@@ -0,0 +1,180 @@
+package multiconfig
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// kubernetesKeys are the keys of the test ConfigMap.
+var kubernetesKeys = map[string]string{
+	"name":                       "koding\n",
+	"port":                       "6060",
+	"enabled":                    "true",
+	"id":                         "1234567890",
+	"labels":                     "123,456",
+	"users":                      "ankara,istanbul",
+	"interval":                   "10s",
+	"postgres.enabled":           "true",
+	"postgres.port":              "5432",
+	"postgres.hosts":             "192.168.2.1,192.168.2.2,192.168.2.3",
+	"postgres.dbname":            "configdb",
+	"postgres.availabilityratio": "8.23",
+}
+
+// mountKeys writes the keys into dir the way Kubernetes mounts a ConfigMap:
+// each key is a link to the file of the current "..data" directory.
+func mountKeys(t *testing.T, dir string, keys map[string]string) {
+	data, err := ioutil.TempDir(dir, "..data_")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, val := range keys {
+		if err := ioutil.WriteFile(filepath.Join(data, key), []byte(val), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		link := filepath.Join(dir, key)
+		os.Remove(link)
+		if err := os.Symlink(filepath.Join("..data", key), link); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// swap the "..data" link atomically
+	tmp := filepath.Join(dir, "..data_tmp")
+	if err := os.Symlink(filepath.Base(data), tmp); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKubernetesLoaderDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "multiconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	mountKeys(t, dir, kubernetesKeys)
+
+	s := &Server{}
+	if err := (&KubernetesLoader{Dir: dir}).Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	testStruct(t, s, getDefaultServer())
+}
+
+func TestKubernetesLoaderWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "multiconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	mountKeys(t, dir, kubernetesKeys)
+
+	m := newDefaultLoader(&TagLoader{}, &KubernetesLoader{Dir: dir, Watch: true})
+	m.WatchInterval = 10 * time.Millisecond
+
+	s := new(Server)
+	m.MustLoad(s)
+
+	changes := make(chan *Server, 1)
+	stop, err := m.Watch(s, func(old, new interface{}) {
+		changes <- new.(*Server)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	keys := map[string]string{}
+	for key, val := range kubernetesKeys {
+		keys[key] = val
+	}
+	keys["postgres.port"] = "6432"
+	mountKeys(t, dir, keys)
+
+	select {
+	case c := <-changes:
+		if c.Postgres.Port != 6432 {
+			t.Errorf("reloaded port is %d, want 6432", c.Postgres.Port)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("config change not notified")
+	}
+}
+
+func TestKubernetesLoaderAPI(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sa-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/api/v1/namespaces/prod/configmaps/app":
+			w.Write([]byte(`{"kind": "ConfigMap", "data": {"name": "koding", "postgres.port": "5432\n"}}`))
+		case "/api/v1/namespaces/prod/secrets/app":
+			// "6432" and "secret"
+			w.Write([]byte(`{"kind": "Secret", "data": {"postgres.port": "NjQzMg==", "postgres.dbname": "c2VjcmV0"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "multiconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	for name, content := range map[string][]byte{
+		"token":     []byte("sa-token\n"),
+		"namespace": []byte("prod"),
+		"ca.crt":    ca,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), content, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer func(dir string) { serviceAccountDir = dir }(serviceAccountDir)
+	serviceAccountDir = dir
+
+	host, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", host)
+	t.Setenv("KUBERNETES_SERVICE_PORT", port)
+
+	s := &Server{}
+	if err := (&KubernetesLoader{ConfigMap: "app", Secret: "app"}).Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Name != "koding" || s.Postgres.Port != 6432 || s.Postgres.DBName != "secret" {
+		t.Errorf("objects not loaded: %+v", s)
+	}
+
+	err = (&KubernetesLoader{ConfigMap: "missing", Namespace: "prod"}).Load(&Server{})
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected a not found error, got: %v", err)
+	}
+}
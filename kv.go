@@ -0,0 +1,106 @@
+package multiconfig
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// kvTree turns the pairs of a key/value store read under the given prefix
+// into a key tree: the rest of each key is split on sep into nested keys, so
+// "myapp/config/postgres/port" under "myapp/config/" is the key "port" of
+// "postgres" with a separator of "/". Keys ending with the separator, which
+// are directories, are ignored.
+func kvTree(source, prefix, sep string, pairs map[string]string) (map[string]interface{}, error) {
+	keys := make([]string, 0, len(pairs))
+	for key := range pairs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	tree := map[string]interface{}{}
+	for _, key := range keys {
+		rest := strings.TrimPrefix(key, prefix)
+		if rest == "" || strings.HasSuffix(rest, sep) {
+			continue
+		}
+
+		var names []string
+		for _, name := range strings.Split(rest, sep) {
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+
+		node := tree
+		for _, name := range names[:len(names)-1] {
+			child, ok := node[name].(map[string]interface{})
+			if !ok {
+				if _, isValue := node[name]; isValue {
+					return nil, fmt.Errorf("multiconfig: %s: key %s holds both a value and other keys", source, key)
+				}
+
+				child = map[string]interface{}{}
+				node[name] = child
+			}
+			node = child
+		}
+
+		name := names[len(names)-1]
+		if _, ok := node[name].(map[string]interface{}); ok {
+			return nil, fmt.Errorf("multiconfig: %s: key %s holds both a value and other keys", source, key)
+		}
+		node[name] = pairs[key]
+	}
+
+	return tree, nil
+}
+
+// decodeKV decodes the pairs of a key/value store read under the given
+// prefix, whose keys are split on sep, into the struct pointed by s. Values
+// are strings, converted the same way environment variables are.
+func decodeKV(source, prefix, sep string, pairs map[string]string, s interface{}, strict bool) error {
+	raw, err := kvTree(source, prefix, sep, pairs)
+	if err != nil {
+		return err
+	}
+
+	d := &decoder{
+		format:   "kv",
+		target:   s,
+		source:   source,
+		tracking: isTracking(s),
+		strict:   strict,
+	}
+
+	if err := d.decode(raw); err != nil {
+		return err
+	}
+
+	if len(d.unknown) > 0 {
+		sort.Strings(d.unknown)
+		return fmt.Errorf("multiconfig: unknown keys in %s: %s", source, strings.Join(d.unknown, ", "))
+	}
+
+	return nil
+}
+
+// kvSum returns the checksum of the pairs of a key/value store.
+func kvSum(pairs map[string]string) []byte {
+	keys := make([]string, 0, len(pairs))
+	for key := range pairs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintf(h, "%d:%s%d:%s", len(key), key, len(pairs[key]), pairs[key])
+	}
+
+	return h.Sum(nil)
+}
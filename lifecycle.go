@@ -0,0 +1,115 @@
+package multiconfig
+
+import (
+	"context"
+	"fmt"
+)
+
+// hooks are the functions run by a DefaultLoader around its loaders.
+type hooks struct {
+	beforeLoad  []func(s interface{})
+	afterLoader []func(name string, s interface{})
+	afterLoad   []func(s interface{}) error
+}
+
+// BeforeLoad adds a function run by Load on the struct being loaded before
+// any source, default tags included, is loaded into it. Hooks are run in the
+// order they're added, and must be added before the loader is shared by
+// goroutines.
+func (d *DefaultLoader) BeforeLoad(fn func(s interface{})) {
+	d.hooks.beforeLoad = append(d.hooks.beforeLoad, fn)
+}
+
+// AfterLoader adds a function run by Load each time one of the loaders of d
+// has loaded the struct, i.e: to derive a field from the values of the
+// previous sources before the next ones override it. fn is given the name of
+// the loader, such as "*multiconfig.EnvironmentLoader". It isn't run for the
+// loaders which fail.
+func (d *DefaultLoader) AfterLoader(fn func(name string, s interface{})) {
+	d.hooks.afterLoader = append(d.hooks.afterLoader, fn)
+}
+
+// AfterLoad adds a function run by Load once every source is loaded, and
+// before the config is validated, i.e: to trim the whitespace of values or
+// expand the "~" of paths. An error stops the load.
+func (d *DefaultLoader) AfterLoad(fn func(s interface{}) error) {
+	d.hooks.afterLoad = append(d.hooks.afterLoad, fn)
+}
+
+// run loads s with the given loaders, traced, and runs the hooks of d
+// around them.
+func (d *DefaultLoader) run(ctx context.Context, s interface{}, loaders ...Loader) error {
+	for _, fn := range d.hooks.beforeLoad {
+		fn(s)
+	}
+
+	// s is tracked until its fields are recorded, for Sources, LoadedFields
+	// and Validate
+	done := startTracking(s)
+	err := loadContext(ctx, d.traced(loaders...), s)
+	d.record(s)
+	done()
+	if err != nil {
+		return err
+	}
+
+	for _, fn := range d.hooks.afterLoad {
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hooked returns the loader running the loaders of l, or l itself if it's
+// not a MultiLoader, each one followed by the AfterLoader hooks of d.
+func (d *DefaultLoader) hooked(l Loader) Loader {
+	if len(d.hooks.afterLoader) == 0 {
+		return l
+	}
+
+	m, ok := l.(multiLoader)
+	if !ok {
+		return hookedLoader{l, d.hooks.afterLoader}
+	}
+
+	hooked := make(multiLoader, len(m))
+	for i, l := range m {
+		hooked[i] = hookedLoader{l, d.hooks.afterLoader}
+	}
+
+	return hooked
+}
+
+// hookedLoader runs the hooks once its Loader succeeded.
+type hookedLoader struct {
+	Loader
+	hooks []func(name string, s interface{})
+}
+
+func (h hookedLoader) Load(s interface{}) error {
+	return h.LoadContext(context.Background(), s)
+}
+
+func (h hookedLoader) LoadContext(ctx context.Context, s interface{}) error {
+	if err := loadContext(ctx, h.Loader, s); err != nil {
+		return err
+	}
+
+	for _, fn := range h.hooks {
+		fn(loaderName(h.Loader), s)
+	}
+
+	return nil
+}
+
+// loaderName returns the name of the loader l reported to the tracer and
+// the hooks, i.e: "*multiconfig.EnvironmentLoader".
+func loaderName(l Loader) string {
+	if h, ok := l.(hookedLoader); ok {
+		return loaderName(h.Loader)
+	}
+
+	return fmt.Sprintf("%T", l)
+}
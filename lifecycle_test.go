@@ -0,0 +1,79 @@
+package multiconfig
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLifecycleHooks(t *testing.T) {
+	type Config struct {
+		Name string `default:"koding"`
+		Path string
+		URL  string
+	}
+
+	m := newDefaultLoader(&TagLoader{}, &EnvironmentLoader{
+		getenv: testEnvironment{"CONFIG_PATH": "  ~/data  "}.get,
+	})
+
+	var calls []string
+	m.BeforeLoad(func(s interface{}) {
+		calls = append(calls, "before "+s.(*Config).Name)
+	})
+	m.AfterLoader(func(name string, s interface{}) {
+		c := s.(*Config)
+		calls = append(calls, name)
+
+		// derived from the name before the environment is loaded
+		if c.URL == "" {
+			c.URL = "https://" + c.Name
+		}
+	})
+	m.AfterLoad(func(s interface{}) error {
+		c := s.(*Config)
+		c.Path = strings.Replace(strings.TrimSpace(c.Path), "~", "/home/koding", 1)
+		calls = append(calls, "after")
+		return nil
+	})
+
+	s := &Config{}
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"before ", "*multiconfig.TagLoader", "*multiconfig.EnvironmentLoader", "after"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("hooks calls are wrong: %v, want: %v", calls, want)
+	}
+
+	if s.Path != "/home/koding/data" || s.URL != "https://koding" {
+		t.Errorf("config is wrong: %+v", s)
+	}
+}
+
+func TestAfterLoadError(t *testing.T) {
+	m := newDefaultLoader(&TagLoader{})
+
+	errHook := errors.New("invalid config")
+	m.AfterLoad(func(s interface{}) error { return errHook })
+
+	var loaders []string
+	m.AfterLoader(func(name string, s interface{}) { loaders = append(loaders, name) })
+
+	if err := m.Load(&Server{}); err != errHook {
+		t.Errorf("error is wrong: %v, want: %v", err, errHook)
+	}
+
+	// the failing loader isn't followed by the hooks
+	m.Loader = MultiLoader(&TagLoader{}, &EnvironmentLoader{getenv: testEnvironment{"SERVER_PORT": "http"}.get})
+	loaders = nil
+	if err := m.Load(&Server{}); err == nil {
+		t.Fatal("loading an invalid port should fail")
+	}
+
+	if !reflect.DeepEqual(loaders, []string{"*multiconfig.TagLoader"}) {
+		t.Errorf("hooked loaders are wrong: %v", loaders)
+	}
+}
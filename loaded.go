@@ -0,0 +1,249 @@
+package multiconfig
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fatih/structs"
+)
+
+// loadState holds the fields set by the loaders of a struct.
+type loadState struct {
+	// depth is the number of nested loaders currently loading, or
+	// validators validating, the struct.
+	depth int
+
+	// fields maps the path of each set field to the loader which set it last.
+	fields map[string]string
+
+	// tracer, if set, is handed the fields set.
+	tracer Tracer
+}
+
+// loaded tracks the fields set while loading a struct with a MultiLoader,
+// keyed by the pointer of the struct, for the loaders to record the fields
+// they set. Tracking the set fields allows distinguishing a zero value
+// provided by a source from a field which was never provided at all. A
+// struct is only tracked while it's loaded, or validated by the
+// DefaultLoader which loaded it, see DefaultLoader.LoadedFields.
+var loaded = struct {
+	sync.Mutex
+	m map[interface{}]*loadState
+}{m: make(map[interface{}]*loadState)}
+
+// loadKey returns the key of s in the tracked structs. Only non-nil pointers
+// can be tracked.
+func loadKey(s interface{}) (interface{}, bool) {
+	v := reflect.ValueOf(s)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil, false
+	}
+
+	return s, true
+}
+
+// startTracking starts tracking the fields set for s, unless s is already
+// being tracked by an outer MultiLoader. The returned function must be
+// called once loading is done, the outermost call forgetting the fields.
+func startTracking(s interface{}) func() {
+	return track(s, make(map[string]string))
+}
+
+// track tracks the fields set for s, starting with the given ones, unless s
+// is already being tracked. The returned function stops tracking s.
+func track(s interface{}, fields map[string]string) func() {
+	key, ok := loadKey(s)
+	if !ok {
+		return func() {}
+	}
+
+	loaded.Lock()
+	defer loaded.Unlock()
+
+	state, ok := loaded.m[key]
+	if !ok {
+		state = &loadState{fields: fields}
+		loaded.m[key] = state
+	}
+	state.depth++
+
+	return func() {
+		loaded.Lock()
+		defer loaded.Unlock()
+
+		if state.depth--; state.depth == 0 {
+			delete(loaded.m, key)
+		}
+	}
+}
+
+// isTracking reports whether the fields set for s are tracked.
+func isTracking(s interface{}) bool {
+	key, ok := loadKey(s)
+	if !ok {
+		return false
+	}
+
+	loaded.Lock()
+	defer loaded.Unlock()
+
+	_, ok = loaded.m[key]
+	return ok
+}
+
+// markLoaded records that the field at the given path of s was set by
+// source. It's a no-op if s is not being tracked.
+func markLoaded(s interface{}, path, source string) {
+	key, ok := loadKey(s)
+	if !ok {
+		return
+	}
+
+	loaded.Lock()
+	var tracer Tracer
+	var previous string
+	if state, ok := loaded.m[key]; ok {
+		previous = state.fields[path]
+		state.fields[path] = source
+		tracer = state.tracer
+	}
+	loaded.Unlock()
+
+	if tracer != nil {
+		tracer.Trace(TraceEvent{
+			Kind:     TraceSet,
+			Loader:   source,
+			Path:     path,
+			Value:    traceValue(s, path),
+			Previous: previous,
+		})
+	}
+}
+
+// loadedField reports whether the fields of s are tracked, and if so whether
+// the field at the given path was set.
+func loadedField(s interface{}, path string) (isSet, tracked bool) {
+	key, ok := loadKey(s)
+	if !ok {
+		return false, false
+	}
+
+	loaded.Lock()
+	defer loaded.Unlock()
+
+	state, ok := loaded.m[key]
+	if !ok {
+		return false, false
+	}
+
+	_, isSet = state.fields[path]
+	return isSet, true
+}
+
+// loadedWithin reports whether the field at path, or any field nested in it,
+// was set by a source while s is tracked.
+func loadedWithin(s interface{}, path string) bool {
+	for f := range loadedSources(s) {
+		if f == path || strings.HasPrefix(f, path+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LoadedFields returns the sorted paths of the fields of s, i.e:
+// "Postgres.Port", set by any source during the last load of s by d. A field
+// set to its zero value by a source is part of the result, while a field
+// never provided by any source is not. It returns nil if s was never loaded
+// by d: the fields are recorded for the pointer s, not for a copy of the
+// struct.
+func (d *DefaultLoader) LoadedFields(s interface{}) []string {
+	sources := d.Sources(s)
+	if sources == nil {
+		return nil
+	}
+
+	fields := make([]string, 0, len(sources))
+	for path := range sources {
+		fields = append(fields, path)
+	}
+	sort.Strings(fields)
+
+	return fields
+}
+
+// fieldPath resolves the given field names, which may refer to fields
+// promoted from embedded structs, into the full path of the field within the
+// struct s, i.e: "Port" is resolved to "Postgres.Port" for a struct embedding
+// Postgres.
+func fieldPath(s interface{}, names ...string) string {
+	t := reflect.TypeOf(s)
+
+	var path []string
+	for _, name := range names {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		if t.Kind() != reflect.Struct {
+			path = append(path, name)
+			continue
+		}
+
+		field, ok := t.FieldByName(name)
+		if !ok {
+			path = append(path, name)
+			continue
+		}
+
+		path = append(path, indexNames(t, field.Index)...)
+		t = field.Type
+	}
+
+	return strings.Join(path, ".")
+}
+
+// indexNames returns the names of the fields along the given index sequence
+// of the struct type t.
+func indexNames(t reflect.Type, index []int) []string {
+	names := make([]string, 0, len(index))
+	for _, i := range index {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		field := t.Field(i)
+		names = append(names, field.Name)
+		t = field.Type
+	}
+
+	return names
+}
+
+// fieldByPath returns the field at the given path of the struct s, i.e:
+// "Postgres.Port". Names are matched exactly and may refer to fields promoted
+// from embedded structs. The nil pointers to structs along the path are
+// allocated.
+func fieldByPath(s interface{}, path string) (*structs.Field, bool) {
+	names := strings.Split(path, ".")
+
+	field, ok := structs.New(s).FieldOk(names[0])
+	for _, name := range names[1:] {
+		if ok && isStructPtr(field) && field.IsZero() {
+			if err := field.Set(reflect.New(reflect.TypeOf(field.Value()).Elem()).Interface()); err != nil {
+				return nil, false
+			}
+		}
+
+		if !ok || field.Kind() != reflect.Struct && !isStructPtr(field) {
+			return nil, false
+		}
+
+		field, ok = field.FieldOk(name)
+	}
+
+	return field, ok
+}
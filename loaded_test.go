@@ -0,0 +1,104 @@
+package multiconfig
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type TrackedPostgres struct {
+	Enabled bool `required:"true"`
+	Port    int  `required:"true"`
+	DBName  string
+}
+
+type TrackedServer struct {
+	Name     string `default:"koding"`
+	Postgres TrackedPostgres
+}
+
+func TestRequiredUnsetBool(t *testing.T) {
+	m := &DefaultLoader{
+		Loader: MultiLoader(
+			&TagLoader{},
+			&TOMLLoader{Reader: strings.NewReader("[Postgres]\nPort = 0\n")},
+		),
+		Validator: &RequiredValidator{},
+	}
+
+	s := &TrackedServer{}
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	err := m.Validate(s)
+	if err == nil {
+		t.Fatal("Postgres.Enabled should be required")
+	}
+
+	errStr := "multiconfig: field 'Postgres.Enabled' is required"
+	if err.Error() != errStr {
+		t.Fatalf("Err string is wrong: expected %s, got: %s", errStr, err.Error())
+	}
+}
+
+func TestRequiredExplicitZero(t *testing.T) {
+	m := &DefaultLoader{
+		Loader: MultiLoader(
+			&TagLoader{},
+			&YAMLLoader{Reader: strings.NewReader("postgres:\n  enabled: false\n")},
+			&FlagLoader{Args: []string{"-postgres-port", "0"}},
+		),
+		Validator: &RequiredValidator{},
+	}
+
+	s := &TrackedServer{}
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Validate(s); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"Name", "Postgres.Enabled", "Postgres.Port"}
+	if diff := cmp.Diff(want, m.LoadedFields(s)); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+
+	// the fields set are scoped to the loads of m: a validator used on its
+	// own, or another loader, only sees the zero values
+	errStr := "multiconfig: field 'Postgres.Enabled' is required"
+	if err := (&RequiredValidator{}).Validate(s); err == nil || !strings.Contains(err.Error(), errStr) {
+		t.Errorf("got error %v, want %s", err, errStr)
+	}
+
+	other := &DefaultLoader{Loader: m.Loader, Validator: m.Validator}
+	if err := other.Validate(s); err == nil || !strings.Contains(err.Error(), errStr) {
+		t.Errorf("got error %v, want %s", err, errStr)
+	}
+
+	// the struct isn't tracked anymore once loaded
+	if isTracking(s) {
+		t.Error("the struct should not be tracked after its load")
+	}
+}
+
+func TestLoadedFieldsNotLoaded(t *testing.T) {
+	if fields := New().LoadedFields(&TrackedServer{}); fields != nil {
+		t.Errorf("expected no loaded fields, got: %v", fields)
+	}
+}
+
+func TestFieldPath(t *testing.T) {
+	s := &TaggedServer{}
+
+	if path := fieldPath(s, "Port"); path != "Postgres.Port" {
+		t.Errorf("path is wrong: %s, want: Postgres.Port", path)
+	}
+
+	if path := fieldPath(&App{}, "API", "Host"); path != "API.AppServer.Host" {
+		t.Errorf("path is wrong: %s, want: API.AppServer.Host", path)
+	}
+}
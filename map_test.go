@@ -0,0 +1,156 @@
+package multiconfig
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type MapConfig struct {
+	Labels    map[string]string `default:"env=prod"`
+	Ports     map[string]int
+	Databases map[string]Postgres
+}
+
+func TestMapFields(t *testing.T) {
+	want := &MapConfig{
+		Labels: map[string]string{"env": "prod", "foo": "bar", "baz": "qux"},
+		Ports:  map[string]int{"http": 80, "https": 443},
+		Databases: map[string]Postgres{
+			"main":    {Port: 5432, DBName: "main", Hosts: []string{"db1", "db2"}},
+			"replica": {Port: 5433},
+		},
+	}
+
+	sources := map[string]Loader{
+		"toml": &TOMLLoader{Reader: strings.NewReader(`
+[Labels]
+foo = "bar"
+baz = "qux"
+
+[Ports]
+http = 80
+https = 443
+
+[Databases.main]
+Port = 5432
+DBName = "main"
+Hosts = ["db1", "db2"]
+
+[Databases.replica]
+Port = 5433
+`)},
+		"yaml": &YAMLLoader{Reader: strings.NewReader(`
+labels:
+  foo: bar
+  baz: qux
+ports:
+  http: 80
+  https: 443
+databases:
+  main:
+    port: 5432
+    dbname: main
+    hosts: [db1, db2]
+  replica:
+    port: 5433
+`)},
+		"env": &EnvironmentLoader{
+			getenv:  testEnv.get,
+			environ: testEnv.environ,
+		},
+		"flags": MultiLoader(
+			&FlagLoader{Args: []string{"-labels", "foo=bar,baz=qux", "-ports", "http=80,https=443"}},
+			&EnvironmentLoader{
+				Prefix:  "FLAGS",
+				getenv:  testEnv.get,
+				environ: testEnv.environ,
+			},
+		),
+	}
+
+	for name, loader := range sources {
+		s := &MapConfig{}
+		if err := MultiLoader(&TagLoader{}, loader).Load(s); err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(s, want) {
+			t.Errorf("%s: config is %+v, want %+v", name, s, want)
+		}
+	}
+}
+
+// testEnvironment is a fake environment.
+type testEnvironment map[string]string
+
+func (e testEnvironment) get(key string) string { return e[key] }
+
+func (e testEnvironment) environ() []string {
+	var environ []string
+	for key, val := range e {
+		environ = append(environ, key+"="+val)
+	}
+	return environ
+}
+
+var testEnv = testEnvironment{
+	"MAPCONFIG_LABELS":                 "foo=bar",
+	"MAPCONFIG_LABELS_BAZ":             "qux",
+	"MAPCONFIG_PORTS_HTTP":             "80",
+	"MAPCONFIG_PORTS_HTTPS":            "443",
+	"MAPCONFIG_DATABASES_MAIN_PORT":    "5432",
+	"MAPCONFIG_DATABASES_MAIN_DBNAME":  "main",
+	"MAPCONFIG_DATABASES_MAIN_HOSTS":   "db1,db2",
+	"MAPCONFIG_DATABASES_REPLICA_PORT": "5433",
+	"FLAGS_DATABASES_MAIN_PORT":        "5432",
+	"FLAGS_DATABASES_MAIN_DBNAME":      "main",
+	"FLAGS_DATABASES_MAIN_HOSTS":       "db1,db2",
+	"FLAGS_DATABASES_REPLICA_PORT":     "5433",
+}
+
+func TestMapFieldsStrict(t *testing.T) {
+	env := testEnvironment{
+		"MAPCONFIG_LABELS_FOO":           "bar",
+		"MAPCONFIG_DATABASES_MAIN_PORT":  "5432",
+		"MAPCONFIG_DATABASES_MAIN_PROTO": "tcp",
+	}
+
+	e := &EnvironmentLoader{Strict: true, getenv: env.get, environ: env.environ}
+	err := e.Load(&MapConfig{})
+	if err == nil || err.Error() != "multiconfig: unknown environment variables: MAPCONFIG_DATABASES_MAIN_PROTO" {
+		t.Errorf("expected an unknown variable error, got: %v", err)
+	}
+}
+
+func TestMapFieldsMerge(t *testing.T) {
+	type MergeMapConfig struct {
+		Labels   map[string]string `default:"env=prod"`
+		Replaced map[string]string `default:"env=prod" merge:"replace"`
+	}
+
+	t.Setenv("MERGEMAPCONFIG_LABELS", "foo=bar")
+	t.Setenv("MERGEMAPCONFIG_REPLACED", "foo=bar")
+
+	s := &MergeMapConfig{}
+	if err := MultiLoader(&TagLoader{}, &EnvironmentLoader{}).Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	want := &MergeMapConfig{
+		Labels:   map[string]string{"env": "prod", "foo": "bar"},
+		Replaced: map[string]string{"foo": "bar"},
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("config is %+v, want %+v", s, want)
+	}
+}
+
+func TestMapFieldsInvalid(t *testing.T) {
+	f := &FlagLoader{Args: []string{"-labels", "foo"}}
+	err := f.Load(&MapConfig{})
+	if err == nil || !strings.Contains(err.Error(), `invalid map entry "foo"`) {
+		t.Errorf("expected an invalid entry error, got: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package multiconfig
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// mergeTag is the tag selecting how a slice or a map field given by several
+// loaders is merged:
+//
+//	Hosts []string `merge:"append"`
+//
+// The strategies are:
+//
+//	replace: a later loader replaces the value wholesale
+//	append:  a later loader appends its elements, or adds its map entries
+//	union:   like append, but elements already present are not added again
+//
+// By default slices are replaced and map entries are added. The values of the
+// default tag are merged like the values of any other loader.
+const mergeTag = "merge"
+
+// Merge strategies of the merge tag.
+const (
+	mergeReplace = "replace"
+	mergeAppend  = "append"
+	mergeUnion   = "union"
+)
+
+// mergeStrategy checks the merge strategy given by the merge tag of a field.
+// path is the path of the field used in error messages.
+func mergeStrategy(strategy, path string) (string, error) {
+	switch strategy {
+	case "", mergeReplace, mergeAppend, mergeUnion:
+		return strategy, nil
+	default:
+		return "", fieldErr(path, strategy, fmt.Errorf("unknown merge strategy %q", strategy))
+	}
+}
+
+// mergeSlices merges the elements of the slice v into the slice old
+// according to the given strategy. It returns v unless the strategy is
+// append or union.
+func mergeSlices(strategy string, old, v reflect.Value) reflect.Value {
+	if old.Kind() != reflect.Slice || old.Len() == 0 {
+		return v
+	}
+
+	switch strategy {
+	case mergeAppend:
+		return reflect.AppendSlice(reflect.AppendSlice(reflect.MakeSlice(old.Type(), 0, old.Len()+v.Len()), old), v)
+	case mergeUnion:
+		merged := reflect.AppendSlice(reflect.MakeSlice(old.Type(), 0, old.Len()+v.Len()), old)
+		for i := 0; i < v.Len(); i++ {
+			if !containsValue(merged, v.Index(i)) {
+				merged = reflect.Append(merged, v.Index(i))
+			}
+		}
+		return merged
+	}
+
+	return v
+}
+
+// containsValue reports whether the slice list contains an element deeply
+// equal to v.
+func containsValue(list, v reflect.Value) bool {
+	for i := 0; i < list.Len(); i++ {
+		if reflect.DeepEqual(list.Index(i).Interface(), v.Interface()) {
+			return true
+		}
+	}
+
+	return false
+}
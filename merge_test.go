@@ -0,0 +1,60 @@
+package multiconfig
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type MergeServer struct {
+	Hosts    []string          `merge:"append"`
+	Users    []string          `merge:"union"`
+	Ports    []int             `merge:"replace"`
+	Labels   map[string]string `merge:"replace"`
+	Settings map[string]string
+}
+
+func TestMerge(t *testing.T) {
+	t.Setenv("MERGESERVER_HOSTS", "env-host")
+	t.Setenv("MERGESERVER_USERS", "ankara,izmir")
+
+	base := `{"hosts": ["a"], "users": ["ankara"], "ports": [1], "labels": {"a": "1"}, "settings": {"a": "1"}}`
+	overlay := `{"hosts": ["b"], "users": ["istanbul"], "ports": [2], "labels": {"b": "2"}, "settings": {"b": "2"}}`
+
+	l := MultiLoader(
+		&JSONLoader{Reader: strings.NewReader(base)},
+		&JSONLoader{Reader: strings.NewReader(overlay)},
+		&EnvironmentLoader{},
+		&FlagLoader{Args: []string{"-hosts", "flag-host"}},
+	)
+
+	s := new(MergeServer)
+	if err := l.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	want := &MergeServer{
+		Hosts:    []string{"a", "b", "env-host", "flag-host"},
+		Users:    []string{"ankara", "istanbul", "izmir"},
+		Ports:    []int{2},
+		Labels:   map[string]string{"b": "2"},
+		Settings: map[string]string{"a": "1", "b": "2"},
+	}
+
+	if diff := cmp.Diff(want, s); diff != "" {
+		t.Errorf("merged config is wrong (-want +got):\n%s", diff)
+	}
+}
+
+func TestMergeUnknownStrategy(t *testing.T) {
+	var s struct {
+		Hosts []string `merge:"prepend"`
+	}
+
+	err := (&JSONLoader{Reader: strings.NewReader(`{"hosts": ["a"]}`)}).Load(&s)
+	want := `multiconfig: field 'Hosts': unknown merge strategy "prepend"`
+	if err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+}
@@ -0,0 +1,82 @@
+package multiconfig
+
+import (
+	"reflect"
+)
+
+// Validatable is implemented by the config structs, or the structs nested in
+// them, checking rules which span several fields, so they live next to the
+// struct definition:
+//
+//	func (t *TLS) Validate() error {
+//		if (t.Cert == "") != (t.Key == "") {
+//			return errors.New("Cert and Key must both be set or both empty")
+//		}
+//		return nil
+//	}
+type Validatable interface {
+	Validate() error
+}
+
+// MethodValidator validates a struct by calling the Validate method of the
+// struct and of the structs nested in it which implement Validatable. It's
+// one of the default validators of the DefaultLoader, run after the ones
+// checking the tags.
+//
+// The nested structs are validated first, including embedded structs and
+// non-nil pointers to structs. The error of a nested struct is reported
+// with its path, i.e: "multiconfig: field 'TLS': Cert and Key must both be
+// set or both empty", and the errors of every struct are returned within
+// ValidationErrors.
+type MethodValidator struct{}
+
+// Validate calls the Validate methods of s and of its nested structs.
+func (MethodValidator) Validate(s interface{}) error {
+	v := reflect.ValueOf(s)
+	if isRaw(s) || v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs ValidationErrors
+	callValidate("", v.Elem(), &errs)
+
+	return errs.err()
+}
+
+// callValidate calls the Validate methods of the nested structs of the
+// struct v, found at path, and then the one of v, adding their errors to
+// errs.
+func callValidate(path string, v reflect.Value, errs *ValidationErrors) {
+	for _, field := range structFields(v.Type()) {
+		fv := v.FieldByIndex(field.Index)
+		if fv.Kind() == reflect.Ptr {
+			// a nil pointer is an optional struct, which has nothing to check
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+
+		if fv.Kind() == reflect.Struct && !isUnmarshalerType(fv.Type()) {
+			callValidate(joinPath(path, field.Name), fv, errs)
+		}
+	}
+
+	if !v.CanAddr() {
+		return
+	}
+
+	c, ok := v.Addr().Interface().(Validatable)
+	if !ok {
+		return
+	}
+
+	if err := c.Validate(); err != nil {
+		if path == "" {
+			errs.add(err)
+			return
+		}
+
+		errs.add(fieldErr(path, nil, err))
+	}
+}
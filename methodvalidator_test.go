@@ -0,0 +1,67 @@
+package multiconfig
+
+import (
+	"errors"
+	"testing"
+)
+
+type checkedTLS struct {
+	Cert string
+	Key  string
+}
+
+func (c *checkedTLS) Validate() error {
+	if (c.Cert == "") != (c.Key == "") {
+		return errors.New("Cert and Key must both be set or both empty")
+	}
+
+	return nil
+}
+
+type checkedServer struct {
+	Name    string `required:"true"`
+	Workers int
+	TLS     checkedTLS
+	Admin   *checkedTLS
+}
+
+func (c checkedServer) Validate() error {
+	if c.Workers < 0 {
+		return errors.New("Workers must not be negative")
+	}
+
+	return nil
+}
+
+func TestMethodValidator(t *testing.T) {
+	m := newDefaultLoader()
+
+	s := &checkedServer{Name: "koding", TLS: checkedTLS{Cert: "cert.pem", Key: "key.pem"}}
+	if err := m.Validate(s); err != nil {
+		t.Fatal(err)
+	}
+
+	s = &checkedServer{
+		Workers: -1,
+		TLS:     checkedTLS{Cert: "cert.pem"},
+		Admin:   &checkedTLS{Key: "key.pem"},
+	}
+
+	err := m.Validate(s)
+	want := "multiconfig: field 'Name' is required\n" +
+		"multiconfig: field 'TLS': Cert and Key must both be set or both empty\n" +
+		"multiconfig: field 'Admin': Cert and Key must both be set or both empty\n" +
+		"Workers must not be negative"
+	if err == nil || err.Error() != want {
+		t.Fatalf("unexpected error:\n%v", err)
+	}
+
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Path != "Name" {
+		t.Errorf("unexpected field error: %v", fe)
+	}
+
+	if err := (MethodValidator{}).Validate(checkedServer{}); err != nil {
+		t.Errorf("a struct which isn't a pointer should be skipped, got %v", err)
+	}
+}
@@ -1,9 +1,15 @@
 package multiconfig
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
+	"log"
 	"os"
+	pathpkg "path"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
@@ -19,61 +25,341 @@ type Loader interface {
 	Load(s interface{}) error
 }
 
+// ContextLoader is a Loader whose load can be bound to a context, such as
+// the loaders of remote sources, i.e: HTTPLoader or VaultLoader.
+type ContextLoader interface {
+	Loader
+
+	// LoadContext is like Load but honors the cancellation and the deadline
+	// of ctx.
+	LoadContext(ctx context.Context, s interface{}) error
+}
+
 // DefaultLoader implements the Loader interface. It initializes the given
 // pointer of struct s with configuration from the default sources. The order
 // of load is TagLoader, FileLoader, EnvLoader and lastly FlagLoader. An error
 // in any step stops the loading process. Each step overrides the previous
 // step's config (i.e: defining a flag will override previous environment or
 // file config). To customize the order use the individual load functions.
+//
+// A DefaultLoader, like the loaders and validators of this package, is safe
+// for concurrent use once its fields are set: it can be built at startup and
+// shared by goroutines loading distinct structs.
 type DefaultLoader struct {
 	Loader
 	Validator
+
+	// WatchInterval is how often Watch checks the configuration files for
+	// changes. The default is DefaultWatchInterval.
+	WatchInterval time.Duration
+
+	// RemoteWatchInterval is how often Watch polls the remote sources whose
+	// Watch option is set, such as an EtcdLoader or a GCSLoader. Polls are
+	// longer and may be billed, i.e: each access to a Secret Manager secret,
+	// so the default is DefaultRemoteWatchInterval.
+	RemoteWatchInterval time.Duration
+
+	// Logger receives the warnings of Load, such as the ones about the
+	// fields tagged as deprecated, i.e: `deprecated:"use Postgres.DSN
+	// instead"`, which are set by a source. A deprecated field tagged with
+	// `replacedBy:"Postgres.DSN"` has its value copied to the replacement,
+	// unless a source sets the replacement too. It also receives the errors
+	// of the reloads of Watch. If nil, the standard logger is used.
+	Logger *log.Logger
+
+	// ErrorHandler, if set, handles the errors of MustLoad and MustValidate
+	// instead of exiting, i.e: to retry, fall back to another config or fail
+	// a test. The default prints the error and exits with status 2.
+	ErrorHandler func(err error)
+
+	// Tracer, if set, is handed the events of Load: the invocation of each
+	// loader, each field set by a source along with the source it overrides,
+	// and the values skipped, i.e: see LogTracer.
+	Tracer Tracer
+
+	hooks hooks
+
+	// refresh is how often Watch reloads the configuration, see RefreshEvery.
+	refresh time.Duration
+
+	// loads holds the sources of the fields set during the last load of the
+	// structs loaded by d, see Sources, LoadedFields and Validate.
+	loads records
 }
 
 // NewWithPath returns a new instance of Loader to read from the given
 // configuration file.
 func NewWithPath(path string) *DefaultLoader {
+	return NewWithPaths(path)
+}
+
+// NewStrictWithPath is like NewWithPath but rejects the keys of the file and
+// the environment variables which don't match any field, i.e: a misspelled
+// "protgres.port", instead of ignoring them. Unknown flags are always
+// rejected.
+func NewStrictWithPath(path string) *DefaultLoader {
+	d := NewWithPath(path)
+	for _, l := range d.Loader.(multiLoader) {
+		setStrict(l)
+	}
+
+	return d
+}
+
+// NewWithPaths returns a new instance of Loader to read from the given
+// configuration files. The files are loaded in the given order into the same
+// struct, so a value defined in a later file overrides the one defined in an
+// earlier file while keys missing from the later file are left intact. This
+// applies to nested structs as well: an overlay that only defines
+// Postgres.Port keeps Postgres.Hosts from the base file. Slices are not
+// merged, a slice defined in a later file replaces the previous one
+// wholesale. Each file's format is chosen by its extension, so formats can be
+// mixed.
+//
+// A path may also be a glob pattern, i.e: "conf.d/*.toml", or a directory,
+// such as "conf.d", whose files are loaded in lexical order. Files with an
+// unsupported extension are skipped. The patterns and directories are
+// expanded once, when the loader is created.
+func NewWithPaths(paths ...string) *DefaultLoader {
+	return NewWithFS(nil, paths...)
+}
+
+// NewWithFS is like NewWithPaths but reads the configuration files from the
+// given file system, such as an embed.FS:
+//
+//	//go:embed config.toml
+//	var configFS embed.FS
+//
+//	m := multiconfig.NewWithFS(configFS, "config.toml")
+//
+// A nil fsys is the operating system's file system.
+func NewWithFS(fsys fs.FS, paths ...string) *DefaultLoader {
 	loaders := []Loader{}
 
 	// Read default values defined via tag fields "default"
 	loaders = append(loaders, &TagLoader{})
 
-	// Choose what while is passed
-	if strings.HasSuffix(path, "toml") {
-		loaders = append(loaders, &TOMLLoader{Path: path})
+	for _, path := range paths {
+		for _, path := range expandPath(fsys, path) {
+			if l := fileLoader(fsys, path); l != nil {
+				loaders = append(loaders, l)
+			}
+		}
 	}
 
-	if strings.HasSuffix(path, "json") {
-		loaders = append(loaders, &JSONLoader{Path: path})
+	e := &EnvironmentLoader{}
+	f := &FlagLoader{}
+
+	loaders = append(loaders, e, f)
+	return newDefaultLoader(loaders...)
+}
+
+// Format is the format of a configuration source.
+type Format string
+
+// Formats supported by NewWithReader.
+const (
+	TOML   Format = "toml"
+	JSON   Format = "json"
+	YAML   Format = "yaml"
+	HCL    Format = "hcl"
+	INI    Format = "ini"
+	DotEnv Format = "env"
+)
+
+// NewWithReader returns a new instance of Loader to read the configuration
+// in the given format from r, such as stdin, an HTTP response body or a
+// test fixture. The reader is read when loading, so it can be used once.
+func NewWithReader(r io.Reader, format Format) *DefaultLoader {
+	var l Loader = unsupportedFormat(format)
+	if rl := readerLoader(string(format), r); rl != nil {
+		l = rl
+	}
+
+	return newDefaultLoader(
+		&TagLoader{},
+		l,
+		&EnvironmentLoader{},
+		&FlagLoader{},
+	)
+}
+
+// unsupportedFormat is a loader failing to load an unsupported format.
+type unsupportedFormat Format
+
+func (f unsupportedFormat) Load(s interface{}) error {
+	return fmt.Errorf("multiconfig: unsupported format %q", string(f))
+}
+
+// expandPath returns the files matching path in fsys, or in the operating
+// system's file system if fsys is nil: the files of a directory or matching a
+// glob pattern, in lexical order, or the path itself.
+func expandPath(fsys fs.FS, path string) []string {
+	if strings.ContainsAny(path, "*?[") {
+		var matches []string
+		if fsys != nil {
+			matches, _ = fs.Glob(fsys, path)
+		} else {
+			matches, _ = filepath.Glob(path)
+		}
+		return matches
 	}
 
-	if strings.HasSuffix(path, "yml") || strings.HasSuffix(path, "yaml") {
-		loaders = append(loaders, &YAMLLoader{Path: path})
+	var entries []fs.DirEntry
+	var join func(elem ...string) string
+	if fsys != nil {
+		entries, _ = fs.ReadDir(fsys, path)
+		join = pathpkg.Join
+	} else {
+		entries, _ = os.ReadDir(path)
+		join = filepath.Join
 	}
 
-	e := &EnvironmentLoader{}
-	f := &FlagLoader{}
+	if entries == nil {
+		return []string{path}
+	}
 
-	loaders = append(loaders, e, f)
-	loader := MultiLoader(loaders...)
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			files = append(files, join(path, entry.Name()))
+		}
+	}
 
-	d := &DefaultLoader{}
-	d.Loader = loader
-	d.Validator = MultiValidator(&RequiredValidator{})
-	return d
+	return files
+}
+
+// fileLoader returns the file loader matching the extension of the given
+// path, read from fsys. It returns nil if the extension is not supported.
+func fileLoader(fsys fs.FS, path string) Loader {
+	switch formatOf(path) {
+	case "toml":
+		return &TOMLLoader{Path: path, FileOptions: FileOptions{FS: fsys}}
+	case "json":
+		return &JSONLoader{Path: path, FileOptions: FileOptions{FS: fsys}}
+	case "yaml":
+		return &YAMLLoader{Path: path, FileOptions: FileOptions{FS: fsys}}
+	case "hcl":
+		return &HCLLoader{Path: path, FileOptions: FileOptions{FS: fsys}}
+	case "ini":
+		return &INILoader{Path: path, FileOptions: FileOptions{FS: fsys}}
+	case "env":
+		return &DotEnvLoader{Path: path, FS: fsys}
+	}
+
+	return nil
+}
+
+// fileOptionsLoader is the interface of the loaders decoding a file, which
+// embed FileOptions.
+type fileOptionsLoader interface {
+	fileOptions() *FileOptions
+}
+
+// setStrict enables the Strict option of the loader l, if it has one.
+func setStrict(l Loader) {
+	switch l := l.(type) {
+	case fileOptionsLoader:
+		l.fileOptions().Strict = true
+	case *DotEnvLoader:
+		l.Strict = true
+	case *EnvironmentLoader:
+		l.Strict = true
+	}
+}
+
+// SetNameTag names the fields after the given tag, i.e: "json", in the keys
+// of the files, the environment variables and the flags loaded by d, for the
+// fields it names. See the NameTag option of the loaders.
+func (d *DefaultLoader) SetNameTag(tag string) {
+	setNameTag(d.Loader, tag)
+}
+
+// setNameTag sets the NameTag option of the loader l, and of the loaders it
+// runs, if they have one.
+func setNameTag(l Loader, tag string) {
+	switch l := l.(type) {
+	case multiLoader:
+		for _, loader := range l {
+			setNameTag(loader, tag)
+		}
+	case *optionalLoader:
+		setNameTag(l.Loader, tag)
+	case fileOptionsLoader:
+		l.fileOptions().NameTag = tag
+	case *DotEnvLoader:
+		l.NameTag = tag
+	case *EnvironmentLoader:
+		l.NameTag = tag
+	case *FlagLoader:
+		l.NameTag = tag
+	}
+}
+
+// readerLoader returns the loader decoding the given format from r. It
+// returns nil if the format is not supported.
+func readerLoader(format string, r io.Reader) Loader {
+	switch format {
+	case "toml":
+		return &TOMLLoader{Reader: r}
+	case "json":
+		return &JSONLoader{Reader: r}
+	case "yaml":
+		return &YAMLLoader{Reader: r}
+	case "hcl":
+		return &HCLLoader{Reader: r}
+	case "ini":
+		return &INILoader{Reader: r}
+	case "env":
+		return &DotEnvLoader{Reader: r}
+	}
+
+	return nil
+}
+
+// formatOf returns the format of the given file path based on its extension.
+// It returns an empty string if the extension is not supported.
+func formatOf(path string) string {
+	switch {
+	case strings.HasSuffix(path, "toml"):
+		return "toml"
+	case strings.HasSuffix(path, "json"):
+		return "json"
+	case strings.HasSuffix(path, "yml"), strings.HasSuffix(path, "yaml"):
+		return "yaml"
+	case strings.HasSuffix(path, "hcl"):
+		return "hcl"
+	case strings.HasSuffix(path, "ini"):
+		return "ini"
+	case strings.HasSuffix(path, ".env"):
+		return "env"
+	}
+
+	return ""
 }
 
 // New returns a new instance of DefaultLoader without any file loaders.
 func New() *DefaultLoader {
-	loader := MultiLoader(
+	return newDefaultLoader(
 		&TagLoader{},
 		&EnvironmentLoader{},
 		&FlagLoader{},
 	)
+}
 
+// newDefaultLoader returns a DefaultLoader running the given loaders in order
+// and the default validators.
+func newDefaultLoader(loaders ...Loader) *DefaultLoader {
 	d := &DefaultLoader{}
-	d.Loader = loader
-	d.Validator = MultiValidator(&RequiredValidator{})
+	d.Loader = MultiLoader(loaders...)
+	d.Validator = MultiValidator(
+		&RequiredValidator{},
+		&OneOfValidator{},
+		&RangeValidator{},
+		&LenValidator{},
+		&PatternValidator{},
+		MethodValidator{},
+	)
 	return d
 }
 
@@ -91,11 +377,40 @@ func MustLoad(conf interface{}) {
 	d.MustLoad(conf)
 }
 
-// MustLoad is like Load but panics if the config cannot be parsed.
+// Load loads the source into the config defined by struct s. A warning is
+// logged for each deprecated field set by a source, see Logger.
+//
+// s may also be a pointer to a map[string]interface{}, which receives the
+// key trees of the sources holding one, such as files, merged key by key.
+// The sources relying on the fields of a struct, such as the environment
+// variables or the flags, are skipped, and so are the validators.
+func (d *DefaultLoader) Load(s interface{}) error {
+	return d.LoadContext(context.Background(), s)
+}
+
+// LoadContext is like Load but stops once ctx is done, i.e: on shutdown. The
+// remote loaders, such as HTTPLoader or VaultLoader, bind their requests to
+// ctx, while the other loaders are only run if ctx isn't done yet. The error
+// of ctx is returned once it's done, i.e: context.Canceled.
+func (d *DefaultLoader) LoadContext(ctx context.Context, s interface{}) error {
+	return d.run(ctx, s, defaultsLoader{}, d.hooked(d.Loader), deprecationLoader{d.logger()})
+}
+
+// logger returns the Logger of d or, if nil, the standard logger.
+func (d *DefaultLoader) logger() *log.Logger {
+	if d.Logger != nil {
+		return d.Logger
+	}
+
+	return log.Default()
+}
+
+// MustLoad is like LoadAndValidate but exits if the config cannot be parsed
+// or is invalid, unless the error is handled by the ErrorHandler.
 func (d *DefaultLoader) MustLoad(conf interface{}) {
 	if err := d.Load(conf); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(2)
+		d.handleError(err)
+		return
 	}
 
 	// we at koding, believe having sane defaults in our system, this is the
@@ -106,122 +421,302 @@ func (d *DefaultLoader) MustLoad(conf interface{}) {
 	}
 }
 
-// MustValidate validates the struct. It exits with status 1 if it can't
-// validate.
+// Validate validates s with the Validator of d. The booleans and numbers set
+// to their zero value by a source during the last load of s by d satisfy the
+// RequiredValidator, see LoadedFields.
+func (d *DefaultLoader) Validate(s interface{}) error {
+	if sources, ok := d.loads.get(s); ok {
+		defer track(s, sources)()
+	}
+
+	return d.Validator.Validate(s)
+}
+
+// MustValidate validates the struct. It exits with status 2 if it can't
+// validate, unless the error is handled by the ErrorHandler.
 func (d *DefaultLoader) MustValidate(conf interface{}) {
 	if err := d.Validate(conf); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(2)
+		d.handleError(err)
 	}
 }
 
+// LoadAndValidate loads the struct like Load and then validates it, unless
+// there are no validators. It returns the first error, leaving the decision
+// to exit, retry or fall back to the caller.
+func (d *DefaultLoader) LoadAndValidate(conf interface{}) error {
+	if err := d.Load(conf); err != nil {
+		return err
+	}
+
+	if d.Validator == nil {
+		return nil
+	}
+
+	return d.Validate(conf)
+}
+
+// handleError passes err to the ErrorHandler or, if nil, prints it and
+// exits with status 2.
+func (d *DefaultLoader) handleError(err error) {
+	if d.ErrorHandler != nil {
+		d.ErrorHandler(err)
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(2)
+}
+
+// AddValidator adds validators run after the current ones by Validate,
+// MustLoad and Watch, i.e: cross-field checks of the config.
+func (d *DefaultLoader) AddValidator(validators ...Validator) {
+	if d.Validator != nil {
+		validators = append([]Validator{d.Validator}, validators...)
+	}
+
+	d.Validator = MultiValidator(validators...)
+}
+
+// SetValidators replaces the validators, the default ones included.
+func (d *DefaultLoader) SetValidators(validators ...Validator) {
+	d.Validator = MultiValidator(validators...)
+}
+
+// DefaultSliceSeparator is the separator of the elements of a slice value
+// given as a string, i.e: "ankara,istanbul", by environment variables, flags
+// and default tags. The loaders of those sources can use another separator
+// with their SliceSeparator option. File formats such as TOML, JSON and YAML
+// are not affected, they keep using their native list syntax.
+const DefaultSliceSeparator = ","
+
 // fieldSet sets field value from the given string value. It converts the
 // string value in a sane way and is usefulf or environment variables or flags
-// which are by nature in string types.
-func fieldSet(field *structs.Field, v string) error {
-	switch f := field.Value().(type) {
-	case flag.Value:
-		if v := reflect.ValueOf(field.Value()); v.IsNil() {
-			typ := v.Type()
-			if typ.Kind() == reflect.Ptr {
-				typ = typ.Elem()
-			}
+// which are by nature in string types. Slice elements are separated by sep,
+// DefaultSliceSeparator is used if it's empty. path is the path of the field
+// within the loaded struct, used in error messages.
+func fieldSet(field *structs.Field, v string, sep string, path string) error {
+	if field.Kind() == reflect.Interface {
+		return &unsupportedError{name: path, kind: field.Kind()}
+	}
 
-			if err := field.Set(reflect.New(typ).Interface()); err != nil {
-				return err
-			}
+	strategy, err := mergeStrategy(field.Tag(mergeTag), path)
+	if err != nil {
+		return err
+	}
 
-			f = field.Value().(flag.Value)
+	// work on a settable copy of the field's value, which is stored back
+	// once the string is converted
+	old := reflect.ValueOf(field.Value())
+	val := reflect.New(old.Type()).Elem()
+	if !(strategy == mergeReplace && val.Kind() == reflect.Map) {
+		val.Set(old)
+	}
+
+	if layout := field.Tag(layoutTag); layout != "" && isTimeType(val.Type()) {
+		err = setTime(val, v, sep, layout, path)
+	} else {
+		err = setString(val, v, sep, path)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return field.Set(mergeSlices(strategy, old, val).Interface())
+}
+
+var (
+	flagValueType = reflect.TypeOf((*flag.Value)(nil)).Elem()
+	durationType  = reflect.TypeOf(time.Duration(0))
+)
+
+// setString sets the value v from the given string value s, converted to the
+// type of v. name is the path of the field holding v, used in error
+// messages.
+func setString(v reflect.Value, s string, sep string, name string) error {
+	err := convertString(v, s, sep, name)
+	if _, ok := err.(*FieldError); err == nil || ok || isUnsupported(err) {
+		return err
+	}
+
+	return fieldErr(name, s, err)
+}
+
+// convertString is like setString but doesn't add the name of the field to
+// the conversion errors.
+func convertString(v reflect.Value, s string, sep string, name string) error {
+	if ok, err := decodeHook(v, s); ok {
+		return err
+	}
+
+	// a nil pointer implementing flag.Value is allocated before being set
+	if v.Kind() == reflect.Ptr && v.Type().Implements(flagValueType) {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
 		}
 
-		return f.Set(v)
+		return v.Interface().(flag.Value).Set(s)
 	}
 
-	// TODO: add support for other types
-	switch field.Kind() {
+	if v.CanAddr() && v.Addr().Type().Implements(flagValueType) {
+		return v.Addr().Interface().(flag.Value).Set(s)
+	}
+
+	if ok, err := unmarshalText(v, []byte(s)); ok {
+		return err
+	}
+
+	if ok, err := setEnum(v, s); ok {
+		return err
+	}
+
+	switch v.Kind() {
 	case reflect.Bool:
-		val, err := strconv.ParseBool(v)
+		val, err := strconv.ParseBool(s)
 		if err != nil {
 			return err
 		}
 
-		if err := field.Set(val); err != nil {
-			return err
+		v.SetBool(val)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == durationType {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return err
+			}
+
+			v.SetInt(int64(d))
+			return nil
 		}
-	case reflect.Int:
-		i, err := strconv.Atoi(v)
+
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
 		if err != nil {
 			return err
 		}
 
-		if err := field.Set(i); err != nil {
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
 			return err
 		}
-	case reflect.String:
-		if err := field.Set(v); err != nil {
+
+		v.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
 			return err
 		}
+
+		v.SetFloat(f)
+	case reflect.String:
+		v.SetString(s)
 	case reflect.Slice:
-		switch t := field.Value().(type) {
-		case []string:
-			if err := field.Set(strings.Split(v, ",")); err != nil {
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b, err := decodeBytes(s, false)
+			if err != nil {
 				return err
 			}
-		case []int:
-			var list []int
-			for _, in := range strings.Split(v, ",") {
-				i, err := strconv.Atoi(in)
-				if err != nil {
-					return err
-				}
 
-				list = append(list, i)
-			}
+			v.SetBytes(b)
+			return nil
+		}
+
+		elems := splitList(s, sep)
+		list := reflect.MakeSlice(v.Type(), len(elems), len(elems))
+		for i, elem := range elems {
+			if err := convertString(list.Index(i), elem, sep, name); err != nil {
+				if isUnsupported(err) {
+					return fieldErrorf(name, s, "of type slice is unsupported: %s (%s)", v.Kind(), v.Type())
+				}
 
-			if err := field.Set(list); err != nil {
 				return err
 			}
-		default:
-			return fmt.Errorf("multiconfig: field '%s' of type slice is unsupported: %s (%T)",
-				field.Name(), field.Kind(), t)
-		}
-	case reflect.Float64:
-		f, err := strconv.ParseFloat(v, 64)
-		if err != nil {
-			return err
 		}
 
-		if err := field.Set(f); err != nil {
-			return err
+		v.Set(list)
+	case reflect.Map:
+		// entries are added to a copy of the map, so a map shared with
+		// another value isn't modified
+		m := reflect.MakeMap(v.Type())
+		if !v.IsNil() {
+			iter := v.MapRange()
+			for iter.Next() {
+				m.SetMapIndex(iter.Key(), iter.Value())
+			}
 		}
-	case reflect.Int64:
-		switch t := field.Value().(type) {
-		case time.Duration:
-			d, err := time.ParseDuration(v)
-			if err != nil {
-				return err
+
+		for _, entry := range splitList(s, sep) {
+			kv := strings.SplitN(entry, "=", 2)
+			if len(kv) != 2 {
+				return fmt.Errorf("invalid map entry %q, expected key=value", entry)
 			}
 
-			if err := field.Set(d); err != nil {
-				return err
+			key := reflect.New(v.Type().Key()).Elem()
+			err := convertString(key, strings.TrimSpace(kv[0]), sep, name)
+
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err == nil {
+				err = convertString(elem, kv[1], sep, name)
 			}
-		case int64:
-			p, err := strconv.ParseInt(v, 10, 0)
-			if err != nil {
-				return err
+
+			if isUnsupported(err) {
+				return fieldErrorf(name, s, "of type map is unsupported: %s (%s)", v.Kind(), v.Type())
 			}
 
-			if err := field.Set(p); err != nil {
+			if err != nil {
 				return err
 			}
-		default:
-			return fmt.Errorf("multiconfig: field '%s' of type int64 is unsupported: %s (%T)",
-				field.Name(), field.Kind(), t)
+
+			m.SetMapIndex(key, elem)
 		}
 
+		v.Set(m)
+	case reflect.Ptr:
+		// a new value is allocated, so a value shared with another pointer
+		// isn't modified
+		elem := reflect.New(v.Type().Elem())
+		if err := convertString(elem.Elem(), s, sep, name); err != nil {
+			return err
+		}
+
+		v.Set(elem)
 	default:
-		return fmt.Errorf("multiconfig: field '%s' has unsupported type: %s", field.Name(), field.Kind())
+		return &unsupportedError{name: name, kind: v.Kind()}
 	}
 
 	return nil
 }
+
+// unsupportedError states that a field's type can't be set from a string.
+type unsupportedError struct {
+	name string
+	kind reflect.Kind
+}
+
+func (e *unsupportedError) Error() string {
+	return fmt.Sprintf("multiconfig: field '%s' has unsupported type: %s", e.name, e.kind)
+}
+
+func isUnsupported(err error) bool {
+	_, ok := err.(*unsupportedError)
+	return ok
+}
+
+// splitList splits the given slice value into its elements separated by sep,
+// or DefaultSliceSeparator if sep is empty. Empty elements are dropped, so an
+// empty value results in an empty slice.
+func splitList(v, sep string) []string {
+	if sep == "" {
+		sep = DefaultSliceSeparator
+	}
+
+	list := []string{}
+	for _, elem := range strings.Split(v, sep) {
+		if elem != "" {
+			list = append(list, elem)
+		}
+	}
+
+	return list
+}
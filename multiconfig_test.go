@@ -1,6 +1,11 @@
 package multiconfig
 
 import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -74,9 +79,13 @@ type (
 )
 
 var (
-	testTOML = "testdata/config.toml"
-	testJSON = "testdata/config.json"
-	testYAML = "testdata/config.yaml"
+	testTOML    = "testdata/config.toml"
+	testJSON    = "testdata/config.json"
+	testYAML    = "testdata/config.yaml"
+	testHCL     = "testdata/config.hcl"
+	testINI     = "testdata/config.ini"
+	testOverlay = "testdata/overlay.yaml"
+	testDotEnv  = "testdata/config.env"
 )
 
 func getDefaultServer() *Server {
@@ -156,6 +165,7 @@ func TestLoad(t *testing.T) {
 }
 
 func TestDefaultLoader(t *testing.T) {
+	setEnvVars(t, "Server", "")
 	m := New()
 
 	s := new(Server)
@@ -174,6 +184,83 @@ func TestDefaultLoader(t *testing.T) {
 	}
 }
 
+func TestDefaultLoaderConcurrent(t *testing.T) {
+	flags := &FlagLoader{Args: []string{"-name", "flag"}}
+	m := newDefaultLoader(
+		&TagLoader{},
+		&TOMLLoader{Path: testTOML},
+		&EnvironmentLoader{getenv: testEnvironment{"SERVER_PORT": "7070"}.get},
+		flags,
+	)
+
+	want := getDefaultServer()
+	want.Name = "flag"
+	want.Port = 7070
+
+	// a single loader is shared by the goroutines, i.e: request handlers
+	var wg sync.WaitGroup
+	servers := make([]*Server, 8)
+	errs := make([]error, len(servers))
+	for i := range servers {
+		servers[i] = new(Server)
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = m.LoadAndValidate(servers[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for i, s := range servers {
+		if errs[i] != nil {
+			t.Fatalf("server %d: %s", i, errs[i])
+		}
+
+		testStruct(t, s, want)
+	}
+
+	if flags.Command() != "" {
+		t.Errorf("unexpected command: %q", flags.Command())
+	}
+}
+
+func TestDefaultLoaderContext(t *testing.T) {
+	srv := newConfigServer(t)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	s := new(Server)
+	err := newDefaultLoader(&TagLoader{}).LoadContext(ctx, s)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a canceled error, got: %v", err)
+	}
+
+	if s.Port != 0 {
+		t.Errorf("the loaders should not run once the context is done: %+v", s)
+	}
+
+	for i, tracer := range []Tracer{nil, TracerFunc(func(TraceEvent) {})} {
+		m := newDefaultLoader(&TagLoader{}, &HTTPLoader{URL: srv.URL + "/slow"}, &EnvironmentLoader{})
+		m.Tracer = tracer
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		s := new(Server)
+		err := m.LoadContext(ctx, s)
+		if err == nil || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+			t.Errorf("%d: expected a deadline error, got: %v", i, err)
+		}
+
+		if s.Port != 6060 {
+			t.Errorf("%d: the loaders preceding the remote one should run: %+v", i, s)
+		}
+	}
+}
+
 func testStruct(t *testing.T, s *Server, d *Server) {
 	if s.Name != d.Name {
 		t.Errorf("Name value is wrong: %s, want: %s", s.Name, d.Name)
@@ -284,3 +371,224 @@ func TestLoadApp(t *testing.T) {
 		t.Errorf("diff = %s", diff)
 	}
 }
+
+func TestNewWithPaths(t *testing.T) {
+	m := NewWithPaths(testTOML, testOverlay)
+
+	s := new(Server)
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	want := getDefaultServer()
+	want.Name = "overlay"
+	want.Users = []string{"izmir"}
+	want.Postgres.Port = 6432
+
+	testStruct(t, s, want)
+}
+
+func TestNewWithReader(t *testing.T) {
+	formats := map[string]Format{
+		testTOML: TOML,
+		testJSON: JSON,
+		testYAML: YAML,
+		testHCL:  HCL,
+		testINI:  INI,
+	}
+
+	for path, format := range formats {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s := new(Server)
+		err = NewWithReader(f, format).Load(s)
+		f.Close()
+		if err != nil {
+			t.Errorf("%s: %s", format, err)
+			continue
+		}
+
+		testStruct(t, s, getDefaultServer())
+	}
+
+	err := NewWithReader(strings.NewReader(""), "xml").Load(new(Server))
+	if err == nil || !strings.Contains(err.Error(), `unsupported format "xml"`) {
+		t.Errorf("loading an unsupported format should fail, got: %v", err)
+	}
+}
+
+func TestNewWithProfiles(t *testing.T) {
+	s := new(Server)
+	if err := NewWithProfiles(testTOML, "dev").Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	want := getDefaultServer()
+	want.Name = "koding-dev"
+	want.Users = []string{"dev"}
+	want.Postgres.Port = 6432
+	testStruct(t, s, want)
+
+	// a missing profile file is skipped
+	s = new(Server)
+	if err := NewWithProfiles(testTOML, "prod", "").Load(s); err != nil {
+		t.Fatal(err)
+	}
+	testStruct(t, s, getDefaultServer())
+
+	if p := profilePath("conf/config.toml", "dev"); p != "conf/config.dev.toml" {
+		t.Errorf("profile path is %s, want conf/config.dev.toml", p)
+	}
+}
+
+func TestNewWithPathsGlob(t *testing.T) {
+	want := &Server{
+		Name:    "koding",
+		Port:    6060,
+		Enabled: true,
+		Users:   []string{"ankara", "istanbul"},
+		Postgres: Postgres{
+			Enabled: true,
+			Port:    6432,
+			Hosts:   []string{"192.168.2.1"},
+			DBName:  "configdb",
+		},
+	}
+
+	for _, path := range []string{"testdata/conf.d", "testdata/conf.d/*"} {
+		s := new(Server)
+		if err := NewWithPaths(path).Load(s); err != nil {
+			t.Fatalf("%s: %s", path, err)
+		}
+
+		testStruct(t, s, want)
+	}
+
+	// only the TOML and the YAML files match
+	s := new(Server)
+	if err := NewWithFS(os.DirFS("testdata"), "conf.d/*.*ml").Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	want.Postgres.Port = 5432
+	testStruct(t, s, want)
+}
+
+func TestErrorHandler(t *testing.T) {
+	var handled []error
+
+	m := newDefaultLoader(&EnvironmentLoader{getenv: testEnvironment{"SERVER_PORT": "http"}.get})
+	m.ErrorHandler = func(err error) { handled = append(handled, err) }
+
+	// the error of Load is handled instead of exiting, and the config is not
+	// validated any further
+	m.MustLoad(new(Server))
+	if len(handled) != 1 || !strings.Contains(handled[0].Error(), "http") {
+		t.Fatalf("unexpected handled errors: %v", handled)
+	}
+
+	m = NewWithPath(testTOML)
+	m.ErrorHandler = func(err error) { handled = append(handled, err) }
+	m.AddValidator(ValidatorFunc(func(interface{}) error {
+		return errors.New("multiconfig: invalid config")
+	}))
+
+	s := new(Server)
+	m.MustLoad(s)
+	if len(handled) != 2 || handled[1].Error() != "multiconfig: invalid config" {
+		t.Fatalf("unexpected handled errors: %v", handled)
+	}
+
+	if err := m.LoadAndValidate(s); err == nil || err.Error() != "multiconfig: invalid config" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	m.SetValidators()
+	if err := m.LoadAndValidate(s); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSetNameTag(t *testing.T) {
+	type Database struct {
+		DBName  string `json:"db_name"`
+		MaxConn int    `json:"max_conn,omitempty"`
+		Host    string `json:"-"`
+	}
+
+	type API struct {
+		Name     string   `json:"service_name"`
+		Database Database `json:"database"`
+	}
+
+	m := newDefaultLoader(
+		&TOMLLoader{Reader: strings.NewReader("service_name = \"api\"\n[database]\ndb_name = \"app\"\nHost = \"db\"\n"), FileOptions: FileOptions{Strict: true}},
+		&EnvironmentLoader{getenv: testEnvironment{"API_DATABASE_MAX_CONN": "10"}.get},
+		&FlagLoader{Args: []string{"-database-db_name", "main"}},
+	)
+	m.SetNameTag("json")
+
+	s := &API{}
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	want := &API{Name: "api", Database: Database{DBName: "main", MaxConn: 10, Host: "db"}}
+	if diff := cmp.Diff(want, s); diff != "" {
+		t.Errorf("config is wrong (-want +got):\n%s", diff)
+	}
+}
+
+func TestFlattenedStructs(t *testing.T) {
+	type Cache struct {
+		Addr string
+		TTL  int
+	}
+
+	type App struct {
+		Name     string
+		Postgres `structs:",flatten"`
+		Cache    *Cache `structs:",flatten"`
+	}
+
+	m := newDefaultLoader(
+		&TOMLLoader{Reader: strings.NewReader("Name = \"app\"\nPort = 5432\nAddr = \"redis:6379\"\n"), FileOptions: FileOptions{Strict: true}},
+		&EnvironmentLoader{getenv: testEnvironment{"APP_DBNAME": "main", "APP_TTL": "60"}.get},
+		&FlagLoader{Args: []string{"-hosts", "db1,db2"}},
+	)
+
+	s := &App{}
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Port != 5432 || s.DBName != "main" || len(s.Hosts) != 2 {
+		t.Errorf("Postgres value is wrong: %+v", s.Postgres)
+	}
+
+	if s.Cache == nil || s.Cache.Addr != "redis:6379" || s.Cache.TTL != 60 {
+		t.Errorf("Cache value is wrong: %+v", s.Cache)
+	}
+
+	var flags []string
+	for _, o := range (&FlagLoader{}).Options(&App{}) {
+		flags = append(flags, o.Flag+" "+o.Env)
+	}
+
+	want := []string{
+		"name APP_NAME",
+		"enabled APP_ENABLED",
+		"port APP_PORT",
+		"hosts APP_HOSTS",
+		"dbname APP_DBNAME",
+		"availabilityratio APP_AVAILABILITYRATIO",
+		"addr APP_ADDR",
+		"ttl APP_TTL",
+	}
+	if diff := cmp.Diff(want, flags); diff != "" {
+		t.Errorf("flags are wrong (-want +got):\n%s", diff)
+	}
+}
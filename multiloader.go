@@ -1,5 +1,7 @@
 package multiconfig
 
+import "context"
+
 type multiLoader []Loader
 
 // MultiLoader creates a loader that executes the loaders one by one in order
@@ -10,8 +12,17 @@ func MultiLoader(loader ...Loader) Loader {
 
 // Load loads the source into the config defined by struct s
 func (m multiLoader) Load(s interface{}) error {
+	return m.LoadContext(context.Background(), s)
+}
+
+// LoadContext is like Load but stops once ctx is done. The loaders which are
+// a ContextLoader are given ctx, the others are only run if ctx isn't done
+// yet.
+func (m multiLoader) LoadContext(ctx context.Context, s interface{}) error {
+	defer startTracking(s)()
+
 	for _, loader := range m {
-		if err := loader.Load(s); err != nil {
+		if err := loadContext(ctx, loader, s); err != nil {
 			return err
 		}
 	}
@@ -19,6 +30,20 @@ func (m multiLoader) Load(s interface{}) error {
 	return nil
 }
 
+// loadContext loads s with the loader l, bound to ctx if l is a
+// ContextLoader. It returns the error of ctx if it's done before l is run.
+func loadContext(ctx context.Context, l Loader, s interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if cl, ok := l.(ContextLoader); ok {
+		return cl.LoadContext(ctx, s)
+	}
+
+	return l.Load(s)
+}
+
 // MustLoad loads the source into the struct, it panics if gets any error
 func (m multiLoader) MustLoad(s interface{}) {
 	if err := m.Load(s); err != nil {
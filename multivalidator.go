@@ -8,16 +8,19 @@ func MultiValidator(validators ...Validator) Validator {
 }
 
 // Validate tries to validate given struct with all the validators. If it doesn't
-// have any Validator it will simply skip the validation step. If any of the
-// given validators return err, it will stop validating and return it.
+// have any Validator it will simply skip the validation step. The errors of
+// all the validators are returned together as ValidationErrors.
 func (d multiValidator) Validate(s interface{}) error {
+	if isRaw(s) {
+		return nil
+	}
+
+	var errs ValidationErrors
 	for _, validator := range d {
-		if err := validator.Validate(s); err != nil {
-			return err
-		}
+		errs.add(validator.Validate(s))
 	}
 
-	return nil
+	return errs.err()
 }
 
 // MustValidate validates the struct, it panics if gets any error
@@ -0,0 +1,216 @@
+package multiconfig
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/fatih/structs"
+)
+
+// LoadWithOverrides loads s like Load and then sets the given overrides,
+// keyed by the dotted path of their field, i.e: "Name" or "Postgres.Port".
+// Override values are converted to the type of their field the same way the
+// values of a file are: a string is parsed like an environment variable, a
+// number is converted to any numeric kind as long as it fits, and so on.
+//
+// Unknown paths are reported together in a single error, before any
+// override is set.
+func (d *DefaultLoader) LoadWithOverrides(s interface{}, overrides map[string]interface{}) error {
+	return d.run(context.Background(), s, defaultsLoader{}, d.hooked(d.Loader), overrideLoader(overrides), deprecationLoader{d.logger()})
+}
+
+// overrideLoader loads values keyed by dotted field paths.
+type overrideLoader map[string]interface{}
+
+// Load sets the values of o into the fields of the struct s.
+func (o overrideLoader) Load(s interface{}) error {
+	v := reflect.ValueOf(s)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("multiconfig: cannot load into %T, a non-nil pointer is required", s)
+	}
+
+	paths := make([]string, 0, len(o))
+	for path := range o {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var unknown []string
+	fields := make([]reflect.Value, len(paths))
+	for i, path := range paths {
+		field, ok := fieldByName(v.Elem(), path)
+		if !ok {
+			unknown = append(unknown, path)
+		}
+		fields[i] = field
+	}
+
+	if len(unknown) > 0 {
+		return fmt.Errorf("multiconfig: unknown override fields: %s", strings.Join(unknown, ", "))
+	}
+
+	d := &decoder{
+		format:   "override",
+		target:   s,
+		source:   "override",
+		tracking: isTracking(s),
+	}
+
+	for i, path := range paths {
+		if err := d.override(path, o[path], fields[i]); err != nil {
+			return err
+		}
+
+		markLoaded(s, path, d.source)
+	}
+
+	return nil
+}
+
+// override sets data into v, which is replaced as a whole by a value of its
+// own type and converted otherwise.
+func (d *decoder) override(path string, data interface{}, v reflect.Value) error {
+	if data != nil && reflect.TypeOf(data).AssignableTo(v.Type()) {
+		v.Set(reflect.ValueOf(data))
+		return nil
+	}
+
+	return d.value(path, data, v)
+}
+
+// fieldByName returns the exported field at the given dotted path of the
+// struct v, allocating the nil pointers along the way. Names are matched
+// exactly and may refer to fields promoted from embedded structs.
+func fieldByName(v reflect.Value, path string) (reflect.Value, bool) {
+	for _, name := range strings.Split(path, ".") {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+
+		field, ok := v.Type().FieldByName(name)
+		if !ok || field.PkgPath != "" {
+			return reflect.Value{}, false
+		}
+
+		v = fieldByIndex(v, field.Index)
+	}
+
+	return v, true
+}
+
+// OverrideLoader satisfies the loader interface. It sets the fields named by
+// the dotted paths of its overrides in the form of "path=value", i.e:
+// "postgres.port=5433", an escape hatch to set any field without a flag.
+// Paths are matched case-insensitively and values are parsed like the ones of
+// environment variables. When a path is given several times, the last value
+// is set.
+type OverrideLoader struct {
+	// Overrides are the "path=value" overrides to set.
+	Overrides []string
+}
+
+// Load sets the overrides into the fields of the struct s. Unknown paths are
+// reported together in a single error, before any override is set.
+func (o *OverrideLoader) Load(s interface{}) error {
+	t := reflect.TypeOf(s)
+
+	var paths []string
+	values := make(map[string]string, len(o.Overrides))
+	for _, override := range o.Overrides {
+		kv := strings.SplitN(override, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return fmt.Errorf("multiconfig: invalid override '%s', want path=value", override)
+		}
+
+		path := resolvePath(t, kv[0])
+		if _, ok := values[path]; !ok {
+			paths = append(paths, path)
+		}
+		values[path] = kv[1]
+	}
+
+	var unknown []string
+	fields := make([]*structs.Field, len(paths))
+	for i, path := range paths {
+		field, ok := fieldByPath(s, path)
+		if !ok {
+			unknown = append(unknown, path)
+		}
+		fields[i] = field
+	}
+
+	if len(unknown) > 0 {
+		return fmt.Errorf("multiconfig: unknown override fields: %s", strings.Join(unknown, ", "))
+	}
+
+	for i, path := range paths {
+		path = fieldPath(s, strings.Split(path, ".")...)
+		if err := fieldSet(fields[i], values[paths[i]], "", path); err != nil {
+			return withLoader(err, "override")
+		}
+
+		markLoaded(s, path, "override")
+	}
+
+	return nil
+}
+
+// resolvePath returns the dotted path of the struct type t matching the
+// given path case-insensitively, i.e: "Postgres.Port" for "postgres.port".
+// The names which don't match any field are kept as is.
+func resolvePath(t reflect.Type, path string) string {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		if t == nil || t.Kind() != reflect.Struct {
+			break
+		}
+
+		field, ok := t.FieldByName(name)
+		if !ok {
+			field, ok = t.FieldByNameFunc(func(n string) bool { return strings.EqualFold(n, name) })
+		}
+
+		if !ok {
+			break
+		}
+
+		names[i] = field.Name
+		t = field.Type
+	}
+
+	return strings.Join(names, ".")
+}
+
+// overrideFlag is the flag.Value of a repeatable flag collecting overrides.
+type overrideFlag []string
+
+func (o *overrideFlag) Set(val string) error {
+	if !strings.Contains(val, "=") {
+		return fmt.Errorf("want path=value, got '%s'", val)
+	}
+
+	*o = append(*o, val)
+	return nil
+}
+
+func (o *overrideFlag) String() string {
+	if o == nil {
+		return ""
+	}
+
+	return strings.Join(*o, ",")
+}
@@ -0,0 +1,135 @@
+package multiconfig
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadWithOverrides(t *testing.T) {
+	m := NewWithPath(testTOML)
+
+	s := new(Server)
+	overrides := map[string]interface{}{
+		"Name":                       "override",
+		"Port":                       int32(7070),
+		"Interval":                   "1m",
+		"Users":                      []string{"izmir"},
+		"Labels":                     []interface{}{1, "2"},
+		"Postgres.Port":              "6432",
+		"Postgres.Hosts":             []string{"localhost"},
+		"Postgres.AvailabilityRatio": 1,
+	}
+
+	if err := m.LoadWithOverrides(s, overrides); err != nil {
+		t.Fatal(err)
+	}
+
+	want := getDefaultServer()
+	want.Name = "override"
+	want.Port = 7070
+	want.Interval = time.Minute
+	want.Users = []string{"izmir"}
+	want.Labels = []int{1, 2}
+	want.Postgres.Port = 6432
+	want.Postgres.Hosts = []string{"localhost"}
+	want.Postgres.AvailabilityRatio = 1
+
+	testStruct(t, s, want)
+
+	if !strings.Contains(strings.Join(m.LoadedFields(s), " "), "Postgres.Port") {
+		t.Errorf("Postgres.Port should be reported as loaded, got %v", m.LoadedFields(s))
+	}
+}
+
+func TestLoadWithOverridesErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]interface{}
+		err       string
+	}{
+		{
+			name:      "unknown paths",
+			overrides: map[string]interface{}{"Postgres.Nope": 1, "Nope": 1, "Name.Nope": 1},
+			err:       "unknown override fields: Name.Nope, Nope, Postgres.Nope",
+		},
+		{
+			name:      "unconvertible value",
+			overrides: map[string]interface{}{"Postgres.Port": []string{"5432"}},
+			err:       "field 'Postgres.Port'",
+		},
+		{
+			name:      "invalid string",
+			overrides: map[string]interface{}{"Enabled": "maybe"},
+			err:       "field 'Enabled'",
+		},
+		{
+			name:      "overflow",
+			overrides: map[string]interface{}{"Port": uint64(1 << 63)},
+			err:       "field 'Port'",
+		},
+	}
+
+	for _, test := range tests {
+		err := NewWithPath(testTOML).LoadWithOverrides(new(Server), test.overrides)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, want it to contain %q", test.name, err, test.err)
+		}
+	}
+}
+
+func TestOverrideLoader(t *testing.T) {
+	s := &Server{Name: "koding"}
+	o := &OverrideLoader{Overrides: []string{
+		"postgres.port=5433",
+		"PORT=7070",
+		"users=izmir,ankara",
+		"postgres.port=5434",
+	}}
+
+	if err := o.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Postgres.Port != 5434 || s.Port != 7070 || s.Name != "koding" {
+		t.Errorf("unexpected config: %+v", s)
+	}
+
+	if len(s.Users) != 2 || s.Users[1] != "ankara" {
+		t.Errorf("Users value is wrong: %v", s.Users)
+	}
+
+	errs := map[string]string{
+		"postgres.port":      "multiconfig: invalid override 'postgres.port', want path=value",
+		"postgres.prot=5433": "multiconfig: unknown override fields: Postgres.prot",
+	}
+
+	for override, want := range errs {
+		err := (&OverrideLoader{Overrides: []string{override}}).Load(&Server{})
+		if err == nil || err.Error() != want {
+			t.Errorf("%s: unexpected error: %v", override, err)
+		}
+	}
+}
+
+func TestFlagSetOverrides(t *testing.T) {
+	m := &FlagLoader{
+		SetFlag: "set",
+		Args:    []string{"-set", "postgres.dbname=flagdb", "-postgres-dbname", "configdb", "-set", "name=koding"},
+	}
+
+	s := &Server{}
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	// the overrides are set after the other flags
+	if s.Postgres.DBName != "flagdb" || s.Name != "koding" {
+		t.Errorf("unexpected config: %+v", s)
+	}
+
+	m.Args = []string{"-set", "postgres.dbname"}
+	if err := m.Load(s); err == nil {
+		t.Error("an override without value should fail")
+	}
+}
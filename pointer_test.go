@@ -0,0 +1,92 @@
+package multiconfig
+
+import (
+	"strings"
+	"testing"
+)
+
+type PtrConfig struct {
+	Port    *int `default:"80"`
+	Enabled *bool
+	Name    *string
+	DB      *Postgres
+	Missing *int
+	Cache   *Postgres
+}
+
+func TestPointerFields(t *testing.T) {
+	sources := map[string]Loader{
+		"toml": &TOMLLoader{Reader: strings.NewReader(`
+Enabled = false
+Name = "koding"
+
+[DB]
+Port = 5432
+`)},
+		"env": &EnvironmentLoader{
+			getenv: testEnvironment{
+				"PTRCONFIG_ENABLED": "false",
+				"PTRCONFIG_NAME":    "koding",
+				"PTRCONFIG_DB_PORT": "5432",
+			}.get,
+		},
+		"flags": &FlagLoader{Args: []string{"-enabled=false", "-name", "koding", "-db-port", "5432"}},
+	}
+
+	for name, loader := range sources {
+		s := &PtrConfig{}
+		if err := MultiLoader(&TagLoader{}, loader).Load(s); err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+
+		if s.Port == nil || *s.Port != 80 {
+			t.Errorf("%s: Port is %v, want a pointer to 80", name, s.Port)
+		}
+
+		if s.Enabled == nil || *s.Enabled {
+			t.Errorf("%s: Enabled is %v, want a pointer to false", name, s.Enabled)
+		}
+
+		if s.Name == nil || *s.Name != "koding" {
+			t.Errorf("%s: Name is %v, want a pointer to koding", name, s.Name)
+		}
+
+		if s.DB == nil || s.DB.Port != 5432 {
+			t.Errorf("%s: DB is %+v, want a struct with port 5432", name, s.DB)
+		}
+
+		if s.Missing != nil || s.Cache != nil {
+			t.Errorf("%s: fields not provided are set: %v, %+v", name, s.Missing, s.Cache)
+		}
+	}
+}
+
+func TestPointerFieldsRequired(t *testing.T) {
+	type Required struct {
+		Port *int `required:"true"`
+	}
+
+	if err := (&RequiredValidator{}).Validate(&Required{}); err == nil {
+		t.Error("expected an error for a nil required pointer")
+	}
+
+	zero := 0
+	if err := (&RequiredValidator{}).Validate(&Required{Port: &zero}); err != nil {
+		t.Errorf("a pointer to a zero value is provided: %s", err)
+	}
+}
+
+func TestPointerFieldsShared(t *testing.T) {
+	port := 80
+	s := &PtrConfig{Port: &port, Missing: &port}
+
+	env := &EnvironmentLoader{getenv: testEnvironment{"PTRCONFIG_PORT": "8080"}.get}
+	if err := env.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if *s.Port != 8080 || *s.Missing != 80 || port != 80 {
+		t.Errorf("pointed value modified in place: port %d, missing %d", *s.Port, *s.Missing)
+	}
+}
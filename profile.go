@@ -0,0 +1,65 @@
+package multiconfig
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+)
+
+// NewWithProfiles returns a new instance of Loader to read from the given
+// configuration file, overlaid by the file of each given profile, i.e:
+//
+//	m := multiconfig.NewWithProfiles("config.toml", os.Getenv("APP_ENV"))
+//
+// loads config.toml then config.dev.toml if APP_ENV is "dev". The file of a
+// profile is named after the configuration file, with the profile inserted
+// before the extension. It's optional: a missing profile file is skipped.
+// Empty profiles are ignored.
+//
+// An overlay only overrides the keys it defines, nested structs included, the
+// same way the files given to NewWithPaths are merged.
+func NewWithProfiles(path string, profiles ...string) *DefaultLoader {
+	loaders := []Loader{&TagLoader{}}
+	if l := fileLoader(nil, path); l != nil {
+		loaders = append(loaders, l)
+	}
+
+	for _, profile := range profiles {
+		if profile == "" {
+			continue
+		}
+
+		if l := fileLoader(nil, profilePath(path, profile)); l != nil {
+			loaders = append(loaders, &optionalLoader{l})
+		}
+	}
+
+	loaders = append(loaders, &EnvironmentLoader{}, &FlagLoader{})
+	return newDefaultLoader(loaders...)
+}
+
+// profilePath returns the path of the file of the given profile, i.e:
+// "config.dev.toml" for the "dev" profile of "config.toml".
+func profilePath(path, profile string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + profile + ext
+}
+
+// optionalLoader is a file loader ignoring a missing file.
+type optionalLoader struct {
+	Loader
+}
+
+// Load loads the file into the struct s, if it exists.
+func (o *optionalLoader) Load(s interface{}) error {
+	return o.LoadContext(context.Background(), s)
+}
+
+// LoadContext is like Load but bound to ctx.
+func (o *optionalLoader) LoadContext(ctx context.Context, s interface{}) error {
+	if err := loadContext(ctx, o.Loader, s); err != ErrFileNotFound {
+		return err
+	}
+
+	return nil
+}
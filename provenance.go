@@ -0,0 +1,194 @@
+package multiconfig
+
+import (
+	"container/list"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// maxRecords is the number of structs whose sources a DefaultLoader
+// remembers. The sources of the least recently loaded structs are forgotten
+// first.
+const maxRecords = 1024
+
+// Sources returns the source which set each field of s during its last load,
+// keyed by the path of the field, i.e: "Postgres.Port". Sources are named
+// after their loader: "default tag", the path of a file or the format of a
+// reader, "env SERVER_PORT", "flag -port" or "override". When several
+// loaders set a field, the last one, which won, is reported. It returns nil
+// if s was never loaded by d, or if it's not among the last 1024 structs
+// loaded by d.
+func (d *DefaultLoader) Sources(s interface{}) map[string]string {
+	sources, _ := d.loads.get(s)
+	return sources
+}
+
+// record remembers the sources of the fields set during the load of s by d.
+func (d *DefaultLoader) record(s interface{}) {
+	d.loads.set(s, loadedSources(s))
+}
+
+// forget forgets the sources of the fields of s, i.e: once Watch replaced
+// it.
+func (d *DefaultLoader) forget(s interface{}) {
+	d.loads.remove(s)
+}
+
+// moveRecord moves the sources recorded for the struct from, nested at the
+// given path, to the struct s, i.e: "Postgres.Port" of from becomes "Port"
+// of s. Every field is moved if path is empty. The sources of from are
+// forgotten.
+func (d *DefaultLoader) moveRecord(from, s interface{}, path string) {
+	recorded, ok := d.loads.remove(from)
+	if !ok {
+		return
+	}
+
+	sources := make(map[string]string)
+	for p, source := range recorded {
+		if path == "" {
+			sources[p] = source
+		} else if strings.HasPrefix(p, path+".") {
+			sources[strings.TrimPrefix(p, path+".")] = source
+		}
+	}
+
+	d.loads.set(s, sources)
+}
+
+// records holds the sources of the fields of the last maxRecords structs
+// loaded by a DefaultLoader. Structs are identified by their type and
+// address rather than by their pointer, so remembering their sources doesn't
+// keep them alive. A struct allocated at the address of a collected one of
+// the same type is reported the sources of the collected one until loaded.
+// Its zero value is ready to use.
+type records struct {
+	sync.Mutex
+
+	// m maps the key of each struct to its element in order.
+	m map[recordKey]*list.Element
+
+	// order holds the records, the most recently loaded struct first.
+	order *list.List
+}
+
+// recordKey identifies a loaded struct.
+type recordKey struct {
+	typ  reflect.Type
+	addr uintptr
+}
+
+// record is the sources of the fields of the struct of key.
+type record struct {
+	key     recordKey
+	sources map[string]string
+}
+
+// keyOf returns the key of the struct pointed by s. Only non-nil pointers
+// can be recorded.
+func keyOf(s interface{}) (recordKey, bool) {
+	v := reflect.ValueOf(s)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return recordKey{}, false
+	}
+
+	return recordKey{typ: v.Type(), addr: v.Pointer()}, true
+}
+
+// get returns a copy of the sources recorded for s.
+func (r *records) get(s interface{}) (map[string]string, bool) {
+	key, ok := keyOf(s)
+	if !ok {
+		return nil, false
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	e, ok := r.m[key]
+	if !ok {
+		return nil, false
+	}
+
+	return copySources(e.Value.(*record).sources), true
+}
+
+// set records the sources of s, forgetting the least recently loaded struct
+// if maxRecords are already recorded.
+func (r *records) set(s interface{}, sources map[string]string) {
+	key, ok := keyOf(s)
+	if !ok {
+		return
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	if r.m == nil {
+		r.m = make(map[recordKey]*list.Element)
+		r.order = list.New()
+	}
+
+	if e, ok := r.m[key]; ok {
+		e.Value.(*record).sources = sources
+		r.order.MoveToFront(e)
+		return
+	}
+
+	r.m[key] = r.order.PushFront(&record{key: key, sources: sources})
+
+	if r.order.Len() > maxRecords {
+		oldest := r.order.Remove(r.order.Back()).(*record)
+		delete(r.m, oldest.key)
+	}
+}
+
+// remove forgets the sources of s and returns them.
+func (r *records) remove(s interface{}) (map[string]string, bool) {
+	key, ok := keyOf(s)
+	if !ok {
+		return nil, false
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	e, ok := r.m[key]
+	if !ok {
+		return nil, false
+	}
+
+	r.order.Remove(e)
+	delete(r.m, key)
+
+	return e.Value.(*record).sources, true
+}
+
+// loadedSources returns a copy of the sources of the fields tracked for s.
+func loadedSources(s interface{}) map[string]string {
+	key, ok := loadKey(s)
+	if !ok {
+		return nil
+	}
+
+	loaded.Lock()
+	defer loaded.Unlock()
+
+	state, ok := loaded.m[key]
+	if !ok {
+		return nil
+	}
+
+	return copySources(state.fields)
+}
+
+// copySources returns a copy of the sources of the fields.
+func copySources(sources map[string]string) map[string]string {
+	c := make(map[string]string, len(sources))
+	for path, source := range sources {
+		c[path] = source
+	}
+
+	return c
+}
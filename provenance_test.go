@@ -0,0 +1,128 @@
+package multiconfig
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestSourcesOverride(t *testing.T) {
+	t.Setenv("SERVER_POSTGRES_PORT", "6432")
+
+	m := NewWithPath(testTOML)
+
+	s := new(Server)
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	sources := m.Sources(s)
+	want := map[string]string{
+		"Port":            "default tag",
+		"Postgres.Port":   "env SERVER_POSTGRES_PORT",
+		"Postgres.Hosts":  testTOML,
+		"Postgres.DBName": "default tag",
+	}
+
+	for path, source := range want {
+		if sources[path] != source {
+			t.Errorf("source of %s is %q, want %q", path, sources[path], source)
+		}
+	}
+}
+
+func TestSources(t *testing.T) {
+	type SourcesServer Server
+
+	t.Setenv("SOURCESSERVER_PORT", "7070")
+
+	m := NewWithPath(testTOML)
+	m.Loader = MultiLoader(m.Loader, &FlagLoader{Args: []string{"-name", "flag"}})
+
+	s := new(SourcesServer)
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	sources := m.Sources(s)
+	want := map[string]string{
+		"Name":            "flag -name",
+		"Port":            "env SOURCESSERVER_PORT",
+		"Postgres.Port":   testTOML,
+		"Postgres.DBName": "default tag",
+	}
+
+	for path, source := range want {
+		if sources[path] != source {
+			t.Errorf("source of %s is %q, want %q", path, sources[path], source)
+		}
+	}
+}
+
+func TestSourcesScopedToLoader(t *testing.T) {
+	m := NewWithPath(testTOML)
+
+	s := new(Server)
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if sources := NewWithPath(testTOML).Sources(s); sources != nil {
+		t.Errorf("the sources of a struct loaded by another loader should be nil, got %v", sources)
+	}
+
+	other := new(Server)
+	if err := MultiLoader(&TagLoader{}, &TOMLLoader{Path: testTOML}).Load(other); err != nil {
+		t.Fatal(err)
+	}
+
+	if sources := m.Sources(other); sources != nil {
+		t.Errorf("the sources of a struct not loaded by the DefaultLoader should be nil, got %v", sources)
+	}
+}
+
+func TestSourcesDoNotKeepStructsAlive(t *testing.T) {
+	m := newDefaultLoader(&TagLoader{})
+
+	collected := make(chan struct{})
+	func() {
+		s := new(Server)
+		if err := m.Load(s); err != nil {
+			t.Fatal(err)
+		}
+
+		runtime.SetFinalizer(s, func(*Server) { close(collected) })
+	}()
+
+	for i := 0; i < 10; i++ {
+		runtime.GC()
+
+		select {
+		case <-collected:
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	t.Error("a loaded struct should be garbage collected once unreachable")
+}
+
+func TestSourcesBounded(t *testing.T) {
+	m := newDefaultLoader(&TagLoader{})
+
+	structs := make([]*Server, maxRecords+1)
+	for i := range structs {
+		structs[i] = new(Server)
+		if err := m.Load(structs[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if sources := m.Sources(structs[0]); sources != nil {
+		t.Errorf("the sources of the least recently loaded struct should be forgotten, got %v", sources)
+	}
+
+	if sources := m.Sources(structs[maxRecords]); sources["Port"] != "default tag" {
+		t.Errorf("unexpected sources: %v", sources)
+	}
+}
@@ -0,0 +1,29 @@
+package multiconfig
+
+import "strings"
+
+// isRaw reports whether s is a pointer to a map[string]interface{}, into
+// which the key tree of the configuration is loaded as is, without a schema.
+// The loaders and validators which rely on the fields of a struct, such as
+// TagLoader, EnvironmentLoader or FlagLoader, skip it.
+func isRaw(s interface{}) bool {
+	_, ok := s.(*map[string]interface{})
+	return ok
+}
+
+// rawValue returns the value at the given dotted path of the key tree s.
+func rawValue(s *map[string]interface{}, path string) (interface{}, bool) {
+	var v interface{} = *s
+	for _, key := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		if v, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+
+	return v, true
+}
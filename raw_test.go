@@ -0,0 +1,55 @@
+package multiconfig
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLoadRaw(t *testing.T) {
+	overlay := strings.NewReader(`
+Postgres:
+  Port: 6432
+plugins:
+  cache:
+    size: 128
+`)
+
+	m := NewBuilder().
+		WithTags().
+		WithFile(testTOML).
+		WithReader(overlay, YAML).
+		WithEnv("").
+		WithFlags().
+		Build()
+	m.Tracer = TracerFunc(func(TraceEvent) {})
+
+	raw := map[string]interface{}{}
+	if err := m.LoadAndValidate(&raw); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]interface{}{
+		"Name":               "koding",
+		"Postgres.Port":      6432,
+		"Postgres.Enabled":   true,
+		"plugins.cache.size": 128,
+	}
+
+	for path, want := range tests {
+		got, ok := rawValue(&raw, path)
+		if !ok {
+			t.Errorf("%s: missing", path)
+			continue
+		}
+
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s: got %v, want %v", path, got, want)
+		}
+	}
+
+	// the sections of the files are merged key by key
+	if hosts, _ := rawValue(&raw, "Postgres.Hosts"); hosts == nil {
+		t.Error("the keys of the first file should be kept")
+	}
+}
@@ -0,0 +1,93 @@
+package multiconfig
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/fatih/structs"
+)
+
+// FieldChange describes a field whose value changed between two
+// configurations.
+type FieldChange struct {
+	// Path is the path of the field, i.e: "Postgres.Port"
+	Path string
+
+	// Old and New hold the previous and the new value of the field. Values
+	// of fields tagged with `secret:"true"` are redacted.
+	Old, New interface{}
+}
+
+// String returns a human readable form of the change.
+func (c FieldChange) String() string {
+	return fmt.Sprintf("%s: %v -> %v", c.Path, c.Old, c.New)
+}
+
+// Diff reports every field whose value differs between the configurations
+// current and candidate, pointers to structs of the same type, i.e: to log
+// what a reload changed in the onChange function of Watch.
+func Diff(current, candidate interface{}) ([]FieldChange, error) {
+	a, b := reflect.TypeOf(current), reflect.TypeOf(candidate)
+	if a == nil || a.Kind() != reflect.Ptr || a.Elem().Kind() != reflect.Struct || a != b {
+		return nil, fmt.Errorf("multiconfig: Diff needs two pointers to a struct of the same type, got %T and %T", current, candidate)
+	}
+
+	var changes []FieldChange
+	for _, d := range compareFields("", structs.Fields(current), structs.Fields(candidate)) {
+		changes = append(changes, FieldChange{Path: d.Field, Old: d.A, New: d.B})
+	}
+
+	return changes, nil
+}
+
+// Reload loads the configuration into a new struct of the type of s and
+// validates it. If it's valid, it replaces the content of s and the fields
+// which changed are returned. Otherwise s is left untouched and the error is
+// returned, so an invalid edit of the config doesn't reach a running
+// service.
+//
+// s is modified in place: the callers reading it from other goroutines must
+// synchronize with Reload, or use Watch, which hands each configuration in a
+// new struct.
+func (d *DefaultLoader) Reload(s interface{}) ([]FieldChange, error) {
+	conf, changes, err := d.candidate(s)
+	if err != nil {
+		return nil, err
+	}
+
+	reflect.ValueOf(s).Elem().Set(reflect.ValueOf(conf).Elem())
+	d.moveRecord(conf, s, "")
+
+	return changes, nil
+}
+
+// DryRunReload is like Reload but leaves s untouched: it only reports the
+// fields a reload would change, or why the configuration is invalid.
+func (d *DefaultLoader) DryRunReload(s interface{}) ([]FieldChange, error) {
+	conf, changes, err := d.candidate(s)
+	d.forget(conf)
+	return changes, err
+}
+
+// candidate loads and validates the configuration into a new struct of the
+// type of s, and returns it along with the fields which differ from s.
+func (d *DefaultLoader) candidate(s interface{}) (interface{}, []FieldChange, error) {
+	t := reflect.TypeOf(s)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct || reflect.ValueOf(s).IsNil() {
+		return nil, nil, fmt.Errorf("multiconfig: cannot reload %T, a non-nil pointer to a struct is required", s)
+	}
+
+	conf := reflect.New(t.Elem()).Interface()
+	if err := d.reload(context.Background(), conf); err != nil {
+		d.forget(conf)
+		return nil, nil, err
+	}
+
+	changes, err := Diff(s, conf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return conf, changes, nil
+}
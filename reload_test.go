@@ -0,0 +1,125 @@
+package multiconfig
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	type Database struct {
+		Port     int
+		Password string `secret:"true"`
+	}
+
+	type Config struct {
+		Name     string
+		Database Database
+		Hosts    []string
+	}
+
+	a := &Config{Name: "koding", Database: Database{Port: 5432, Password: "a"}, Hosts: []string{"db1"}}
+	b := &Config{Name: "koding", Database: Database{Port: 5433, Password: "b"}, Hosts: []string{"db1", "db2"}}
+
+	changes, err := Diff(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []FieldChange{
+		{Path: "Database.Port", Old: 5432, New: 5433},
+		{Path: "Database.Password", Old: redacted, New: redacted},
+		{Path: "Hosts", Old: []string{"db1"}, New: []string{"db1", "db2"}},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes are wrong: %v, want: %v", changes, want)
+	}
+
+	if _, err := Diff(a, &Database{}); err == nil {
+		t.Error("diffing structs of different types should fail")
+	}
+}
+
+func TestDiffTime(t *testing.T) {
+	type Schedule struct {
+		At time.Time
+	}
+
+	t0 := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	t1 := t0.Add(time.Hour)
+
+	changes, err := Diff(&Schedule{At: t0}, &Schedule{At: t1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []FieldChange{{Path: "At", Old: t0, New: t1}}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes are wrong: %v, want: %v", changes, want)
+	}
+
+	got, err := CompareSources(&Schedule{},
+		&JSONLoader{Reader: strings.NewReader(`{"At": "2020-01-02T03:04:05Z"}`)},
+		&JSONLoader{Reader: strings.NewReader(`{"At": "2020-01-02T04:04:05Z"}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 1 || got[0].Field != "At" {
+		t.Errorf("the time fields should differ: %v", got)
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	write := func(content string) {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("Name = \"koding\"\n[Postgres]\nPort = 5432\nHosts = [\"localhost\"]\n")
+
+	m := NewWithPath(path)
+
+	s := new(Server)
+	m.MustLoad(s)
+
+	write("Name = \"koding\"\n[Postgres]\nPort = 6432\nHosts = [\"localhost\"]\n")
+
+	changes, err := m.DryRunReload(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []FieldChange{{Path: "Postgres.Port", Old: 5432, New: 6432}}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes are wrong: %v, want: %v", changes, want)
+	}
+
+	if s.Postgres.Port != 5432 {
+		t.Errorf("a dry run should not modify the config: %d", s.Postgres.Port)
+	}
+
+	changes, err = m.Reload(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(changes, want) || s.Postgres.Port != 6432 {
+		t.Errorf("reloaded config is wrong: %v, %+v", changes, s)
+	}
+
+	// a config missing its required name is rejected
+	write("Name = \"\"\n[Postgres]\nPort = 7432\nHosts = [\"localhost\"]\n")
+
+	if _, err := m.Reload(s); err == nil {
+		t.Error("reloading an invalid config should fail")
+	}
+
+	if s.Name != "koding" || s.Postgres.Port != 6432 {
+		t.Errorf("an invalid config should not be swapped in: %+v", s)
+	}
+}
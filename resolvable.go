@@ -0,0 +1,121 @@
+package multiconfig
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/fatih/structs"
+)
+
+// ResolvableValidator validates that the host names held by the fields
+// tagged with `validate:"resolvable"` are either the host name of the machine
+// or resolvable via DNS:
+//
+//	AdvertiseHost string `validate:"resolvable"`
+//
+// The value may contain a port, i.e: "example.com:8080". Because it performs
+// network lookups during validation, it is not part of the validators of
+// DefaultLoader and has to be added explicitly.
+type ResolvableValidator struct {
+	// TagName holds the validator tag name. The default is "validate"
+	TagName string
+
+	// Resolver is used to look up the host names. If nil,
+	// net.DefaultResolver is used.
+	Resolver *net.Resolver
+
+	// Timeout bounds each lookup. The default is 5 seconds.
+	Timeout time.Duration
+
+	// LookupHost, if set, is called instead of the Resolver. It is useful to
+	// disable network checks in tests.
+	LookupHost func(ctx context.Context, host string) ([]string, error)
+}
+
+// Validate validates that the resolvable fields of the given struct hold a
+// resolvable host name. Empty values and nil pointers are ignored, combine
+// it with the RequiredValidator to reject them.
+func (r *ResolvableValidator) Validate(s interface{}) error {
+	// the defaults are set on a copy, the validator being shared
+	v := *r
+	if v.TagName == "" {
+		v.TagName = "validate"
+	}
+
+	return validateFields(s, v.processField)
+}
+
+func (r *ResolvableValidator) processField(name string, field *structs.Field, v reflect.Value) error {
+	if v.Kind() != reflect.String || !hasRule(field.Tag(r.TagName), "resolvable") {
+		return nil
+	}
+
+	host := v.String()
+	if host == "" {
+		return nil
+	}
+
+	if err := r.resolve(host); err != nil {
+		return fieldErrorf(name, host, "is not a resolvable host name: %s", err)
+	}
+
+	return nil
+}
+
+// resolve looks up the given host name, the host name of the machine is
+// accepted without any lookup.
+func (r *ResolvableValidator) resolve(host string) error {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	if hostname, err := os.Hostname(); err == nil && strings.EqualFold(host, hostname) {
+		return nil
+	}
+
+	timeout := r.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	lookup := r.LookupHost
+	if lookup == nil {
+		resolver := r.Resolver
+		if resolver == nil {
+			resolver = net.DefaultResolver
+		}
+
+		lookup = resolver.LookupHost
+	}
+
+	addrs, err := lookup(ctx, host)
+	if err != nil {
+		return err
+	}
+
+	if len(addrs) == 0 {
+		return fmt.Errorf("no addresses found for %s", host)
+	}
+
+	return nil
+}
+
+// hasRule reports whether the comma separated list of rules of a tag
+// contains the given rule.
+func hasRule(tag, rule string) bool {
+	for _, r := range strings.Split(tag, ",") {
+		if strings.TrimSpace(r) == rule {
+			return true
+		}
+	}
+
+	return false
+}
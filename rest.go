@@ -0,0 +1,181 @@
+package multiconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// restTag is the value of the "config" tag marking the field which captures
+// the keys of a source that don't match any other field of the struct:
+//
+//	Extra map[string]interface{} `config:",rest"`
+const restTag = ",rest"
+
+var restType = reflect.TypeOf(map[string]interface{}{})
+
+// decodeRaw decodes data of the given format into a generic key tree. YAML,
+// whose files may hold several documents, is decoded by decodeDocuments.
+func decodeRaw(format string, data []byte) (map[string]interface{}, error) {
+	raw := map[string]interface{}{}
+
+	switch format {
+	case "toml":
+		if _, err := toml.Decode(string(data), &raw); err != nil {
+			return nil, err
+		}
+	case "json":
+		// keep numbers as json.Number to not lose the precision of integers
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+	case "hcl":
+		return decodeHCL(data)
+	case "ini":
+		return decodeINI(data)
+	default:
+		return nil, fmt.Errorf("multiconfig: unsupported format %q", format)
+	}
+
+	return raw, nil
+}
+
+// decodeDocuments decodes data of the given format into the generic trees of
+// its documents. Only YAML holds several documents, separated by "---", and a
+// YAML document may be a sequence rather than a key tree. The anchors and
+// merge keys of YAML are resolved.
+func decodeDocuments(format string, data []byte) ([]interface{}, error) {
+	if format != "yaml" {
+		raw, err := decodeRaw(format, data)
+		if err != nil {
+			return nil, err
+		}
+
+		return []interface{}{raw}, nil
+	}
+
+	var docs []interface{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for i := 1; ; i++ {
+		var v interface{}
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch v.(type) {
+		case nil:
+			// an empty document
+		case map[interface{}]interface{}, []interface{}:
+			docs = append(docs, normalizeYAML(v))
+		default:
+			return nil, fmt.Errorf("document %d is neither a mapping nor a sequence", i)
+		}
+	}
+
+	if len(docs) == 0 {
+		return []interface{}{map[string]interface{}{}}, nil
+	}
+
+	return docs, nil
+}
+
+// normalizeYAML converts the map[interface{}]interface{} values produced by
+// the yaml decoder into map[string]interface{} recursively. The values are
+// copied, the ones reached through several aliases of an anchor being shared
+// by the decoder.
+func normalizeYAML(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for key, val := range t {
+			m[fmt.Sprint(key)] = normalizeYAML(val)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(t))
+		for i, val := range t {
+			list[i] = normalizeYAML(val)
+		}
+		return list
+	}
+
+	return v
+}
+
+// keyFields returns the index of the fields of the struct type t, keyed by
+// the lower cased name a source uses for them: the name given by the tag of
+// the format, or by the first of nameTags naming the field, or the field
+// name. Fields of embedded structs, and of structs tagged as inline or with
+// `structs:",flatten"`, are promoted.
+func keyFields(t reflect.Type, tagName string, nameTags ...string) map[string][]int {
+	fields := map[string][]int{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" || field.Tag.Get("config") == restTag {
+			continue
+		}
+
+		tag := strings.Split(field.Tag.Get(tagName), ",")
+		if tag[0] == "-" {
+			continue
+		}
+
+		typ := field.Type
+		inline := len(tag) > 1 && tag[1] == "inline"
+		if isFlattened(typ, field.Tag.Get("structs")) {
+			// a nil pointer to a flattened struct is allocated once set
+			inline = true
+			if typ.Kind() == reflect.Ptr {
+				typ = typ.Elem()
+			}
+		}
+
+		if (field.Anonymous || inline) && typ.Kind() == reflect.Struct {
+			for name, index := range keyFields(typ, tagName, nameTags...) {
+				if _, ok := fields[name]; !ok {
+					fields[name] = append([]int{i}, index...)
+				}
+			}
+			continue
+		}
+
+		fields[strings.ToLower(keyName(field, tagName, nameTags))] = []int{i}
+	}
+
+	return fields
+}
+
+// keyName returns the name a source uses for the field, see keyFields.
+func keyName(field reflect.StructField, tagName string, nameTags []string) string {
+	for _, tag := range append([]string{tagName}, nameTags...) {
+		if name := nameFromTag(field.Tag.Get(tag)); name != "" {
+			return name
+		}
+	}
+
+	return field.Name
+}
+
+// nameFromTag returns the name given by the value of a tag such as
+// `json:"db_name,omitempty"`, or an empty string if it doesn't name the field.
+func nameFromTag(tag string) string {
+	name := strings.Split(tag, ",")[0]
+	if name == "-" {
+		return ""
+	}
+
+	return name
+}
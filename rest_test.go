@@ -0,0 +1,90 @@
+package multiconfig
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRestField(t *testing.T) {
+	type Database struct {
+		Host  string
+		Extra map[string]interface{} `config:",rest"`
+	}
+
+	type RestServer struct {
+		Name     string
+		Database Database
+		Extra    map[string]interface{} `config:",rest"`
+	}
+
+	tests := []struct {
+		loader Loader
+		data   string
+	}{
+		{
+			loader: &TOMLLoader{},
+			data:   "Name = \"koding\"\nTimeout = 10\n\n[Database]\nHost = \"localhost\"\nPool = 4\n\n[Cache]\nSize = 128\n",
+		},
+		{
+			loader: &JSONLoader{},
+			data:   `{"name": "koding", "timeout": 10, "database": {"host": "localhost", "pool": 4}, "cache": {"size": 128}}`,
+		},
+		{
+			loader: &YAMLLoader{},
+			data:   "name: koding\ntimeout: 10\ndatabase:\n  host: localhost\n  pool: 4\ncache:\n  size: 128\n",
+		},
+	}
+
+	for _, test := range tests {
+		switch l := test.loader.(type) {
+		case *TOMLLoader:
+			l.Reader = strings.NewReader(test.data)
+		case *JSONLoader:
+			l.Reader = strings.NewReader(test.data)
+		case *YAMLLoader:
+			l.Reader = strings.NewReader(test.data)
+		}
+
+		s := &RestServer{}
+		if err := test.loader.Load(s); err != nil {
+			t.Fatalf("%T: %s", test.loader, err)
+		}
+
+		if s.Name != "koding" || s.Database.Host != "localhost" {
+			t.Errorf("%T: known fields are not loaded: %+v", test.loader, s)
+		}
+
+		if len(s.Extra) != 2 || s.Extra["cache"] == nil && s.Extra["Cache"] == nil {
+			t.Errorf("%T: unexpected rest: %v", test.loader, s.Extra)
+		}
+
+		if len(s.Database.Extra) != 1 {
+			t.Errorf("%T: unexpected nested rest: %v", test.loader, s.Database.Extra)
+		}
+	}
+}
+
+func TestRestFieldValues(t *testing.T) {
+	type RestServer struct {
+		Name  string
+		Extra map[string]interface{} `config:",rest"`
+	}
+
+	s := &RestServer{}
+	l := &YAMLLoader{Reader: strings.NewReader("name: koding\nplugins:\n  auth:\n    enabled: true\n")}
+	if err := l.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"plugins": map[string]interface{}{
+			"auth": map[string]interface{}{"enabled": true},
+		},
+	}
+
+	if diff := cmp.Diff(want, s.Extra); diff != "" {
+		t.Errorf("diff = %s", diff)
+	}
+}
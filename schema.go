@@ -0,0 +1,335 @@
+package multiconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// schemaDraft is the JSON Schema dialect of the schemas generated by Schema.
+const schemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema returns the JSON Schema of the config struct s, to validate config
+// files in CI or to power the autocompletion of editors. Properties are named
+// like the keys of a JSON config file, and are described by:
+//
+//   - their type, the values parsed from text such as durations, times and
+//     the types with an unmarshaler or a decoder being strings, and sizes
+//     being integers or strings
+//   - the value of their default tag
+//   - the required tag
+//   - the oneof tag, as an enum
+//   - the min, max, len and pattern tags of the constraint validators, the
+//     bounds of sizes being counts of bytes while the values written as
+//     strings have none
+//   - the desc and deprecated tags
+//
+// The tags are the default ones of the validators of the DefaultLoader.
+func Schema(s interface{}) ([]byte, error) {
+	t := reflect.TypeOf(s)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("multiconfig: Schema needs a struct, got %T", s)
+	}
+
+	schema, err := structSchema(t, "")
+	if err != nil {
+		return nil, err
+	}
+
+	schema["$schema"] = schemaDraft
+	schema["title"] = t.Name()
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// structSchema returns the schema of the struct type t, found at path.
+func structSchema(t reflect.Type, path string) (map[string]interface{}, error) {
+	properties := map[string]interface{}{}
+	required := []string{}
+
+	if err := addProperties(t, path, properties, &required); err != nil {
+		return nil, err
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema, nil
+}
+
+// addProperties adds the schema of each field of the struct type t to
+// properties, and the names of the required ones to required. The fields of
+// embedded structs, and of the structs tagged with `structs:",flatten"`, are
+// promoted.
+func addProperties(t reflect.Type, path string, properties map[string]interface{}, required *[]string) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" || field.Tag.Get("config") == restTag {
+			continue
+		}
+
+		tag := strings.Split(field.Tag.Get("json"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+
+		inline := len(tag) > 1 && tag[1] == "inline"
+		if isFlattened(field.Type, field.Tag.Get("structs")) {
+			elem := field.Type
+			if elem.Kind() == reflect.Ptr {
+				elem = elem.Elem()
+			}
+
+			if err := addProperties(elem, joinPath(path, field.Name), properties, required); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if (field.Anonymous || inline) && field.Type.Kind() == reflect.Struct && !isUnmarshalerType(field.Type) {
+			if err := addProperties(field.Type, path, properties, required); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := field.Name
+		if tag[0] != "" {
+			name = tag[0]
+		}
+
+		schema, err := fieldSchema(field, joinPath(path, field.Name))
+		if err != nil {
+			return err
+		}
+
+		properties[name] = schema
+		if field.Tag.Get("required") == "true" {
+			*required = append(*required, name)
+		}
+	}
+
+	return nil
+}
+
+// fieldSchema returns the schema of the field found at path, along with the
+// constraints of its tags.
+func fieldSchema(field reflect.StructField, path string) (map[string]interface{}, error) {
+	schema, err := typeSchema(field.Type, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if desc := field.Tag.Get(descTag); desc != "" {
+		schema["description"] = desc
+	}
+
+	if field.Tag.Get(deprecatedTag) != "" {
+		schema["deprecated"] = true
+	}
+
+	if def := field.Tag.Get("default"); def != "" {
+		v, err := schemaValue(field.Type, def, path)
+		if err != nil {
+			return nil, err
+		}
+
+		// the default of a secret is checked but not published
+		if field.Tag.Get("secret") == "true" {
+			v = redacted
+		}
+		schema["default"] = v
+	}
+
+	if oneof := field.Tag.Get("oneof"); oneof != "" {
+		elem := field.Type
+		if elem.Kind() == reflect.Slice {
+			elem = elem.Elem()
+		}
+
+		var enum []interface{}
+		for _, value := range strings.Split(oneof, ",") {
+			v, err := schemaValue(elem, strings.TrimSpace(value), path)
+			if err != nil {
+				return nil, err
+			}
+			enum = append(enum, v)
+		}
+
+		if items, ok := schema["items"].(map[string]interface{}); ok && field.Type.Kind() == reflect.Slice {
+			items["enum"] = enum
+		} else {
+			schema["enum"] = enum
+		}
+	}
+
+	for _, bound := range []struct{ tag, suffix string }{{"min", "min"}, {"max", "max"}, {"len", ""}} {
+		tag := field.Tag.Get(bound.tag)
+		if tag == "" {
+			continue
+		}
+
+		if err := addBound(schema, field.Type, bound.suffix, tag, path); err != nil {
+			return nil, fieldErrorf(path, tag, "has an invalid %s tag: %s", bound.tag, err)
+		}
+	}
+
+	if pattern := field.Tag.Get("pattern"); pattern != "" {
+		re := "^(?:" + pattern + ")$"
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			items["pattern"] = re
+		} else {
+			schema["pattern"] = re
+		}
+	}
+
+	return schema, nil
+}
+
+// addBound adds the bound given by tag of a value of type t to the schema:
+// its minimum or maximum for numbers, or the bounds of its length for
+// strings, arrays and objects. suffix is "min", "max" or, for an exact
+// length, empty.
+func addBound(schema map[string]interface{}, t reflect.Type, suffix, tag, path string) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var noun string
+	switch t.Kind() {
+	case reflect.String:
+		noun = "Length"
+	case reflect.Slice, reflect.Array:
+		noun = "Items"
+	case reflect.Map:
+		noun = "Properties"
+	}
+
+	if noun != "" {
+		n, err := strconv.Atoi(tag)
+		if err != nil {
+			return err
+		}
+
+		switch suffix {
+		case "":
+			schema["min"+noun], schema["max"+noun] = n, n
+		default:
+			schema[suffix+noun] = n
+		}
+
+		return nil
+	}
+
+	if suffix == "" {
+		return nil
+	}
+
+	var v interface{}
+	switch {
+	case t == bytesType:
+		// sizes are bound by their count of bytes, their text form being
+		// a string
+		b, err := ParseBytes(tag)
+		if err != nil {
+			return fieldErr(path, tag, err)
+		}
+
+		v = int64(b)
+	case t == durationType, isUnmarshalerType(t):
+		// the values written as strings have no numeric bound
+		return nil
+	default:
+		var err error
+		if v, err = schemaValue(t, tag, path); err != nil {
+			return err
+		}
+	}
+
+	if suffix == "min" {
+		schema["minimum"] = v
+	} else {
+		schema["maximum"] = v
+	}
+
+	return nil
+}
+
+// typeSchema returns the schema of the values of type t.
+func typeSchema(t reflect.Type, path string) (map[string]interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == bytesType:
+		// a size is a count of bytes or a string such as "512MB"
+		return map[string]interface{}{"type": []string{"integer", "string"}}, nil
+	case t == durationType:
+		return map[string]interface{}{"type": "string"}, nil
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}, nil
+	case isUnmarshalerType(t):
+		return map[string]interface{}{"type": "string"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}, nil
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string"}, nil
+		}
+
+		items, err := typeSchema(t.Elem(), path)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{"type": "array", "items": items}, nil
+	case reflect.Map:
+		values, err := typeSchema(t.Elem(), path)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		return structSchema(t, path)
+	case reflect.Interface:
+		return map[string]interface{}{}, nil
+	}
+
+	return nil, &unsupportedError{name: path, kind: t.Kind()}
+}
+
+// schemaValue converts the string s, such as the value of a default tag, to
+// a value of type t and returns it as a JSON value.
+func schemaValue(t reflect.Type, s, path string) (interface{}, error) {
+	v := reflect.New(t).Elem()
+	if err := setString(v, s, "", path); err != nil {
+		return nil, err
+	}
+
+	return plainValue(v), nil
+}
@@ -0,0 +1,93 @@
+package multiconfig
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSchema(t *testing.T) {
+	type TLS struct {
+		Cert string `json:"cert" required:"true"`
+	}
+
+	type Service struct {
+		TLS
+		Name     string        `required:"true" desc:"Name of the service" pattern:"[a-z]+"`
+		Port     uint16        `default:"8080" min:"1" max:"65535"`
+		Scheme   string        `default:"http" oneof:"http,https"`
+		Timeout  time.Duration `default:"5s" min:"1s"`
+		Size     Bytes         `default:"512MB" min:"1KB" max:"2GB"`
+		Hosts    []string      `min:"1" max:"3"`
+		Key      string        `len:"32"`
+		Token    string        `default:"dev-token" secret:"true"`
+		Gateway  net.IP
+		Labels   map[string]int
+		Database *struct {
+			DSN  string `deprecated:"use URL instead"`
+			Pool int    `default:"10"`
+		}
+		Ignored string `json:"-"`
+	}
+
+	data, err := Schema(&Service{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	var want map[string]interface{}
+	err = json.Unmarshal([]byte(`{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title": "Service",
+		"type": "object",
+		"required": ["cert", "Name"],
+		"properties": {
+			"cert": {"type": "string"},
+			"Name": {"type": "string", "description": "Name of the service", "pattern": "^(?:[a-z]+)$"},
+			"Port": {"type": "integer", "minimum": 1, "maximum": 65535, "default": 8080},
+			"Scheme": {"type": "string", "default": "http", "enum": ["http", "https"]},
+			"Timeout": {"type": "string", "default": "5s"},
+			"Size": {"type": ["integer", "string"], "minimum": 1000, "maximum": 2000000000, "default": "512MB"},
+			"Hosts": {"type": "array", "items": {"type": "string"}, "minItems": 1, "maxItems": 3},
+			"Key": {"type": "string", "minLength": 32, "maxLength": 32},
+			"Token": {"type": "string", "default": "****"},
+			"Gateway": {"type": "string"},
+			"Labels": {"type": "object", "additionalProperties": {"type": "integer"}},
+			"Database": {
+				"type": "object",
+				"properties": {
+					"DSN": {"type": "string", "deprecated": true},
+					"Pool": {"type": "integer", "default": 10}
+				}
+			}
+		}
+	}`), &want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("schema is wrong:\n%s", data)
+	}
+}
+
+func TestSchemaErrors(t *testing.T) {
+	if _, err := Schema("config"); err == nil {
+		t.Error("the schema of a string should fail")
+	}
+
+	type Invalid struct {
+		Port int `default:"http"`
+	}
+
+	if _, err := Schema(&Invalid{}); err == nil {
+		t.Error("an invalid default tag should fail")
+	}
+}
@@ -0,0 +1,75 @@
+package multiconfig
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// SecretsManagerLoader satisfies the loader interface. It loads the
+// configuration from a secret of AWS Secrets Manager holding a JSON object,
+// decoded like a JSON file. Its requests are signed the same way the ones of
+// the SSMLoader are.
+type SecretsManagerLoader struct {
+	// SecretID is the name or the ARN of the secret.
+	SecretID string
+
+	// VersionStage is the staging label of the version of the secret. The
+	// default is AWSCURRENT.
+	VersionStage string
+
+	// Region is the AWS region. If empty, AWS_REGION or AWS_DEFAULT_REGION
+	// is used.
+	Region string
+
+	// Endpoint is the address of the service. The default is the public
+	// endpoint of the region.
+	Endpoint string
+
+	// Client is used to perform the requests. If nil, http.DefaultClient is
+	// used.
+	Client *http.Client
+
+	// Strict rejects the keys which don't match any field instead of
+	// ignoring them.
+	Strict bool
+}
+
+// Load loads the source into the config defined by struct s
+func (l *SecretsManagerLoader) Load(s interface{}) error {
+	return l.LoadContext(context.Background(), s)
+}
+
+// LoadContext is like Load but the requests are bound to the given context,
+// so they honor its cancellation and deadline.
+func (l *SecretsManagerLoader) LoadContext(ctx context.Context, s interface{}) error {
+	if l.SecretID == "" {
+		return ErrSourceNotSet
+	}
+
+	client, err := newAWSClient("secretsmanager", "secretsmanager", l.Region, l.Endpoint, l.Client)
+	if err != nil {
+		return err
+	}
+
+	in := map[string]string{"SecretId": l.SecretID}
+	if l.VersionStage != "" {
+		in["VersionStage"] = l.VersionStage
+	}
+
+	var resp struct {
+		SecretString *string
+	}
+	if err := client.call(ctx, "GetSecretValue", in, &resp); err != nil {
+		return err
+	}
+
+	if resp.SecretString == nil {
+		return fmt.Errorf("multiconfig: secretsmanager: secret %s has no string value", l.SecretID)
+	}
+
+	return decodeSource("json", []byte(*resp.SecretString), s, decodeOptions{
+		source: "secretsmanager " + l.SecretID,
+		strict: l.Strict,
+	})
+}
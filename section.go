@@ -0,0 +1,70 @@
+package multiconfig
+
+import (
+	"fmt"
+	"go/token"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// LoadSection loads the section at the given dotted path of the
+// configuration, i.e: "postgres" or "services.db", into the struct s, as if
+// s were a field of the whole config. A library can thus load its own
+// settings from the config shared with the application, without knowing the
+// schema of the rest of it:
+//
+//	pg := new(Postgres)
+//	err := m.LoadSection(pg, "postgres")
+//
+// Sections are matched case insensitively by the files, while environment
+// variables and flags are named after the path, i.e: POSTGRES_PORT, or
+// APP_POSTGRES_PORT with an EnvironmentLoader Prefix of "APP", and
+// -postgres-port. The fields set by the sources are recorded for s, so
+// Validate, Sources and LoadedFields of d apply to it.
+func (d *DefaultLoader) LoadSection(s interface{}, path string) error {
+	v := reflect.ValueOf(s)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("multiconfig: cannot load into %T, a non-nil pointer to a struct is required", s)
+	}
+
+	if path == "" {
+		return d.Load(s)
+	}
+
+	names := strings.Split(path, ".")
+	typ := v.Elem().Type()
+	for i := len(names) - 1; i >= 0; i-- {
+		name := exportedName(names[i])
+		if !token.IsIdentifier(name) {
+			return fmt.Errorf("multiconfig: invalid section '%s'", path)
+		}
+
+		names[i] = name
+		typ = reflect.StructOf([]reflect.StructField{{Name: name, Type: typ}})
+	}
+
+	// the section is loaded within a struct holding it at path
+	parent := reflect.New(typ)
+	section := parent.Elem()
+	for range names {
+		section = section.Field(0)
+	}
+	section.Set(v.Elem())
+
+	err := d.Load(parent.Interface())
+	d.moveRecord(parent.Interface(), s, strings.Join(names, "."))
+	if err != nil {
+		return err
+	}
+
+	v.Elem().Set(section)
+	return nil
+}
+
+// exportedName returns name with its first letter upper cased.
+func exportedName(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(r)) + name[size:]
+}
@@ -0,0 +1,63 @@
+package multiconfig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadSection(t *testing.T) {
+	t.Setenv("POSTGRES_DBNAME", "envdb")
+
+	m := NewWithPath(testTOML)
+
+	p := new(Postgres)
+	if err := m.LoadSection(p, "postgres"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := getDefaultServer().Postgres
+	want.DBName = "envdb"
+	if p.Port != want.Port || p.DBName != want.DBName || len(p.Hosts) != 3 || p.AvailabilityRatio != want.AvailabilityRatio {
+		t.Errorf("unexpected section: %+v, want %+v", p, want)
+	}
+
+	if err := m.Validate(p); err != nil {
+		t.Errorf("the section should be valid: %s", err)
+	}
+
+	if fields := strings.Join(m.LoadedFields(p), ","); fields != "AvailabilityRatio,DBName,Enabled,Hosts,Port" {
+		t.Errorf("unexpected loaded fields: %s", fields)
+	}
+}
+
+func TestLoadSectionNested(t *testing.T) {
+	type Database struct {
+		Host string `default:"localhost"`
+		Port int    `default:"5432"`
+	}
+
+	m := newDefaultLoader(
+		&TagLoader{},
+		&TOMLLoader{Reader: strings.NewReader("[Services.DB]\nport = 6432\n")},
+		&EnvironmentLoader{Prefix: "APP", getenv: testEnvironment{"APP_SERVICES_DB_HOST": "db"}.get},
+	)
+
+	db := new(Database)
+	if err := m.LoadSection(db, "services.db"); err != nil {
+		t.Fatal(err)
+	}
+
+	if db.Host != "db" || db.Port != 6432 {
+		t.Errorf("unexpected section: %+v", db)
+	}
+
+	for _, path := range []string{"", "services..db", "services.1db"} {
+		if err := m.LoadSection(db, path); path != "" && err == nil {
+			t.Errorf("%q: an invalid section should fail", path)
+		}
+	}
+
+	if err := m.LoadSection(*db, "services.db"); err == nil {
+		t.Error("loading into a non-pointer should fail")
+	}
+}
@@ -0,0 +1,120 @@
+package multiconfig
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// SOPSLoader satisfies the loader interface. It loads the configuration from
+// the given SOPS or age encrypted file, or Reader, which is decrypted in
+// memory, so the plaintext is never written to disk.
+//
+// By default SOPS files are decrypted by the sops command and age files by
+// the age command, with the identity file named by the SOPS_AGE_KEY_FILE
+// environment variable. Both must be in the PATH.
+type SOPSLoader struct {
+	Path   string
+	Reader io.Reader
+
+	// FS, if set, is the file system Path is read from instead of the
+	// operating system's one, i.e: an embed.FS.
+	FS fs.FS
+
+	// Format is the format of the decrypted configuration: "toml", "json",
+	// "yaml", "hcl" or "ini". By default it's chosen by the extension of the
+	// Path, i.e: "config.enc.yaml" is YAML.
+	Format string
+
+	// Decrypt, if set, decrypts the data instead of the sops or age command.
+	// format is the format of the configuration.
+	Decrypt func(data []byte, format string) ([]byte, error)
+}
+
+// Load loads the source into the config defined by struct s.
+// Defaults to using the Reader if provided, otherwise tries to read from the
+// file
+func (l *SOPSLoader) Load(s interface{}) error {
+	format := l.Format
+	if format == "" {
+		format = formatOf(l.Path)
+	}
+
+	if format == "" {
+		return fmt.Errorf("multiconfig: %s: unable to determine the config format", sourceName(l.Path, "sops"))
+	}
+
+	data, err := readSource(l.FS, l.Path, l.Reader)
+	if err != nil {
+		return err
+	}
+
+	decrypt := l.Decrypt
+	if decrypt == nil {
+		decrypt = decryptCommand
+	}
+
+	plain, err := decrypt(data, format)
+	if err != nil {
+		return fmt.Errorf("multiconfig: %s: decrypting: %s", sourceName(l.Path, format), err)
+	}
+
+	return decodeSource(format, plain, s, decodeOptions{
+		source: sourceName(l.Path, format),
+	})
+}
+
+// ageHeaders are the headers of the binary and the armored age files.
+var ageHeaders = []string{"age-encryption.org/v1", "-----BEGIN AGE ENCRYPTED FILE-----"}
+
+// decryptCommand decrypts data with the age command if it's an age file, or
+// with the sops command otherwise.
+func decryptCommand(data []byte, format string) ([]byte, error) {
+	for _, header := range ageHeaders {
+		if bytes.HasPrefix(data, []byte(header)) {
+			args := []string{"--decrypt"}
+			if key := os.Getenv("SOPS_AGE_KEY_FILE"); key != "" {
+				args = append(args, "--identity", key)
+			}
+
+			return runDecrypt(data, "age", args...)
+		}
+	}
+
+	// sops stores the formats it doesn't support, such as TOML, as binary
+	// data
+	typ := format
+	switch format {
+	case "json", "yaml", "ini":
+	case "env":
+		typ = "dotenv"
+	default:
+		typ = "binary"
+	}
+
+	return runDecrypt(data, "sops", "--decrypt", "--input-type", typ, "--output-type", typ, "/dev/stdin")
+}
+
+// runDecrypt runs the given command with data as its standard input and
+// returns its output.
+func runDecrypt(data []byte, name string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %s", err, msg)
+		}
+		return nil, err
+	}
+
+	return stdout.Bytes(), nil
+}
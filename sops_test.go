@@ -0,0 +1,74 @@
+package multiconfig
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSOPSLoader(t *testing.T) {
+	l := &SOPSLoader{
+		Reader: strings.NewReader("encrypted"),
+		Format: "yaml",
+		Decrypt: func(data []byte, format string) ([]byte, error) {
+			if string(data) != "encrypted" || format != "yaml" {
+				t.Errorf("decrypting %q as %s", data, format)
+			}
+			return ioutil.ReadFile(testYAML)
+		},
+	}
+
+	s := &Server{}
+	if err := MultiLoader(&TagLoader{}, l).Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	testStruct(t, s, getDefaultServer())
+
+	l = &SOPSLoader{
+		Path: testYAML,
+		Decrypt: func(data []byte, format string) ([]byte, error) {
+			return nil, errors.New("no key")
+		},
+	}
+
+	want := "multiconfig: testdata/config.yaml: decrypting: no key"
+	if err := l.Load(&Server{}); err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+}
+
+func TestSOPSLoaderCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake sops command is a shell script")
+	}
+
+	dir, err := ioutil.TempDir("", "multiconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the fake sops command checks its arguments and outputs its input
+	script := "#!/bin/sh\n" +
+		"[ \"$*\" = \"--decrypt --input-type binary --output-type binary /dev/stdin\" ] || exit 1\n" +
+		"cat\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "sops"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+	defer os.Setenv("PATH", path)
+
+	s := &Server{}
+	if err := MultiLoader(&TagLoader{}, &SOPSLoader{Path: testTOML}).Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	testStruct(t, s, getDefaultServer())
+}
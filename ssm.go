@@ -0,0 +1,93 @@
+package multiconfig
+
+import (
+	"context"
+	"net/http"
+)
+
+// SSMLoader satisfies the loader interface. It loads the configuration from
+// the parameters of the AWS Systems Manager Parameter Store under Path. The
+// rest of each parameter name is split on "/" into nested fields, so under
+// the path "/myapp/prod/" the parameter "/myapp/prod/postgres/port" sets
+// Postgres.Port. SecureString parameters are decrypted, StringList ones are
+// comma separated lists, and values are converted the same way environment
+// variables are.
+//
+// The requests are signed with the credentials given by the standard
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment
+// variables, or else by the role of AWS_ROLE_ARN assumed with the token of
+// AWS_WEB_IDENTITY_TOKEN_FILE, or else by the profile named by AWS_PROFILE
+// in the shared credentials file, or else by the ECS task role or the EC2
+// instance role.
+type SSMLoader struct {
+	// Path is the path of the parameters, i.e: "/myapp/prod/".
+	Path string
+
+	// Region is the AWS region. If empty, AWS_REGION or AWS_DEFAULT_REGION
+	// is used.
+	Region string
+
+	// Endpoint is the address of the service. The default is the public
+	// endpoint of the region.
+	Endpoint string
+
+	// Client is used to perform the requests. If nil, http.DefaultClient is
+	// used.
+	Client *http.Client
+
+	// Strict rejects the parameters which don't match any field instead of
+	// ignoring them.
+	Strict bool
+}
+
+// Load loads the source into the config defined by struct s
+func (l *SSMLoader) Load(s interface{}) error {
+	return l.LoadContext(context.Background(), s)
+}
+
+// LoadContext is like Load but the requests are bound to the given context,
+// so they honor its cancellation and deadline.
+func (l *SSMLoader) LoadContext(ctx context.Context, s interface{}) error {
+	if l.Path == "" {
+		return ErrSourceNotSet
+	}
+
+	client, err := newAWSClient("ssm", "AmazonSSM", l.Region, l.Endpoint, l.Client)
+	if err != nil {
+		return err
+	}
+
+	pairs := map[string]string{}
+	for token := ""; ; {
+		var resp struct {
+			Parameters []struct {
+				Name  string
+				Value string
+			}
+			NextToken string
+		}
+
+		in := map[string]interface{}{
+			"Path":           l.Path,
+			"Recursive":      true,
+			"WithDecryption": true,
+		}
+		if token != "" {
+			in["NextToken"] = token
+		}
+
+		if err := client.call(ctx, "GetParametersByPath", in, &resp); err != nil {
+			return err
+		}
+
+		for _, p := range resp.Parameters {
+			pairs[p.Name] = p.Value
+		}
+
+		if token = resp.NextToken; token == "" {
+			break
+		}
+	}
+
+	return decodeKV("ssm "+l.Path, l.Path, "/", pairs, s, l.Strict)
+}
@@ -0,0 +1,63 @@
+package multiconfig
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Store holds the current configuration of a service whose sources are
+// watched, so it can be read from any goroutine during a hot reload:
+//
+//	store, err := multiconfig.NewStore[Server](m)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer store.Close()
+//
+//	conf := store.Get()
+//
+// Each reload is loaded into a new struct, which replaces the current one
+// only once it's validated. The structs returned by Get are shared and must
+// not be modified.
+type Store[T any] struct {
+	current atomic.Value // *T
+
+	stop func()
+	once sync.Once
+}
+
+// NewStore loads and validates a T with d, and keeps it current by watching
+// the sources of d, as Watch does, refreshing them every interval given to
+// RefreshEvery. If d reads no source to watch, the configuration loaded is
+// never replaced.
+func NewStore[T any](d *DefaultLoader) (*Store[T], error) {
+	conf, err := LoadWith[T](d)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store[T]{}
+	s.current.Store(conf)
+
+	s.stop, err = d.Watch(conf, func(_, new interface{}) {
+		s.current.Store(new.(*T))
+	})
+	if err == ErrNothingToWatch {
+		s.stop = func() {}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Get returns the current configuration.
+func (s *Store[T]) Get() *T {
+	return s.current.Load().(*T)
+}
+
+// Close stops watching the sources, the configuration returned by Get isn't
+// replaced anymore.
+func (s *Store[T]) Close() {
+	s.once.Do(s.stop)
+}
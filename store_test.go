@@ -0,0 +1,81 @@
+package multiconfig
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	l := &refreshedLoader{names: []string{"koding", "", "invalid", "refreshed"}}
+	m := &DefaultLoader{Loader: l, Validator: ValidatorFunc(func(s interface{}) error {
+		if s.(*Server).Name == "invalid" {
+			return errors.New("invalid name")
+		}
+		return nil
+	})}
+	m.RefreshEvery(5 * time.Millisecond)
+
+	store, err := NewStore[Server](m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	first := store.Get()
+	if first.Name != "koding" {
+		t.Fatalf("unexpected name: %q", first.Name)
+	}
+
+	// the snapshots are read while being replaced, the invalid one never is
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if name := store.Get().Name; name != "koding" && name != "refreshed" {
+					t.Errorf("unexpected name: %q", name)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for store.Get().Name != "refreshed" {
+		if store.Get().Name == "invalid" {
+			t.Fatal("an invalid config should not be stored")
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("config not refreshed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if first.Name != "koding" {
+		t.Errorf("a snapshot should not be modified, got name %q", first.Name)
+	}
+
+	store.Close()
+	store.Close()
+}
+
+func TestStoreInvalid(t *testing.T) {
+	if _, err := NewStore[Server](newDefaultLoader(&TagLoader{})); err == nil {
+		t.Error("a config missing its required fields should not be stored")
+	}
+
+	l := &refreshedLoader{names: []string{"koding"}}
+	store, err := NewStore[Server](&DefaultLoader{Loader: l})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if store.Get().Name != "koding" {
+		t.Errorf("unexpected name: %q", store.Get().Name)
+	}
+}
@@ -0,0 +1,157 @@
+package multiconfig
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// structSource is the source reported for the fields set by a StructLoader.
+const structSource = "struct"
+
+// StructLoader satisfies the loader interface. It copies the non-zero fields
+// of an existing struct, such as a base configuration built in code, into
+// the loaded struct, so the loaders following it override them. It must
+// follow the TagLoader, whose defaults would otherwise override the base:
+//
+//	base := &Server{Name: "koding", Port: 6060}
+//
+//	m := multiconfig.NewBuilder().
+//		WithTags().
+//		WithLoader(&multiconfig.StructLoader{Source: base}).
+//		WithFile("config.toml").
+//		WithEnv("").
+//		Build()
+//
+// Nested structs are copied field by field, and the zero fields of Source
+// leave the ones of the loaded struct untouched. Pointers, slices and maps
+// are copied deeply, so the loaded struct doesn't share them with Source.
+// Slices and maps are merged according to their merge tag, like the ones
+// of the other loaders.
+type StructLoader struct {
+	// Source is a pointer to a struct of the type of the loaded struct.
+	Source interface{}
+}
+
+// Load loads the source into the config defined by struct s
+func (l *StructLoader) Load(s interface{}) error {
+	if l.Source == nil {
+		return ErrSourceNotSet
+	}
+
+	src, dst := reflect.ValueOf(l.Source), reflect.ValueOf(s)
+	if src.Type() != dst.Type() || src.Kind() != reflect.Ptr || src.Elem().Kind() != reflect.Struct || src.IsNil() || dst.IsNil() {
+		return fmt.Errorf("multiconfig: cannot load the struct %T into %T, two pointers to a struct of the same type are required", l.Source, s)
+	}
+
+	return copyStruct("", src.Elem(), dst.Elem(), func(path string) {
+		markLoaded(s, path, structSource)
+	})
+}
+
+// copyStruct copies the non-zero fields of the struct src into dst, found at
+// path, calling fn with the path of each field set. Slices and maps are
+// merged according to their merge tag.
+func copyStruct(path string, src, dst reflect.Value, fn func(path string)) error {
+	for _, field := range structFields(src.Type()) {
+		from, to := src.FieldByIndex(field.Index), dst.FieldByIndex(field.Index)
+		fieldPath := joinPath(path, field.Name)
+
+		if from.IsZero() {
+			continue
+		}
+
+		strategy, err := mergeStrategy(field.Tag.Get(mergeTag), fieldPath)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case isNestedType(from.Type()) && from.Kind() == reflect.Ptr:
+			if to.IsNil() {
+				to.Set(reflect.New(from.Type().Elem()))
+			}
+			if err := copyStruct(fieldPath, from.Elem(), to.Elem(), fn); err != nil {
+				return err
+			}
+		case isNestedType(from.Type()):
+			if err := copyStruct(fieldPath, from, to, fn); err != nil {
+				return err
+			}
+		case from.Kind() == reflect.Slice:
+			to.Set(mergeSlices(strategy, to, cloneValue(from)))
+			fn(fieldPath)
+		case from.Kind() == reflect.Map:
+			to.Set(mergeMaps(strategy, to, cloneValue(from)))
+			fn(fieldPath)
+		default:
+			to.Set(cloneValue(from))
+			fn(fieldPath)
+		}
+	}
+
+	return nil
+}
+
+// mergeMaps merges the entries of the map v into the map old according to
+// the given strategy: unless it's replace, the entries of v are added to a
+// copy of old.
+func mergeMaps(strategy string, old, v reflect.Value) reflect.Value {
+	if strategy == mergeReplace || old.Len() == 0 {
+		return v
+	}
+
+	merged := reflect.MakeMapWithSize(old.Type(), old.Len()+v.Len())
+	for _, m := range []reflect.Value{old, v} {
+		for iter := m.MapRange(); iter.Next(); {
+			merged.SetMapIndex(iter.Key(), iter.Value())
+		}
+	}
+
+	return merged
+}
+
+// cloneValue returns a copy of v sharing none of its pointers, slices and
+// maps, nor the ones of their elements and of the exported fields of its
+// structs.
+func cloneValue(v reflect.Value) reflect.Value {
+	if v.IsZero() {
+		return v
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		p := reflect.New(v.Type().Elem())
+		p.Elem().Set(cloneValue(v.Elem()))
+		return p
+	case reflect.Slice:
+		list := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			list.Index(i).Set(cloneValue(v.Index(i)))
+		}
+		return list
+	case reflect.Map:
+		m := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			m.SetMapIndex(iter.Key(), cloneValue(iter.Value()))
+		}
+		return m
+	case reflect.Array:
+		list := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			list.Index(i).Set(cloneValue(v.Index(i)))
+		}
+		return list
+	case reflect.Struct:
+		// unexported fields can't be set, they are copied as they are
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(cloneValue(v.Field(i)))
+			}
+		}
+		return c
+	default:
+		return v
+	}
+}
@@ -0,0 +1,192 @@
+package multiconfig
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStructLoader(t *testing.T) {
+	base := &Server{
+		Name:  "base",
+		Port:  7070,
+		Users: []string{"koding"},
+		Postgres: Postgres{
+			Hosts: []string{"db"},
+		},
+	}
+
+	m := newDefaultLoader(
+		&TagLoader{},
+		&StructLoader{Source: base},
+		&EnvironmentLoader{Prefix: "BASE", getenv: testEnvironment{"BASE_NAME": "env"}.get},
+	)
+
+	s := &Server{}
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	// the zero fields of the base leave the defaults untouched
+	want := &Server{
+		Name:  "env",
+		Port:  7070,
+		Users: []string{"koding"},
+		Postgres: Postgres{
+			Hosts:  []string{"db"},
+			DBName: "configdb",
+		},
+	}
+	if diff := cmp.Diff(want, s, cmp.AllowUnexported(Server{}, Postgres{})); diff != "" {
+		t.Errorf("unexpected config (-want +got):\n%s", diff)
+	}
+
+	wantLoaded := []string{"Name", "Port", "Postgres.DBName", "Postgres.Hosts", "Users"}
+	if diff := cmp.Diff(wantLoaded, m.LoadedFields(s)); diff != "" {
+		t.Errorf("unexpected loaded fields (-want +got):\n%s", diff)
+	}
+
+	s.Users[0] = "changed"
+	if base.Users[0] != "koding" {
+		t.Errorf("the slices of the source should not be shared")
+	}
+}
+
+func TestStructLoaderBuilder(t *testing.T) {
+	base := &Server{Port: 7070, Postgres: Postgres{DBName: "basedb"}}
+
+	m := NewBuilder().
+		WithTags().
+		WithLoader(&StructLoader{Source: base}).
+		WithReader(strings.NewReader("Name = \"koding\"\n[Postgres]\nDBName = \"filedb\"\n"), TOML).
+		Build()
+
+	s := &Server{}
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	// the base overrides the defaults, the file overrides the base
+	if s.Name != "koding" || s.Port != 7070 || s.Postgres.DBName != "filedb" {
+		t.Errorf("unexpected config: %+v", s)
+	}
+}
+
+func TestStructLoaderPointers(t *testing.T) {
+	type Nested struct {
+		Primary *Postgres
+		Replica *Postgres
+		Timeout *int
+		Tags    map[string]*string
+	}
+
+	timeout, tag := 30, "blue"
+	base := &Nested{Primary: &Postgres{Port: 6432}, Timeout: &timeout, Tags: map[string]*string{"color": &tag}}
+
+	s := &Nested{Replica: &Postgres{Port: 5432}}
+	if err := (&StructLoader{Source: base}).Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Primary == nil || s.Primary.Port != 6432 || s.Replica.Port != 5432 {
+		t.Errorf("unexpected config: %+v %+v", s.Primary, s.Replica)
+	}
+
+	if s.Timeout == base.Timeout || s.Tags["color"] == base.Tags["color"] {
+		t.Fatalf("the pointers of the source should not be shared")
+	}
+
+	if *s.Timeout != 30 || *s.Tags["color"] != "blue" {
+		t.Errorf("unexpected config: %d %s", *s.Timeout, *s.Tags["color"])
+	}
+}
+
+func TestStructLoaderNestedCollections(t *testing.T) {
+	type DB struct {
+		Hosts  []string
+		Labels map[string]string
+	}
+
+	type Cluster struct {
+		DBs    []DB
+		ByName map[string]DB
+	}
+
+	base := &Cluster{
+		DBs:    []DB{{Hosts: []string{"db1"}, Labels: map[string]string{"k": "v"}}},
+		ByName: map[string]DB{"main": {Hosts: []string{"db1"}}},
+	}
+
+	s := &Cluster{}
+	if err := (&StructLoader{Source: base}).Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	s.DBs[0].Hosts[0] = "changed"
+	s.DBs[0].Labels["k"] = "changed"
+	s.ByName["main"].Hosts[0] = "changed"
+
+	if base.DBs[0].Hosts[0] != "db1" || base.DBs[0].Labels["k"] != "v" || base.ByName["main"].Hosts[0] != "db1" {
+		t.Errorf("the collections of the elements of the source should not be shared: %+v", base)
+	}
+}
+
+func TestStructLoaderMerge(t *testing.T) {
+	type Merged struct {
+		Hosts    []string `merge:"append"`
+		Tags     []string `merge:"union"`
+		Labels   map[string]string
+		Replaced map[string]string `merge:"replace"`
+	}
+
+	base := &Merged{
+		Hosts:    []string{"db2"},
+		Tags:     []string{"a", "b"},
+		Labels:   map[string]string{"env": "prod"},
+		Replaced: map[string]string{"env": "prod"},
+	}
+
+	s := &Merged{
+		Hosts:    []string{"db1"},
+		Tags:     []string{"a"},
+		Labels:   map[string]string{"team": "infra"},
+		Replaced: map[string]string{"team": "infra"},
+	}
+
+	if err := (&StructLoader{Source: base}).Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Merged{
+		Hosts:    []string{"db1", "db2"},
+		Tags:     []string{"a", "b"},
+		Labels:   map[string]string{"env": "prod", "team": "infra"},
+		Replaced: map[string]string{"env": "prod"},
+	}
+	if diff := cmp.Diff(want, s); diff != "" {
+		t.Errorf("unexpected config (-want +got):\n%s", diff)
+	}
+
+	invalid := &struct {
+		Hosts []string `merge:"prepend"`
+	}{Hosts: []string{"db1"}}
+
+	if err := (&StructLoader{Source: invalid}).Load(&struct {
+		Hosts []string `merge:"prepend"`
+	}{}); err == nil {
+		t.Error("an unknown merge strategy should fail")
+	}
+}
+
+func TestStructLoaderErrors(t *testing.T) {
+	if err := (&StructLoader{}).Load(&Server{}); err != ErrSourceNotSet {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err := (&StructLoader{Source: &Postgres{}}).Load(&Server{})
+	want := "multiconfig: cannot load the struct *multiconfig.Postgres into *multiconfig.Server, two pointers to a struct of the same type are required"
+	if err == nil || err.Error() != want {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package multiconfig
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// StructChecker is the interface of the libraries validating a whole struct
+// against the constraints of its tags, such as the *validator.Validate of
+// github.com/go-playground/validator.
+type StructChecker interface {
+	Struct(s interface{}) error
+}
+
+// StructValidator satisfies the Validator interface by delegating to a
+// StructChecker, so constraints already written for go-playground/validator
+// don't have to be duplicated:
+//
+//	type Server struct {
+//		Port int    `validate:"min=1,max=65535"`
+//		Host string `validate:"required,hostname"`
+//	}
+//
+//	m := multiconfig.New()
+//	m.AddValidator(&multiconfig.StructValidator{Checker: validator.New()})
+//
+// The errors of go-playground/validator are reported with the path of the
+// failing field and the tag it fails.
+type StructValidator struct {
+	// Checker validates the struct.
+	Checker StructChecker
+}
+
+// fieldError is the interface of the errors go-playground/validator returns
+// for each failing field, within its ValidationErrors.
+type fieldError interface {
+	StructNamespace() string
+	Tag() string
+	Param() string
+	Value() interface{}
+}
+
+// Validate validates the given struct with the Checker.
+func (v *StructValidator) Validate(s interface{}) error {
+	if v.Checker == nil {
+		return nil
+	}
+
+	err := v.Checker.Struct(s)
+	if err == nil {
+		return nil
+	}
+
+	// ValidationErrors is a slice of field errors
+	var errs ValidationErrors
+	if list := reflect.ValueOf(err); list.Kind() == reflect.Slice {
+		for i := 0; i < list.Len(); i++ {
+			if fe, ok := list.Index(i).Interface().(fieldError); ok {
+				errs.add(checkerError(reflect.TypeOf(s), fe))
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return fmt.Errorf("multiconfig: %s", err)
+	}
+
+	return errs
+}
+
+// checkerError returns the error of a field of the struct type t failing a
+// constraint of go-playground/validator. The value of a secret is redacted.
+func checkerError(t reflect.Type, fe fieldError) error {
+	// the namespace starts with the name of the struct
+	name := fe.StructNamespace()
+	if i := strings.Index(name, "."); i != -1 {
+		name = name[i+1:]
+	}
+
+	tag := fe.Tag()
+	if fe.Param() != "" {
+		tag += "=" + fe.Param()
+	}
+
+	value := fe.Value()
+	if isSecretPath(t, name) {
+		value = redacted
+	}
+
+	return fieldErrorf(name, value, "failed the '%s' validation, got '%v'", tag, value)
+}
+
+// isSecretPath reports whether the field found at the given path of the
+// struct type t, i.e: "Postgres.Password" or "Hosts[0]", is tagged with
+// `secret:"true"`, or belongs to such a field.
+func isSecretPath(t reflect.Type, path string) bool {
+	for _, name := range strings.Split(path, ".") {
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		if t == nil || t.Kind() != reflect.Struct {
+			return false
+		}
+
+		if i := strings.Index(name, "["); i != -1 {
+			name = name[:i]
+		}
+
+		field, ok := t.FieldByName(name)
+		if !ok {
+			return false
+		}
+
+		if field.Tag.Get("secret") == "true" {
+			return true
+		}
+
+		t = field.Type
+	}
+
+	return false
+}
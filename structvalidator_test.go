@@ -0,0 +1,96 @@
+package multiconfig
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// playgroundError mimics a FieldError of go-playground/validator.
+type playgroundError struct {
+	namespace, tag, param string
+	value                 interface{}
+}
+
+func (e playgroundError) StructNamespace() string { return e.namespace }
+func (e playgroundError) Tag() string             { return e.tag }
+func (e playgroundError) Param() string           { return e.param }
+func (e playgroundError) Value() interface{}      { return e.value }
+
+// playgroundErrors mimics the ValidationErrors of go-playground/validator.
+type playgroundErrors []interface{}
+
+func (e playgroundErrors) Error() string { return "validation failed" }
+
+// playgroundChecker checks the port of a Server the way
+// `validate:"min=1024"` would.
+type playgroundChecker struct{}
+
+func (playgroundChecker) Struct(s interface{}) error {
+	server, ok := s.(*Server)
+	if !ok {
+		return errors.New("validator: unsupported type")
+	}
+
+	if server.Postgres.Port < 1024 {
+		return playgroundErrors{playgroundError{"Server.Postgres.Port", "min", "1024", server.Postgres.Port}}
+	}
+
+	return nil
+}
+
+// checkerFunc is a StructChecker validating with a function.
+type checkerFunc func(s interface{}) error
+
+func (f checkerFunc) Struct(s interface{}) error { return f(s) }
+
+func TestStructValidator(t *testing.T) {
+	v := &StructValidator{Checker: playgroundChecker{}}
+
+	s := getDefaultServer()
+	if err := v.Validate(s); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Postgres.Port = 80
+	err := v.Validate(s)
+	if err == nil || err.Error() != "multiconfig: field 'Postgres.Port' failed the 'min=1024' validation, got '80'" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	secret := &StructValidator{Checker: checkerFunc(func(s interface{}) error {
+		return playgroundErrors{playgroundError{"Database.Password", "min", "8", "s3cr3t"}}
+	})}
+
+	err = secret.Validate(&struct {
+		Password string `secret:"true"`
+	}{"s3cr3t"})
+	if err == nil || err.Error() != "multiconfig: field 'Password' failed the 'min=8' validation, got '****'" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err = v.Validate(&struct{}{})
+	if err == nil || !strings.HasPrefix(err.Error(), "multiconfig: validator:") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestStructValidatorDefaultLoader(t *testing.T) {
+	m := NewWithPath(testTOML)
+	m.AddValidator(&StructValidator{Checker: playgroundChecker{}})
+
+	s := new(Server)
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Validate(s); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Name = ""
+	err := m.Validate(s)
+	if err == nil || err.Error() != "multiconfig: field 'Name' is required" {
+		t.Errorf("the required validator didn't run first: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package multiconfig
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// didYouMean returns prefix+name followed by the closest of the candidates,
+// if any is close enough to be a misspelling of name, i.e:
+// `Postgres.prot (did you mean "Postgres.Port"?)` for the prefix "Postgres.".
+func didYouMean(prefix, name string, candidates []string) string {
+	if s := suggest(name, candidates); s != "" {
+		return fmt.Sprintf("%s%s (did you mean %q?)", prefix, name, prefix+s)
+	}
+
+	return prefix + name
+}
+
+// suggest returns the candidate closest to name, compared case
+// insensitively, or an empty string if none is within a third of the length
+// of name, and at least one, edits of it.
+func suggest(name string, candidates []string) string {
+	max := len(name) / 3
+	if max < 1 {
+		max = 1
+	}
+
+	best, bestDist := "", max+1
+	for _, c := range candidates {
+		dist := editDistance(strings.ToLower(name), strings.ToLower(c))
+		if dist < bestDist || dist == bestDist && c < best {
+			best, bestDist = c, dist
+		}
+	}
+
+	return best
+}
+
+// editDistance returns the number of insertions, deletions, substitutions
+// and transpositions of adjacent characters turning a into b, a transposition
+// being the most common typo.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	// d[i][j] is the distance between the first i runes of a and the first j
+	// runes of b
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			d[i][j] = minInt(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(ra)][len(rb)]
+}
+
+func minInt(n int, ns ...int) int {
+	for _, m := range ns {
+		if m < n {
+			n = m
+		}
+	}
+
+	return n
+}
+
+// keyNames returns the names a source uses for the fields of the struct type
+// t, given by fields as returned by keyFields, see keyName.
+func keyNames(t reflect.Type, tagName string, nameTags []string, fields map[string][]int) []string {
+	names := make([]string, 0, len(fields))
+	for _, index := range fields {
+		names = append(names, keyName(t.FieldByIndex(index), tagName, nameTags))
+	}
+
+	return names
+}
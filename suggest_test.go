@@ -0,0 +1,23 @@
+package multiconfig
+
+import "testing"
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"Name", "Port", "Postgres", "Enabled"}
+
+	tests := map[string]string{
+		"Nmae":     "Name",
+		"prot":     "Port",
+		"port":     "Port",
+		"protgres": "Postgres",
+		"enabeld":  "Enabled",
+		"users":    "",
+		"x":        "",
+	}
+
+	for name, want := range tests {
+		if got := suggest(name, candidates); got != want {
+			t.Errorf("%s: suggestion is %q, want %q", name, got, want)
+		}
+	}
+}
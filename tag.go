@@ -2,12 +2,25 @@ package multiconfig
 
 import (
 	"reflect"
-
-	"github.com/fatih/structs"
+	"strings"
 )
 
+// defaultTagSource is the source reported for the fields set by their
+// default tag.
+const defaultTagSource = "default tag"
+
 // TagLoader satisfies the loader interface. It parses a struct's field tags
 // and populates the each field with that given tag.
+//
+// Slices and maps take their elements as for environment variables, i.e:
+// `default:"localhost:5432,replica:5432"` or `default:"env=dev,team=core"`.
+// Nested structs, and collections of structs, take a JSON literal instead:
+//
+//	Primary  Database   `default:"{\"host\": \"db\", \"port\": 5432}"`
+//	Replicas []Database `default:"[{\"host\": \"replica\"}]"`
+//
+// The keys of the literal of a struct are merged over the defaults of its
+// fields, and a nil pointer to a struct given a literal is allocated.
 type TagLoader struct {
 	// DefaultTagName is the default tag name for struct fields to define
 	// default values for a field. Example:
@@ -17,44 +30,178 @@ type TagLoader struct {
 	//
 	// The default value is "default" if it's not set explicitly.
 	DefaultTagName string
+
+	// SliceSeparator separates the elements of the default values of slice
+	// fields, i.e: `default:"ankara;istanbul"` with a separator of ";". The
+	// default is ",".
+	SliceSeparator string
+}
+
+// defaultField is a field with a default tag.
+type defaultField struct {
+	// path is the path of the field, i.e: "Postgres.Port".
+	path string
+
+	// index is the index sequence of the field within the struct.
+	index []int
+
+	// value is the default value of the field.
+	value string
+
+	// literal is true if the value is a JSON literal, the default of a
+	// nested struct or of a collection of structs.
+	literal bool
+}
+
+// defaultsKey is the key of the cached default fields of a struct type.
+type defaultsKey struct {
+	typ reflect.Type
+	tag string
 }
 
 func (t *TagLoader) Load(s interface{}) error {
-	if t.DefaultTagName == "" {
-		t.DefaultTagName = "default"
+	if isRaw(s) {
+		return nil
+	}
+
+	return t.setDefaults(s, "")
+}
+
+// setDefaults sets the fields of s nested at the given path, or all of them
+// if path is empty, to their default value.
+func (t *TagLoader) setDefaults(s interface{}, path string) error {
+	v := reflect.ValueOf(s)
+	for _, field := range t.defaultFields(v.Type()) {
+		if path != "" && !strings.HasPrefix(field.path, path+".") {
+			continue
+		}
+
+		// a nil pointer is a struct not provided, which has no defaults
+		if !reachable(v, field.index) {
+			continue
+		}
+
+		if field.literal {
+			if err := t.setLiteral(s, field); err != nil {
+				return withLoader(err, defaultTagSource)
+			}
+			continue
+		}
+
+		f, ok := fieldByPath(s, field.path)
+		if !ok {
+			continue
+		}
+
+		if err := fieldSet(f, field.value, t.SliceSeparator, field.path); err != nil {
+			return withLoader(err, defaultTagSource)
+		}
+
+		markLoaded(s, field.path, defaultTagSource)
 	}
 
-	for _, field := range structs.Fields(s) {
+	return nil
+}
+
+// setLiteral sets the field of s from its default JSON literal, i.e:
+// `default:"{\"host\": \"db\", \"port\": 5432}"`. The keys of the literal are
+// merged over the defaults of the fields of a struct, and a nil pointer to a
+// struct is allocated.
+func (t *TagLoader) setLiteral(s interface{}, field defaultField) error {
+	raw, err := decodeRaw("json", []byte(`{"default": `+field.value+`}`))
+	if err != nil {
+		return fieldErrorf(field.path, field.value, "has an invalid default tag: %s", err)
+	}
 
-		if err := t.processField(t.DefaultTagName, field); err != nil {
+	v := fieldByIndex(reflect.ValueOf(s).Elem(), field.index)
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		// the struct being allocated gets the defaults of its fields
+		v.Set(reflect.New(v.Type().Elem()))
+		if err := t.setDefaults(s, field.path); err != nil {
 			return err
 		}
 	}
 
+	d := &decoder{
+		format:   "json",
+		target:   s,
+		source:   defaultTagSource,
+		tracking: isTracking(s),
+	}
+
+	if err := d.value(field.path, raw["default"], v); err != nil {
+		return err
+	}
+
+	// the fields of a struct are reported by the decoder
+	if reflect.Indirect(v).Kind() != reflect.Struct {
+		markLoaded(s, field.path, defaultTagSource)
+	}
+
 	return nil
 }
 
-// processField gets tagName and the field, recursively checks if the field has the given
-// tag, if yes, sets it otherwise ignores
-func (t *TagLoader) processField(tagName string, field *structs.Field) error {
-	switch field.Kind() {
-	case reflect.Struct:
-		for _, f := range field.Fields() {
-			if err := t.processField(tagName, f); err != nil {
-				return err
-			}
+// defaultFields returns the fields of the struct type t, or of the struct t
+// points to, which have a default tag, in the order of their declaration.
+// They are cached per type.
+func (t *TagLoader) defaultFields(typ reflect.Type) []defaultField {
+	key := defaultsKey{typ: typ, tag: t.DefaultTagName}
+	if key.tag == "" {
+		key.tag = "default"
+	}
+
+	return cached(key, func() interface{} {
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
 		}
-	default:
-		defaultVal := field.Tag(t.DefaultTagName)
-		if defaultVal == "" {
-			return nil
+
+		return appendDefaultFields(nil, typ, key.tag, "", nil)
+	}).([]defaultField)
+}
+
+// appendDefaultFields appends the fields of the struct type typ with a
+// default tag named tag to fields, recursing into the nested structs. path
+// and index are the ones of the struct within the loaded struct.
+func appendDefaultFields(fields []defaultField, typ reflect.Type, tag, path string, index []int) []defaultField {
+	for _, field := range structFields(typ) {
+		fieldPath := joinPath(path, field.Name)
+		fieldIndex := append(append([]int(nil), index...), field.Index...)
+
+		if isNestedType(field.Type) {
+			elem := field.Type
+			if elem.Kind() == reflect.Ptr {
+				elem = elem.Elem()
+			}
+
+			fields = appendDefaultFields(fields, elem, tag, fieldPath, fieldIndex)
+
+			// the literal of the struct is merged over its fields' defaults
+			if value := field.Tag.Get(tag); value != "" {
+				fields = append(fields, defaultField{path: fieldPath, index: fieldIndex, value: value, literal: true})
+			}
+			continue
 		}
 
-		err := fieldSet(field, defaultVal)
-		if err != nil {
-			return err
+		if value := field.Tag.Get(tag); value != "" {
+			fields = append(fields, defaultField{
+				path:    fieldPath,
+				index:   fieldIndex,
+				value:   value,
+				literal: isStructCollection(field.Type),
+			})
 		}
 	}
 
-	return nil
+	return fields
+}
+
+// isStructCollection reports whether t is a slice, an array or a map of
+// nested structs, whose default is a JSON literal.
+func isStructCollection(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return isNestedType(t.Elem())
+	}
+
+	return false
 }
@@ -1,6 +1,9 @@
 package multiconfig
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestDefaultValues(t *testing.T) {
 	m := &TagLoader{}
@@ -17,3 +20,89 @@ func TestDefaultValues(t *testing.T) {
 		t.Errorf("Postgres DBName value is wrong: %s, want: %s", s.Postgres.DBName, getDefaultServer().Postgres.DBName)
 	}
 }
+
+func TestDefaultValuesSliceSeparator(t *testing.T) {
+	s := &struct {
+		Users  []string `default:"ankara;istanbul"`
+		Labels []int    `default:"123;456"`
+	}{}
+
+	m := &TagLoader{SliceSeparator: ";"}
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.Users) != 2 || s.Users[0] != "ankara" || s.Users[1] != "istanbul" {
+		t.Errorf("Users value is wrong: %v", s.Users)
+	}
+
+	if len(s.Labels) != 2 || s.Labels[0] != 123 || s.Labels[1] != 456 {
+		t.Errorf("Labels value is wrong: %v", s.Labels)
+	}
+}
+
+func TestDefaultValuesComposite(t *testing.T) {
+	type Database struct {
+		Host string `default:"localhost"`
+		Port int    `default:"5432"`
+	}
+
+	s := &struct {
+		Hosts    []string          `default:"localhost:5432,replica:5432"`
+		Labels   map[string]string `default:"env=dev,team=core"`
+		Primary  Database          `default:"{\"host\": \"db\"}"`
+		Backup   *Database         `default:"{\"port\": 6432}"`
+		Optional *Database
+		Replicas []Database          `default:"[{\"host\": \"r1\", \"port\": 5433}, {\"host\": \"r2\"}]"`
+		Shards   map[string]Database `default:"{\"eu\": {\"host\": \"eu.db\"}}"`
+	}{}
+
+	m := newDefaultLoader(&TagLoader{})
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.Hosts) != 2 || s.Hosts[1] != "replica:5432" {
+		t.Errorf("Hosts value is wrong: %v", s.Hosts)
+	}
+
+	if len(s.Labels) != 2 || s.Labels["team"] != "core" {
+		t.Errorf("Labels value is wrong: %v", s.Labels)
+	}
+
+	if s.Primary != (Database{Host: "db", Port: 5432}) {
+		t.Errorf("Primary value is wrong: %+v", s.Primary)
+	}
+
+	if s.Backup == nil || *s.Backup != (Database{Host: "localhost", Port: 6432}) {
+		t.Errorf("Backup value is wrong: %+v", s.Backup)
+	}
+
+	if s.Optional != nil {
+		t.Errorf("Optional should be nil: %+v", s.Optional)
+	}
+
+	if len(s.Replicas) != 2 || s.Replicas[0] != (Database{Host: "r1", Port: 5433}) || s.Replicas[1].Host != "r2" {
+		t.Errorf("Replicas value is wrong: %+v", s.Replicas)
+	}
+
+	if s.Shards["eu"].Host != "eu.db" {
+		t.Errorf("Shards value is wrong: %+v", s.Shards)
+	}
+
+	loaded := strings.Join(m.LoadedFields(s), ",")
+	if loaded != "Backup.Host,Backup.Port,Hosts,Labels,Primary.Host,Primary.Port,Replicas,Replicas[0].Host,Replicas[0].Port,Replicas[1].Host,Shards,Shards.eu.Host" {
+		t.Errorf("unexpected loaded fields: %s", loaded)
+	}
+}
+
+func TestDefaultValuesInvalidLiteral(t *testing.T) {
+	s := &struct {
+		Primary struct{ Host string } `default:"{host: db}"`
+	}{}
+
+	err := (&TagLoader{}).Load(s)
+	if err == nil || !strings.HasPrefix(err.Error(), "multiconfig: field 'Primary' has an invalid default tag") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
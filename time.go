@@ -0,0 +1,67 @@
+package multiconfig
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// layoutTag is the tag giving the layout time.Time fields are parsed with, as
+// understood by time.Parse, i.e: `layout:"2006-01-02"`. Without it, times are
+// parsed as RFC 3339.
+const layoutTag = "layout"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// isTimeType reports whether t is time.Time, a pointer to it or a slice of
+// them, which a layout applies to.
+func isTimeType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice:
+		return t.Elem() == timeType
+	}
+
+	return t == timeType
+}
+
+// setTime sets v, of a type reported by isTimeType, from the given string
+// value s parsed with layout. The elements of slices are separated by sep.
+// name is the path of the field holding v, used in error messages.
+func setTime(v reflect.Value, s, sep, layout, name string) error {
+	if err := convertTime(v, s, sep, layout); err != nil {
+		return fieldErr(name, s, err)
+	}
+
+	return nil
+}
+
+func convertTime(v reflect.Value, s, sep, layout string) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(timeType)
+		if err := convertTime(elem.Elem(), s, sep, layout); err != nil {
+			return err
+		}
+
+		v.Set(elem)
+	case reflect.Slice:
+		elems := splitList(s, sep)
+		list := reflect.MakeSlice(v.Type(), len(elems), len(elems))
+		for i, elem := range elems {
+			if err := convertTime(list.Index(i), elem, sep, layout); err != nil {
+				return err
+			}
+		}
+
+		v.Set(list)
+	default:
+		t, err := time.Parse(layout, strings.TrimSpace(s))
+		if err != nil {
+			return err
+		}
+
+		v.Set(reflect.ValueOf(t))
+	}
+
+	return nil
+}
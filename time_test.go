@@ -0,0 +1,69 @@
+package multiconfig
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type Schedule struct {
+	Start    time.Time   `layout:"2006-01-02" default:"2024-01-02"`
+	End      *time.Time  `layout:"2006-01-02"`
+	Holidays []time.Time `layout:"Jan 2"`
+	Updated  time.Time
+}
+
+func TestTimeLayout(t *testing.T) {
+	sources := map[string]Loader{
+		"json": &JSONLoader{Reader: strings.NewReader(
+			`{"End": "2024-12-31", "Holidays": ["Jan 1", "Dec 25"], "Updated": "2024-03-04T05:06:07Z"}`)},
+		"yaml": &YAMLLoader{Reader: strings.NewReader(
+			"end: 2024-12-31\nholidays: [Jan 1, Dec 25]\nupdated: 2024-03-04T05:06:07Z\n")},
+		"toml": &TOMLLoader{Reader: strings.NewReader(
+			"End = \"2024-12-31\"\nHolidays = [\"Jan 1\", \"Dec 25\"]\nUpdated = \"2024-03-04T05:06:07Z\"\n")},
+		"env": &EnvironmentLoader{getenv: testEnvironment{
+			"SCHEDULE_END":      "2024-12-31",
+			"SCHEDULE_HOLIDAYS": "Jan 1,Dec 25",
+			"SCHEDULE_UPDATED":  "2024-03-04T05:06:07Z",
+		}.get},
+		"flags": &FlagLoader{Args: []string{
+			"-end", "2024-12-31", "-holidays", "Jan 1,Dec 25", "-updated", "2024-03-04T05:06:07Z",
+		}},
+	}
+
+	for name, loader := range sources {
+		s := &Schedule{}
+		if err := MultiLoader(&TagLoader{}, loader).Load(s); err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+
+		if !s.Start.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("%s: start is %s", name, s.Start)
+		}
+
+		if s.End == nil || !s.End.Equal(time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("%s: end is %v", name, s.End)
+		}
+
+		if len(s.Holidays) != 2 || s.Holidays[1].Month() != time.December || s.Holidays[1].Day() != 25 {
+			t.Errorf("%s: holidays are %v", name, s.Holidays)
+		}
+
+		if !s.Updated.Equal(time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)) {
+			t.Errorf("%s: updated is %s", name, s.Updated)
+		}
+	}
+}
+
+func TestTimeLayoutError(t *testing.T) {
+	err := (&FlagLoader{Args: []string{"-start", "02/01/2024"}}).Load(&Schedule{})
+	if err == nil || !strings.Contains(err.Error(), "multiconfig: field 'Start': parsing time") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err = (&JSONLoader{Reader: strings.NewReader(`{"Holidays": ["Jan 1", "25/12"]}`)}).Load(&Schedule{})
+	if err == nil || !strings.Contains(err.Error(), "multiconfig: field 'Holidays[1]': parsing time") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
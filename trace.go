@@ -0,0 +1,213 @@
+package multiconfig
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// Tracer receives the events of the loading pipeline of a DefaultLoader, to
+// see what each loader did when a value comes out wrong.
+type Tracer interface {
+	Trace(e TraceEvent)
+}
+
+// TracerFunc is an adapter to use an ordinary function as a Tracer.
+type TracerFunc func(e TraceEvent)
+
+// Trace calls f(e).
+func (f TracerFunc) Trace(e TraceEvent) {
+	f(e)
+}
+
+// TraceKind is the kind of a TraceEvent.
+type TraceKind int
+
+const (
+	// TraceLoad is the invocation of a loader, whose Err is set if it
+	// failed.
+	TraceLoad TraceKind = iota
+
+	// TraceSet is a field set by a source. If Previous is set, the value
+	// of the previous source is overridden.
+	TraceSet
+
+	// TraceSkip is a value which isn't set, see Reason.
+	TraceSkip
+)
+
+// TraceEvent is an event of the loading pipeline.
+type TraceEvent struct {
+	Kind TraceKind
+
+	// Loader is the loader invoked, i.e: "*multiconfig.EnvironmentLoader",
+	// or the source of the value set or skipped, i.e: "env SERVER_PORT".
+	Loader string
+
+	// Path is the path of the field set or skipped, i.e: "Postgres.Port".
+	Path string
+
+	// Value is the value of the field set or skipped. The values of the
+	// fields tagged with `secret:"true"` are redacted.
+	Value interface{}
+
+	// Previous is the source which set the field before it was set again.
+	Previous string
+
+	// Reason tells why a value was skipped.
+	Reason string
+
+	// Err is the error of a failed loader.
+	Err error
+}
+
+// String describes the event on a single line.
+func (e TraceEvent) String() string {
+	switch e.Kind {
+	case TraceLoad:
+		if e.Err != nil {
+			return fmt.Sprintf("multiconfig: %s failed: %s", e.Loader, e.Err)
+		}
+
+		return fmt.Sprintf("multiconfig: %s loaded", e.Loader)
+	case TraceSet:
+		if e.Previous != "" {
+			return fmt.Sprintf("multiconfig: field '%s' set to '%v' by %s, overriding %s", e.Path, e.Value, e.Loader, e.Previous)
+		}
+
+		return fmt.Sprintf("multiconfig: field '%s' set to '%v' by %s", e.Path, e.Value, e.Loader)
+	}
+
+	return fmt.Sprintf("multiconfig: field '%s' skipped: %s", e.Path, e.Reason)
+}
+
+// LogTracer returns a Tracer printing each event to l, or to the standard
+// logger if nil.
+func LogTracer(l *log.Logger) Tracer {
+	if l == nil {
+		l = log.Default()
+	}
+
+	return TracerFunc(func(e TraceEvent) {
+		l.Println(e)
+	})
+}
+
+// traced returns the loader running the given loaders in order, tracing them
+// with the Tracer of d, if any. The loaders of a MultiLoader are traced one
+// by one.
+func (d *DefaultLoader) traced(loaders ...Loader) Loader {
+	if d.Tracer == nil {
+		return MultiLoader(loaders...)
+	}
+
+	var traced multiLoader
+	for _, l := range loaders {
+		if m, ok := l.(multiLoader); ok {
+			for _, l := range m {
+				traced = append(traced, tracedLoader{l, d.Tracer})
+			}
+			continue
+		}
+
+		traced = append(traced, tracedLoader{l, d.Tracer})
+	}
+
+	return tracingLoader{traced, d.Tracer}
+}
+
+// tracingLoader loads with its Loader, handing the fields set by any source
+// to the tracer.
+type tracingLoader struct {
+	Loader
+	tracer Tracer
+}
+
+func (t tracingLoader) Load(s interface{}) error {
+	return t.LoadContext(context.Background(), s)
+}
+
+func (t tracingLoader) LoadContext(ctx context.Context, s interface{}) error {
+	defer startTracking(s)()
+	defer setTracer(s, t.tracer)()
+
+	return loadContext(ctx, t.Loader, s)
+}
+
+// tracedLoader traces the invocation of its Loader.
+type tracedLoader struct {
+	Loader
+	tracer Tracer
+}
+
+func (t tracedLoader) Load(s interface{}) error {
+	return t.LoadContext(context.Background(), s)
+}
+
+func (t tracedLoader) LoadContext(ctx context.Context, s interface{}) error {
+	err := loadContext(ctx, t.Loader, s)
+	t.tracer.Trace(TraceEvent{Kind: TraceLoad, Loader: loaderName(t.Loader), Err: err})
+
+	return err
+}
+
+// setTracer sets the tracer of the fields of s while it's tracked. The
+// returned function restores the previous tracer.
+func setTracer(s interface{}, tracer Tracer) func() {
+	key, ok := loadKey(s)
+	if !ok {
+		return func() {}
+	}
+
+	loaded.Lock()
+	defer loaded.Unlock()
+
+	state, ok := loaded.m[key]
+	if !ok {
+		return func() {}
+	}
+
+	previous := state.tracer
+	state.tracer = tracer
+
+	return func() {
+		loaded.Lock()
+		state.tracer = previous
+		loaded.Unlock()
+	}
+}
+
+// trace hands the event to the tracer of s, if any.
+func trace(s interface{}, e TraceEvent) {
+	key, ok := loadKey(s)
+	if !ok {
+		return
+	}
+
+	loaded.Lock()
+	var tracer Tracer
+	if state, ok := loaded.m[key]; ok {
+		tracer = state.tracer
+	}
+	loaded.Unlock()
+
+	if tracer != nil {
+		tracer.Trace(e)
+	}
+}
+
+// traceValue returns the value of the field at path of s for a TraceEvent,
+// redacted if it's a secret.
+func traceValue(s interface{}, path string) interface{} {
+	if raw, ok := s.(*map[string]interface{}); ok {
+		v, _ := rawValue(raw, path)
+		return v
+	}
+
+	field, ok := fieldByPath(s, path)
+	if !ok {
+		return nil
+	}
+
+	return redact(field)
+}
@@ -0,0 +1,95 @@
+package multiconfig
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestTracer(t *testing.T) {
+	type Database struct {
+		Host     string `default:"localhost" deprecated:"use DSN instead" replacedBy:"DSN"`
+		DSN      string
+		Password string `secret:"true"`
+		Port     int    `default:"5432"`
+	}
+
+	var events []string
+	m := newDefaultLoader(&TagLoader{}, &EnvironmentLoader{getenv: testEnvironment{
+		"DATABASE_HOST":     "db.example.com",
+		"DATABASE_DSN":      "postgres://db",
+		"DATABASE_PASSWORD": "s3cr3t",
+	}.get})
+	m.Logger = log.New(&bytes.Buffer{}, "", 0)
+	m.Tracer = TracerFunc(func(e TraceEvent) { events = append(events, e.String()) })
+
+	if err := m.Load(&Database{}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"multiconfig: multiconfig.defaultsLoader loaded",
+		"multiconfig: field 'Host' set to 'localhost' by default tag",
+		"multiconfig: field 'Port' set to '5432' by default tag",
+		"multiconfig: *multiconfig.TagLoader loaded",
+		"multiconfig: field 'DSN' set to 'postgres://db' by env DATABASE_DSN",
+		"multiconfig: field 'Host' set to 'db.example.com' by env DATABASE_HOST, overriding default tag",
+		"multiconfig: field 'Password' set to '****' by env DATABASE_PASSWORD",
+		"multiconfig: *multiconfig.EnvironmentLoader loaded",
+		"multiconfig: field 'Host' skipped: not migrated to 'DSN', set by env DATABASE_DSN",
+		"multiconfig: multiconfig.deprecationLoader loaded",
+	}
+
+	// the environment variables are looked up in no particular order
+	if len(events) != len(want) || strings.Join(sortedEvents(events[4:7]), "\n") != strings.Join(want[4:7], "\n") {
+		t.Fatalf("unexpected events:\n%s", strings.Join(events, "\n"))
+	}
+
+	for i, e := range want {
+		if i >= 4 && i < 7 {
+			continue
+		}
+
+		if events[i] != e {
+			t.Errorf("event %d is %q, want %q", i, events[i], e)
+		}
+	}
+}
+
+func sortedEvents(events []string) []string {
+	sorted := append([]string(nil), events...)
+	for i := range sorted {
+		for j := i + 1; j < len(sorted); j++ {
+			if sorted[j] < sorted[i] {
+				sorted[i], sorted[j] = sorted[j], sorted[i]
+			}
+		}
+	}
+
+	return sorted
+}
+
+func TestLogTracer(t *testing.T) {
+	var buf bytes.Buffer
+
+	m := newDefaultLoader(&EnvironmentLoader{getenv: testEnvironment{"SERVER_PORT": "http"}.get})
+	m.Tracer = LogTracer(log.New(&buf, "", 0))
+
+	err := m.Load(&Server{})
+	if err == nil {
+		t.Fatal("loading an invalid port should fail")
+	}
+
+	want := "multiconfig: multiconfig.defaultsLoader loaded\n" +
+		"multiconfig: *multiconfig.EnvironmentLoader failed: " + err.Error() + "\n"
+	if buf.String() != want {
+		t.Errorf("unexpected log: %q", buf.String())
+	}
+
+	var fe *FieldError
+	if !errors.As(err, &fe) {
+		t.Errorf("the error of the loader is not returned as is: %v", err)
+	}
+}
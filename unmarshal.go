@@ -0,0 +1,95 @@
+package multiconfig
+
+import (
+	"encoding"
+	"reflect"
+
+	"github.com/fatih/structs"
+)
+
+// ConfigUnmarshaler is the interface implemented by types that can parse
+// themselves from the raw scalar value of a source, such as the value of an
+// environment variable or of a key in a config file. It takes precedence
+// over encoding.TextUnmarshaler, which is honored as well.
+type ConfigUnmarshaler interface {
+	UnmarshalConfig([]byte) error
+}
+
+var (
+	configUnmarshalerType = reflect.TypeOf((*ConfigUnmarshaler)(nil)).Elem()
+	textUnmarshalerType   = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// unmarshalText hands the raw value b to the ConfigUnmarshaler or
+// encoding.TextUnmarshaler implemented by the type of v, if any. A nil
+// pointer is allocated first. It reports whether v implements one of them.
+func unmarshalText(v reflect.Value, b []byte) (bool, error) {
+	u, ok := unmarshalerValue(v)
+	if !ok {
+		return false, nil
+	}
+
+	switch t := u.Interface().(type) {
+	case ConfigUnmarshaler:
+		return true, t.UnmarshalConfig(b)
+	case encoding.TextUnmarshaler:
+		return true, t.UnmarshalText(b)
+	}
+
+	return false, nil
+}
+
+// unmarshalerValue returns the pointer of v, or v itself if it's a pointer,
+// if it implements ConfigUnmarshaler or encoding.TextUnmarshaler.
+func unmarshalerValue(v reflect.Value) (reflect.Value, bool) {
+	implements := func(t reflect.Type) bool {
+		return t.Implements(configUnmarshalerType) || t.Implements(textUnmarshalerType)
+	}
+
+	if v.Kind() == reflect.Ptr && implements(v.Type()) {
+		if v.IsNil() {
+			if !v.CanSet() {
+				return reflect.Value{}, false
+			}
+
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		return v, true
+	}
+
+	if v.CanAddr() && implements(v.Addr().Type()) {
+		return v.Addr(), true
+	}
+
+	return reflect.Value{}, false
+}
+
+// isUnmarshaler reports whether the field's type, or its pointer,
+// implements ConfigUnmarshaler or encoding.TextUnmarshaler, or has a
+// registered decoder. Such a struct is
+// set as a whole from a single value instead of field by field.
+func isUnmarshaler(field *structs.Field) bool {
+	if field.Kind() == reflect.Interface {
+		return false
+	}
+
+	return isUnmarshalerType(reflect.TypeOf(field.Value()))
+}
+
+// isUnmarshalerType reports whether the type t, or a pointer to it,
+// implements ConfigUnmarshaler or encoding.TextUnmarshaler, or whether a
+// decoder is registered for it.
+func isUnmarshalerType(t reflect.Type) bool {
+	if hasDecoder(t) {
+		return true
+	}
+
+	for _, typ := range []reflect.Type{t, reflect.PtrTo(t)} {
+		if typ.Implements(configUnmarshalerType) || typ.Implements(textUnmarshalerType) {
+			return true
+		}
+	}
+
+	return false
+}
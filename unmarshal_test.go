@@ -0,0 +1,176 @@
+package multiconfig
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"testing"
+)
+
+// IPAddr wraps net.IP and implements encoding.TextUnmarshaler.
+type IPAddr struct {
+	net.IP
+}
+
+func (a *IPAddr) UnmarshalText(b []byte) error {
+	ip := net.ParseIP(string(b))
+	if ip == nil {
+		return errors.New("invalid IP address " + string(b))
+	}
+
+	a.IP = ip
+	return nil
+}
+
+// Level implements ConfigUnmarshaler.
+type Level int
+
+func (l *Level) UnmarshalConfig(b []byte) error {
+	switch string(b) {
+	case "debug":
+		*l = 1
+	case "info":
+		*l = 2
+	default:
+		return errors.New("unknown level " + string(b))
+	}
+
+	return nil
+}
+
+type Network struct {
+	Gateway IPAddr
+	Peers   []IPAddr
+}
+
+type Node struct {
+	Address IPAddr
+	Level   Level
+	Network Network
+}
+
+func TestUnmarshalerFile(t *testing.T) {
+	l := &YAMLLoader{Reader: strings.NewReader(
+		"address: 10.0.0.1\nlevel: debug\nnetwork:\n  gateway: 10.0.0.254\n  peers: [10.0.0.2, 10.0.0.3]\n")}
+
+	n := &Node{}
+	if err := l.Load(n); err != nil {
+		t.Fatal(err)
+	}
+
+	testNode(t, n)
+}
+
+func TestUnmarshalerEnv(t *testing.T) {
+	env := map[string]string{
+		"NODE_ADDRESS":         "10.0.0.1",
+		"NODE_LEVEL":           "debug",
+		"NODE_NETWORK_GATEWAY": "10.0.0.254",
+		"NODE_NETWORK_PEERS":   "10.0.0.2,10.0.0.3",
+	}
+
+	for key, val := range env {
+		os.Setenv(key, val)
+		defer os.Unsetenv(key)
+	}
+
+	n := &Node{}
+	if err := (&EnvironmentLoader{}).Load(n); err != nil {
+		t.Fatal(err)
+	}
+
+	testNode(t, n)
+}
+
+func TestUnmarshalerError(t *testing.T) {
+	l := &FlagLoader{Args: []string{"-network-gateway", "10.0.0"}}
+
+	err := l.Load(&Node{})
+	if err == nil {
+		t.Fatal("gateway should be an invalid IP address")
+	}
+
+	errStr := "multiconfig: field 'Network.Gateway': invalid IP address 10.0.0"
+	if !strings.Contains(err.Error(), errStr) {
+		t.Fatalf("Err string is wrong: expected %s, got: %s", errStr, err.Error())
+	}
+
+	j := &JSONLoader{Reader: strings.NewReader(`{"Network": {"Peers": ["10.0.0.2", "x"]}}`)}
+
+	err = j.Load(&Node{})
+	if err == nil {
+		t.Fatal("peer should be an invalid IP address")
+	}
+
+	errStr = "multiconfig: field 'Network.Peers[1]': invalid IP address x"
+	if err.Error() != errStr {
+		t.Fatalf("Err string is wrong: expected %s, got: %s", errStr, err.Error())
+	}
+}
+
+func testNode(t *testing.T, n *Node) {
+	if n.Address.String() != "10.0.0.1" {
+		t.Errorf("Address value is wrong: %s", n.Address)
+	}
+
+	if n.Level != 1 {
+		t.Errorf("Level value is wrong: %d", n.Level)
+	}
+
+	if n.Network.Gateway.String() != "10.0.0.254" {
+		t.Errorf("Gateway value is wrong: %s", n.Network.Gateway)
+	}
+
+	if len(n.Network.Peers) != 2 || n.Network.Peers[1].String() != "10.0.0.3" {
+		t.Errorf("Peers value is wrong: %v", n.Network.Peers)
+	}
+}
+
+// Hosts holds standard library types implementing encoding.TextUnmarshaler.
+type Hosts struct {
+	Gateway net.IP `default:"10.0.0.254"`
+	Local   net.IP
+	Peers   []net.IP
+	Roles   map[string]net.IP
+}
+
+func TestTextUnmarshalerStdlib(t *testing.T) {
+	env := testEnvironment{
+		"HOSTS_LOCAL":      "10.0.0.1",
+		"HOSTS_PEERS":      "10.0.0.2,10.0.0.3",
+		"HOSTS_ROLES_MAIN": "10.0.0.4",
+	}
+
+	sources := map[string]Loader{
+		"env": &EnvironmentLoader{getenv: env.get, environ: env.environ},
+		"flags": &FlagLoader{Args: []string{
+			"-local", "10.0.0.1", "-peers", "10.0.0.2,10.0.0.3", "-roles", "main=10.0.0.4",
+		}},
+	}
+
+	for name, loader := range sources {
+		h := &Hosts{}
+		if err := MultiLoader(&TagLoader{}, loader).Load(h); err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+
+		if !h.Gateway.Equal(net.IPv4(10, 0, 0, 254)) || !h.Local.Equal(net.IPv4(10, 0, 0, 1)) {
+			t.Errorf("%s: addresses are wrong: %s, %s", name, h.Gateway, h.Local)
+		}
+
+		if len(h.Peers) != 2 || !h.Peers[1].Equal(net.IPv4(10, 0, 0, 3)) {
+			t.Errorf("%s: peers are wrong: %v", name, h.Peers)
+		}
+
+		if !h.Roles["main"].Equal(net.IPv4(10, 0, 0, 4)) {
+			t.Errorf("%s: roles are wrong: %v", name, h.Roles)
+		}
+	}
+
+	err := (&FlagLoader{Args: []string{"-local", "10.0.0"}}).Load(&Hosts{})
+	if err == nil || !strings.Contains(err.Error(), "field 'Local'") {
+		t.Errorf("expected an invalid address error, got: %v", err)
+	}
+}
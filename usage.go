@@ -0,0 +1,122 @@
+package multiconfig
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/structs"
+)
+
+// descTag is the tag describing a field in the help of the flags, i.e:
+// `desc:"URL of the database"`.
+const descTag = "desc"
+
+// Option describes a field which can be set from the command line, to print
+// the help of the flags.
+type Option struct {
+	// Path is the path of the field, i.e: "Postgres.Port".
+	Path string
+
+	// Flag is the name of the flag of the field, and Shorthand its alias.
+	Flag      string
+	Shorthand string
+
+	// Type is the name of the type of the field.
+	Type string
+
+	// Usage is the usage message of the flag, given by the flagUsage or desc
+	// tags, or by the FlagUsageFunc.
+	Usage string
+
+	// Env is the name of the environment variable of the field.
+	Env string
+
+	// Default is the value of the default tag of the field, if any. It's
+	// redacted if the field is tagged with `secret:"true"`.
+	Default string
+
+	// Required is true if the field is required.
+	Required bool
+}
+
+// Options returns the options of the struct s, in the order of its fields,
+// as described by the flags and the environment variables f generates. The
+// options of commands are left out, see CommandFieldTag.
+func (f *FlagLoader) Options(s interface{}) []Option {
+	return f.options(s, "", structs.Fields(s))
+}
+
+// options returns the options of the given fields, found at prefix in the
+// struct s.
+func (f *FlagLoader) options(s interface{}, prefix string, fields []*structs.Field) []Option {
+	e := &EnvironmentLoader{
+		Prefix:    f.EnvPrefix,
+		CamelCase: f.CamelCase,
+		NameTag:   f.NameTag,
+	}
+
+	var options []Option
+
+	l := &flagDefs{FlagLoader: f}
+	l.register = func(path string, field *structs.Field, fl *Flag) {
+		def := field.Tag("default")
+		if def != "" {
+			def = fmt.Sprint(redactValue(field, def))
+		}
+
+		options = append(options, Option{
+			Path:      path,
+			Flag:      fl.Name,
+			Shorthand: fl.Shorthand,
+			Type:      fl.Value.Type(),
+			Usage:     fl.Usage,
+			Env:       e.envNameOf(s, path),
+			Default:   def,
+			Required:  field.Tag("required") == "true",
+		})
+	}
+
+	l.processFields(s, prefix, fields)
+
+	return options
+}
+
+// PrintOptions prints the help of the given options to w, i.e:
+//
+//	-db-url, -d string
+//		URL of the database (env SERVER_DBURL, default postgres://localhost, required)
+//
+// It's the default UsageFunc of a FlagLoader.
+func PrintOptions(w io.Writer, options []Option) {
+	for _, o := range options {
+		line := "  -" + o.Flag
+		if o.Shorthand != "" {
+			line += ", -" + o.Shorthand
+		}
+
+		if o.Type != "bool" {
+			line += " " + o.Type
+		}
+
+		var details []string
+		if o.Env != "" {
+			details = append(details, "env "+o.Env)
+		}
+
+		if o.Default != "" {
+			details = append(details, "default "+o.Default)
+		}
+
+		if o.Required {
+			details = append(details, "required")
+		}
+
+		usage := o.Usage
+		if len(details) > 0 {
+			usage += " (" + strings.Join(details, ", ") + ")"
+		}
+
+		fmt.Fprintf(w, "%s\n    \t%s\n", line, usage)
+	}
+}
@@ -0,0 +1,88 @@
+package multiconfig
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type UsageConfig struct {
+	DBURL    string `flag:"db-url,d" env:"DATABASE_URL" desc:"URL of the database" required:"true"`
+	Port     int    `default:"8080"`
+	Debug    bool
+	Secret   string `flag:"-"`
+	Postgres *Postgres
+}
+
+func TestFlagOptions(t *testing.T) {
+	f := &FlagLoader{EnvPrefix: "APP"}
+
+	options := f.Options(&UsageConfig{})
+	if len(options) != 8 {
+		t.Fatalf("unexpected options: %+v", options)
+	}
+
+	want := []Option{
+		{Path: "DBURL", Flag: "db-url", Shorthand: "d", Type: "string", Usage: "URL of the database",
+			Env: "DATABASE_URL", Required: true},
+		{Path: "Port", Flag: "port", Type: "int", Usage: "Change value of Port.", Env: "APP_PORT", Default: "8080"},
+		{Path: "Debug", Flag: "debug", Type: "bool", Usage: "Change value of Debug.", Env: "APP_DEBUG"},
+		{Path: "Postgres.Enabled", Flag: "postgres-enabled", Type: "bool", Usage: "Change value of Postgres-Enabled.",
+			Env: "APP_POSTGRES_ENABLED"},
+	}
+
+	for i, o := range want {
+		if !reflect.DeepEqual(options[i], o) {
+			t.Errorf("option %d is %+v, want %+v", i, options[i], o)
+		}
+	}
+}
+
+func TestPrintOptions(t *testing.T) {
+	var buf bytes.Buffer
+	PrintOptions(&buf, []Option{
+		{Flag: "db-url", Shorthand: "d", Type: "string", Usage: "URL of the database", Env: "DATABASE_URL", Required: true},
+		{Flag: "port", Type: "int", Usage: "Change value of Port.", Env: "APP_PORT", Default: "8080"},
+		{Flag: "debug", Type: "bool", Usage: "Change value of Debug."},
+	})
+
+	want := "  -db-url, -d string\n    \tURL of the database (env DATABASE_URL, required)\n" +
+		"  -port int\n    \tChange value of Port. (env APP_PORT, default 8080)\n" +
+		"  -debug\n    \tChange value of Debug.\n"
+
+	if buf.String() != want {
+		t.Errorf("unexpected usage:\n%s", buf.String())
+	}
+}
+
+func TestUsageFunc(t *testing.T) {
+	var got []Option
+	f := &FlagLoader{
+		Args:      []string{"-help"},
+		UsageFunc: func(_ io.Writer, options []Option) { got = options },
+	}
+
+	if err := f.Load(&UsageConfig{}); err != flag.ErrHelp {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(got) == 0 || got[0].Flag != "db-url" {
+		t.Errorf("unexpected options: %+v", got)
+	}
+
+	var buf bytes.Buffer
+	f = &FlagLoader{}
+	if err := f.Load(&UsageConfig{}); err != nil {
+		t.Fatal(err)
+	}
+
+	f.flagSet.SetOutput(&buf)
+	f.flagSet.Usage()
+
+	if !strings.Contains(buf.String(), "(env USAGECONFIG_PORT, default 8080)") {
+		t.Errorf("unexpected usage:\n%s", buf.String())
+	}
+}
@@ -1,8 +1,10 @@
 package multiconfig
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/fatih/structs"
 )
@@ -15,6 +17,87 @@ type Validator interface {
 	Validate(s interface{}) error
 }
 
+// ValidatorFunc is an adapter to use an ordinary function as a Validator:
+//
+//	m.AddValidator(multiconfig.ValidatorFunc(func(s interface{}) error {
+//		if conf := s.(*Server); conf.TLS && conf.CertFile == "" {
+//			return errors.New("a certificate is needed for TLS")
+//		}
+//		return nil
+//	}))
+type ValidatorFunc func(s interface{}) error
+
+// Validate calls f(s).
+func (f ValidatorFunc) Validate(s interface{}) error {
+	return f(s)
+}
+
+// ValidationErrors is returned by the validators of this package when fields
+// fail validation. It holds the error of every failing field rather than
+// only the first one, so all of them can be fixed at once.
+type ValidationErrors []error
+
+// Error returns the message of each error, one per line.
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the errors, for errors.Is and errors.As.
+func (e ValidationErrors) Unwrap() []error {
+	return e
+}
+
+// Is reports whether any of the errors matches target. Before Go 1.20,
+// errors.Is doesn't walk the errors returned by Unwrap but calls Is.
+func (e ValidationErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As finds the first of the errors matching target and sets target to it.
+// Before Go 1.20, errors.As doesn't walk the errors returned by Unwrap but
+// calls As.
+func (e ValidationErrors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// add appends err, if not nil. The errors of a ValidationErrors are appended
+// one by one.
+func (e *ValidationErrors) add(err error) {
+	switch errs := err.(type) {
+	case nil:
+	case ValidationErrors:
+		*e = append(*e, errs...)
+	default:
+		*e = append(*e, err)
+	}
+}
+
+// err returns the errors, or nil if there are none.
+func (e ValidationErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+
+	return e
+}
+
 // RequiredValidator validates the struct against zero values.
 type RequiredValidator struct {
 	//  TagName holds the validator tag name. The default is "required"
@@ -27,47 +110,257 @@ type RequiredValidator struct {
 // Validate validates the given struct agaist field's zero values. If
 // intentionaly, the value of a field is `zero-valued`(e.g false, 0, "")
 // required tag should not be set for that field.
+//
+// The only exception are booleans and numbers validated by the DefaultLoader
+// which loaded them, i.e: with MustLoad or DefaultLoader.Validate: their zero
+// value is accepted if it was explicitly provided by a source, while a field
+// never provided by any source is reported as missing. See
+// DefaultLoader.LoadedFields.
+//
+// Required slices and maps must hold at least one element, and required
+// pointers must be non-nil. A required nested struct must have at least one
+// field set, by any source or to a non-zero value.
+//
+// Nested structs are validated field by field, including embedded structs
+// and non-nil pointers to structs. Errors name a field by its full path,
+// i.e: 'API.AppServer.Host' for a Host field promoted from an embedded
+// AppServer, which is validated once, where it's declared. Every missing
+// field is reported, within ValidationErrors.
 func (e *RequiredValidator) Validate(s interface{}) error {
-	if e.TagName == "" {
-		e.TagName = "required"
+	// the defaults are set on a copy, the validator being shared
+	v := *e
+	if v.TagName == "" {
+		v.TagName = "required"
 	}
 
-	if e.TagValue == "" {
-		e.TagValue = "true"
+	if v.TagValue == "" {
+		v.TagValue = "true"
 	}
 
+	var errs ValidationErrors
 	for _, field := range structs.Fields(s) {
-		if err := e.processField("", field); err != nil {
-			return err
-		}
+		errs.add(v.processField(s, "", field))
 	}
 
-	return nil
+	return errs.err()
 }
 
-func (e *RequiredValidator) processField(fieldName string, field *structs.Field) error {
+func (e *RequiredValidator) processField(s interface{}, fieldName string, field *structs.Field) error {
 	fieldName += field.Name()
-	switch field.Kind() {
-	case reflect.Struct:
+	switch {
+	case isStructPtr(field) && !isUnmarshaler(field):
+		// a nil pointer is an optional struct, which has no fields to validate
+		// unless the pointer itself is required
+		if field.IsZero() {
+			if field.Tag(e.TagName) == e.TagValue {
+				return fieldErrorf(fieldName, field.Value(), "is required")
+			}
+
+			return nil
+		}
+
+		fieldName += "."
+
+		var errs ValidationErrors
+		for _, f := range field.Fields() {
+			errs.add(e.processField(s, fieldName, f))
+		}
+
+		return errs.err()
+	case field.Kind() == reflect.Struct && !isUnmarshaler(field):
+		// a required struct must have at least one field set, its own
+		// required fields being meaningless otherwise
+		if field.Tag(e.TagName) == e.TagValue && field.IsZero() && !loadedWithin(s, fieldName) {
+			return fieldErrorf(fieldName, field.Value(), "is required")
+		}
+
 		// this is used for error messages below, when we have an error at the
 		// child properties add parent properties into the error message as well
 		fieldName += "."
 
+		var errs ValidationErrors
 		for _, f := range field.Fields() {
-			if err := e.processField(fieldName, f); err != nil {
-				return err
-			}
+			errs.add(e.processField(s, fieldName, f))
 		}
+
+		return errs.err()
 	default:
 		val := field.Tag(e.TagName)
 		if val != e.TagValue {
 			return nil
 		}
 
-		if field.IsZero() {
-			return fmt.Errorf("multiconfig: field '%s' is required", fieldName)
+		// slices and maps must have at least one element
+		if v := reflect.ValueOf(field.Value()); v.Kind() == reflect.Slice || v.Kind() == reflect.Map {
+			if v.Len() != 0 {
+				return nil
+			}
+
+			return fieldErrorf(fieldName, field.Value(), "is required")
+		}
+
+		if !field.IsZero() {
+			return nil
+		}
+
+		if isSet, tracked := loadedField(s, fieldName); tracked && isSet && zeroIsValue(field.Kind()) {
+			return nil
+		}
+
+		return fieldErrorf(fieldName, field.Value(), "is required")
+	}
+}
+
+// zeroStruct returns a zero struct of the type pointed by the field, a nil
+// pointer to a struct. Its fields describe the nested fields of the pointer
+// without allocating it.
+func zeroStruct(field *structs.Field) *structs.Struct {
+	return structs.New(reflect.New(reflect.TypeOf(field.Value()).Elem()).Interface())
+}
+
+// isNestedPtr reports whether the field is a pointer to a struct whose fields
+// are loaded one by one, rather than a value set as a whole through
+// flag.Value or an unmarshaler.
+func isNestedPtr(field *structs.Field) bool {
+	if !isStructPtr(field) || isUnmarshaler(field) {
+		return false
+	}
+
+	return !reflect.TypeOf(field.Value()).Implements(flagValueType)
+}
+
+// isStructPtr reports whether the field is a pointer to a struct.
+func isStructPtr(field *structs.Field) bool {
+	if field.Kind() != reflect.Ptr || !field.IsExported() {
+		return false
+	}
+
+	return reflect.TypeOf(field.Value()).Elem().Kind() == reflect.Struct
+}
+
+// zeroIsValue reports whether the zero value of the given kind is a
+// meaningful value when explicitly provided, such as false or 0.
+func zeroIsValue(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+// OneOfValidator validates that the value of a field is one of the values
+// listed in its tag. The allowed values are separated by commas:
+//
+//	Scheme string `oneof:"http,https"`
+//
+// An optional documentation tag describes each allowed value. The
+// descriptions are included in the error message to help operators who are
+// not familiar with the codes:
+//
+//	Scheme string `oneof:"http,https" oneofDoc:"http=plaintext,https=TLS"`
+type OneOfValidator struct {
+	// TagName holds the validator tag name. The default is "oneof"
+	TagName string
+
+	// DocTagName holds the tag name describing the allowed values. The
+	// default is "oneofDoc"
+	DocTagName string
+}
+
+// Validate validates that the fields of the given struct tagged with TagName
+// hold one of the allowed values. Slice fields are validated element by
+// element, and nil pointers are skipped.
+func (o *OneOfValidator) Validate(s interface{}) error {
+	// the defaults are set on a copy, the validator being shared
+	v := *o
+	if v.TagName == "" {
+		v.TagName = "oneof"
+	}
+
+	if v.DocTagName == "" {
+		v.DocTagName = "oneofDoc"
+	}
+
+	return validateFields(s, v.processField)
+}
+
+func (o *OneOfValidator) processField(name string, field *structs.Field, v reflect.Value) error {
+	tag := field.Tag(o.TagName)
+	if tag == "" {
+		return nil
+	}
+
+	allowed := strings.Split(tag, ",")
+
+	values := []reflect.Value{v}
+	if v.Kind() == reflect.Slice {
+		values = values[:0]
+		for i := 0; i < v.Len(); i++ {
+			values = append(values, v.Index(i))
+		}
+	}
+
+	for _, value := range values {
+		if value.Kind() == reflect.Ptr && !value.IsNil() {
+			value = value.Elem()
+		}
+
+		if !oneOf(fmt.Sprint(value.Interface()), allowed) {
+			got := redactValue(field, value.Interface())
+			return fieldErrorf(name, got, "must be one of %s, got '%v'", o.describe(field, allowed), got)
 		}
 	}
 
 	return nil
 }
+
+// describe returns the allowed values of a field along with their
+// descriptions from the documentation tag, if any.
+func (o *OneOfValidator) describe(field *structs.Field, allowed []string) string {
+	docs := oneOfDocs(field.Tag(o.DocTagName))
+
+	described := make([]string, 0, len(allowed))
+	for _, value := range allowed {
+		if doc, ok := docs[value]; ok {
+			value = fmt.Sprintf("%s (%s)", value, doc)
+		}
+
+		described = append(described, value)
+	}
+
+	return "[" + strings.Join(described, ", ") + "]"
+}
+
+// oneOfDocs parses a documentation tag in the form of
+// "value=description,value=description" into a map.
+func oneOfDocs(tag string) map[string]string {
+	docs := map[string]string{}
+	if tag == "" {
+		return docs
+	}
+
+	for _, pair := range strings.Split(tag, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		docs[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+
+	return docs
+}
+
+func oneOf(value string, allowed []string) bool {
+	for _, a := range allowed {
+		if value == strings.TrimSpace(a) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,6 +1,12 @@
 package multiconfig
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
 
 func TestValidators(t *testing.T) {
 	s := getDefaultServer()
@@ -48,3 +54,362 @@ func TestValidatorsCustomTag(t *testing.T) {
 		t.Fatalf("Err string is wrong: expected %s, got: %s", errStr, err.Error())
 	}
 }
+
+func TestOneOfValidator(t *testing.T) {
+	type Endpoint struct {
+		Scheme string `oneof:"http,https" oneofDoc:"http=plaintext,https=TLS"`
+		Ports  []int  `oneof:"80,443"`
+	}
+
+	s := &Endpoint{Scheme: "https", Ports: []int{80, 443}}
+	if err := (&OneOfValidator{}).Validate(s); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Scheme = "ftp"
+	err := (&OneOfValidator{}).Validate(s)
+	if err == nil {
+		t.Fatal("Scheme should be one of http or https")
+	}
+
+	errStr := "multiconfig: field 'Scheme' must be one of [http (plaintext), https (TLS)], got 'ftp'"
+	if err.Error() != errStr {
+		t.Fatalf("Err string is wrong: expected %s, got: %s", errStr, err.Error())
+	}
+
+	s.Scheme = "http"
+	s.Ports = []int{80, 8080}
+	err = (&OneOfValidator{}).Validate(s)
+	if err == nil {
+		t.Fatal("Ports should be one of 80 or 443")
+	}
+
+	errStr = "multiconfig: field 'Ports' must be one of [80, 443], got '8080'"
+	if err.Error() != errStr {
+		t.Fatalf("Err string is wrong: expected %s, got: %s", errStr, err.Error())
+	}
+}
+
+func TestOneOfValidatorPointers(t *testing.T) {
+	type DB struct {
+		Mode *string `oneof:"primary,replica"`
+	}
+
+	type Service struct {
+		Scheme *string `oneof:"http,https"`
+		DB     *DB
+	}
+
+	// nil pointers are optional values
+	s := &Service{}
+	if err := (&OneOfValidator{}).Validate(s); err != nil {
+		t.Fatal(err)
+	}
+
+	scheme, mode := "ftp", "standby"
+	s = &Service{Scheme: &scheme, DB: &DB{Mode: &mode}}
+
+	want := []string{
+		"multiconfig: field 'Scheme' must be one of [http, https], got 'ftp'",
+		"multiconfig: field 'DB.Mode' must be one of [primary, replica], got 'standby'",
+	}
+
+	err := (&OneOfValidator{}).Validate(s)
+	if err == nil || err.Error() != strings.Join(want, "\n") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestResolvableValidator(t *testing.T) {
+	type Cluster struct {
+		AdvertiseHost string `validate:"resolvable"`
+		Peer          string `validate:"resolvable"`
+	}
+
+	lookup := func(ctx context.Context, host string) ([]string, error) {
+		if host == "node1.example.com" {
+			return []string{"10.0.0.1"}, nil
+		}
+
+		return nil, errors.New("no such host")
+	}
+
+	v := &ResolvableValidator{LookupHost: lookup}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Cluster{AdvertiseHost: "node1.example.com:8080", Peer: hostname}
+	if err := v.Validate(s); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Peer = "nod1.example.com"
+	err = v.Validate(s)
+	if err == nil {
+		t.Fatal("Peer should not be resolvable")
+	}
+
+	errStr := "multiconfig: field 'Peer' is not a resolvable host name: no such host"
+	if err.Error() != errStr {
+		t.Fatalf("Err string is wrong: expected %s, got: %s", errStr, err.Error())
+	}
+
+	// the fields of struct pointers are validated too
+	type Node struct {
+		Cluster *Cluster
+	}
+
+	err = v.Validate(&Node{Cluster: s})
+	if err == nil || err.Error() != "multiconfig: field 'Cluster.Peer' is not a resolvable host name: no such host" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := v.Validate(&Node{}); err != nil {
+		t.Errorf("a nil struct pointer should be valid: %s", err)
+	}
+}
+
+func TestValidatorsFlattenedStruct(t *testing.T) {
+	s := &TaggedServer{
+		Name: "koding",
+		Postgres: Postgres{
+			Port:  5432,
+			Hosts: []string{"192.168.2.1"},
+		},
+	}
+
+	if err := (&RequiredValidator{}).Validate(s); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Port = 0
+	errStr := "multiconfig: field 'Postgres.Port' is required"
+	if err := (&RequiredValidator{}).Validate(s); err == nil || err.Error() != errStr {
+		t.Errorf("got error %v, want %s", err, errStr)
+	}
+
+	s.Port = 5432
+	s.Hosts = nil
+	errStr = "multiconfig: field 'Postgres.Hosts' is required"
+	if err := (&RequiredValidator{}).Validate(s); err == nil || err.Error() != errStr {
+		t.Errorf("got error %v, want %s", err, errStr)
+	}
+
+	// a zero port provided by a source through its promoted name is valid
+	s = new(TaggedServer)
+	m := &DefaultLoader{
+		Loader:    &JSONLoader{Reader: strings.NewReader(`{"Name": "koding", "Port": 0, "Hosts": ["192.168.2.1"]}`)},
+		Validator: &RequiredValidator{},
+	}
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Validate(s); err != nil {
+		t.Error(err)
+	}
+}
+
+// RequiredServer is embedded by the structs validating the required fields
+// of embedded structs.
+type RequiredServer struct {
+	Scheme string `default:"https"`
+	Host   string `required:"true"`
+	Port   int
+}
+
+func TestValidatorsEmbeddedStruct(t *testing.T) {
+	type API struct {
+		RequiredServer
+		Test bool
+	}
+
+	type Database struct {
+		RequiredServer
+		DBName string
+	}
+
+	type RequiredApp struct {
+		API   API
+		Mongo Database
+	}
+
+	newApp := func() *RequiredApp {
+		return &RequiredApp{
+			API:   API{RequiredServer: RequiredServer{Host: "api.myapp.com", Port: 81}},
+			Mongo: Database{RequiredServer: RequiredServer{Host: "mongo.myapp.com", Port: 27017}, DBName: "koding"},
+		}
+	}
+
+	a := newApp()
+	if err := (&RequiredValidator{}).Validate(a); err != nil {
+		t.Fatal(err)
+	}
+
+	a.API.Host = ""
+	errStr := "multiconfig: field 'API.RequiredServer.Host' is required"
+	if err := (&RequiredValidator{}).Validate(a); err == nil || err.Error() != errStr {
+		t.Errorf("got error %v, want %s", err, errStr)
+	}
+
+	a = newApp()
+	a.Mongo.Host = ""
+	errStr = "multiconfig: field 'Mongo.RequiredServer.Host' is required"
+	if err := (&RequiredValidator{}).Validate(a); err == nil || err.Error() != errStr {
+		t.Errorf("got error %v, want %s", err, errStr)
+	}
+}
+
+func TestValidatorsEmbeddedStructPointer(t *testing.T) {
+	type Proxy struct {
+		*RequiredServer
+		Backend *RequiredServer `required:"true"`
+	}
+
+	p := &Proxy{Backend: &RequiredServer{Host: "backend"}}
+	if err := (&RequiredValidator{}).Validate(p); err != nil {
+		t.Fatalf("a nil optional struct pointer should be valid: %s", err)
+	}
+
+	p.RequiredServer = &RequiredServer{}
+	errStr := "multiconfig: field 'RequiredServer.Host' is required"
+	if err := (&RequiredValidator{}).Validate(p); err == nil || err.Error() != errStr {
+		t.Errorf("got error %v, want %s", err, errStr)
+	}
+
+	p.RequiredServer.Host = "proxy"
+	p.Backend = nil
+	errStr = "multiconfig: field 'Backend' is required"
+	if err := (&RequiredValidator{}).Validate(p); err == nil || err.Error() != errStr {
+		t.Errorf("got error %v, want %s", err, errStr)
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	type Upstream struct {
+		Host   string `required:"true"`
+		Scheme string `oneof:"http,https"`
+	}
+
+	type Proxy struct {
+		Name      string `required:"true"`
+		Port      int    `required:"true" max:"65535"`
+		Upstreams []string
+		Primary   Upstream
+	}
+
+	p := &Proxy{Port: 70000, Primary: Upstream{Scheme: "ftp"}}
+
+	err := New().Validator.Validate(p)
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("expected ValidationErrors, got %T: %v", err, err)
+	}
+
+	want := []string{
+		"multiconfig: field 'Name' is required",
+		"multiconfig: field 'Primary.Host' is required",
+		"multiconfig: field 'Primary.Scheme' must be one of [http, https], got 'ftp'",
+		"multiconfig: field 'Port' must be at most 65535, got 70000",
+	}
+
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got: %v", len(want), err)
+	}
+
+	for i, e := range errs {
+		if e.Error() != want[i] {
+			t.Errorf("error %d is wrong: expected %s, got: %s", i, want[i], e)
+		}
+	}
+
+	if err.Error() != strings.Join(want, "\n") {
+		t.Errorf("Err string is wrong: %s", err)
+	}
+
+	// As and Is are called by errors.As and errors.Is before Go 1.20
+	var fe *FieldError
+	if !errs.As(&fe) || fe.Path != "Name" {
+		t.Errorf("As should find the first FieldError, got %v", fe)
+	}
+
+	if !errs.Is(errs[1]) || errs.Is(errors.New("other")) {
+		t.Error("Is should match the errors only")
+	}
+}
+
+func TestAddValidator(t *testing.T) {
+	m := NewWithPath(testTOML)
+
+	calls := 0
+	m.AddValidator(ValidatorFunc(func(s interface{}) error {
+		calls++
+		if s.(*Server).Postgres.Port == 5432 {
+			return errors.New("multiconfig: the default port of Postgres is not allowed")
+		}
+		return nil
+	}))
+
+	s := new(Server)
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Name = ""
+	err := m.Validate(s)
+	if err == nil || err.Error() != "multiconfig: field 'Name' is required\nmulticonfig: the default port of Postgres is not allowed" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if calls != 1 {
+		t.Errorf("custom validator called %d times, want 1", calls)
+	}
+
+	m.SetValidators(ValidatorFunc(func(interface{}) error { return nil }))
+	if err := m.Validate(s); err != nil {
+		t.Errorf("the default validators were not replaced: %s", err)
+	}
+}
+
+func TestRequiredCollections(t *testing.T) {
+	type TLS struct {
+		CertFile string
+		KeyFile  string
+	}
+
+	type Cluster struct {
+		Hosts  []string          `required:"true"`
+		Labels map[string]string `required:"true"`
+		TLS    TLS               `required:"true"`
+		Proxy  *TLS              `required:"true"`
+	}
+
+	err := (&RequiredValidator{}).Validate(&Cluster{Hosts: []string{}})
+	want := "multiconfig: field 'Hosts' is required\n" +
+		"multiconfig: field 'Labels' is required\n" +
+		"multiconfig: field 'TLS' is required\n" +
+		"multiconfig: field 'Proxy' is required"
+	if err == nil || err.Error() != want {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	c := &Cluster{
+		Hosts:  []string{"10.0.0.1"},
+		Labels: map[string]string{"env": "prod"},
+		TLS:    TLS{CertFile: "cert.pem"},
+		Proxy:  &TLS{},
+	}
+	if err := (&RequiredValidator{}).Validate(c); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	// a struct provided by a source is present, even with zero fields
+	m := newDefaultLoader(&TagLoader{}, &JSONLoader{Reader: strings.NewReader(
+		`{"Hosts": ["10.0.0.1"], "Labels": {"env": "prod"}, "TLS": {"CertFile": ""}, "Proxy": {}}`)})
+	if _, err := LoadWith[Cluster](m); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
@@ -0,0 +1,273 @@
+package multiconfig
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultVaultMount is the mount path of the KV v2 secrets engine used if
+// the Mount of a VaultLoader is not set.
+const DefaultVaultMount = "secret"
+
+// VaultLoader satisfies the loader interface. It loads the configuration from
+// a secret of a HashiCorp Vault KV version 2 secrets engine. The keys of the
+// secret are mapped to the struct fields the same way the EnvironmentLoader
+// maps environment variables, i.e: STRUCTNAME_FIELDNAME, regardless of their
+// case. Nested objects are flattened, so {"postgres": {"port": 5432}} sets
+// STRUCTNAME_POSTGRES_PORT.
+//
+// The address, token and role are read from the standard VAULT_ADDR,
+// VAULT_TOKEN and VAULT_ROLE environment variables if not set. Without a
+// token, the loader logs in with the Kubernetes auth method using the role
+// and the service account token of the pod.
+type VaultLoader struct {
+	// Path is the path of the secret within the mount, i.e: "myapp/config".
+	Path string
+
+	// Mount is the mount path of the KV v2 secrets engine. If empty,
+	// DefaultVaultMount is used.
+	Mount string
+
+	// Address is the address of the Vault server. If empty, VAULT_ADDR is
+	// used.
+	Address string
+
+	// Token is the Vault token. If empty, VAULT_TOKEN is used.
+	Token string
+
+	// Role is the role to log in with when there is no token. If empty,
+	// VAULT_ROLE is used.
+	Role string
+
+	// AuthMount is the mount path of the Kubernetes auth method. The default
+	// is "kubernetes".
+	AuthMount string
+
+	// Namespace is the Vault Enterprise namespace. If empty, VAULT_NAMESPACE
+	// is used.
+	Namespace string
+
+	// Client is used to perform the requests. If nil, http.DefaultClient is
+	// used.
+	Client *http.Client
+
+	// Prefix prepends given string to every key
+	// {STRUCTNAME}_FIELDNAME will be {PREFIX}_FIELDNAME
+	Prefix string
+
+	// CamelCase adds a separator for field names in camelcase form. See
+	// EnvironmentLoader.CamelCase for details.
+	CamelCase bool
+
+	// SliceSeparator separates the elements of slice fields given as
+	// strings. The default is ",".
+	SliceSeparator string
+
+	// Strict rejects the keys starting with the prefix which don't match any
+	// field instead of ignoring them.
+	Strict bool
+}
+
+// Load loads the source into the config defined by struct s
+func (v *VaultLoader) Load(s interface{}) error {
+	return v.LoadContext(context.Background(), s)
+}
+
+// LoadContext is like Load but the requests are bound to the given context,
+// so they honor its cancellation and deadline.
+func (v *VaultLoader) LoadContext(ctx context.Context, s interface{}) error {
+	if v.Path == "" {
+		return ErrSourceNotSet
+	}
+
+	addr := envOr(v.Address, "VAULT_ADDR")
+	if addr == "" {
+		return fmt.Errorf("multiconfig: vault: address is not set")
+	}
+	addr = strings.TrimSuffix(addr, "/")
+
+	token := envOr(v.Token, "VAULT_TOKEN")
+	if token == "" {
+		var err error
+		if token, err = v.login(ctx, addr); err != nil {
+			return err
+		}
+	}
+
+	mount := v.Mount
+	if mount == "" {
+		mount = DefaultVaultMount
+	}
+	mount = strings.Trim(mount, "/")
+	path := strings.Trim(v.Path, "/")
+
+	var secret struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := v.do(ctx, http.MethodGet, addr+"/v1/"+mount+"/data/"+path, token, nil, &secret); err != nil {
+		return err
+	}
+
+	vars := map[string]string{}
+	flattenSecret(vars, "", secret.Data.Data, v.SliceSeparator)
+
+	e := &EnvironmentLoader{
+		Prefix:         v.Prefix,
+		CamelCase:      v.CamelCase,
+		SliceSeparator: v.SliceSeparator,
+		Strict:         v.Strict,
+		getenv:         func(key string) string { return vars[key] },
+		environ: func() []string {
+			environ := make([]string, 0, len(vars))
+			for key, val := range vars {
+				environ = append(environ, key+"="+val)
+			}
+			return environ
+		},
+		source: "vault " + mount + "/" + path,
+	}
+
+	return e.Load(s)
+}
+
+// login logs in with the Kubernetes auth method and returns the client
+// token.
+func (v *VaultLoader) login(ctx context.Context, addr string) (string, error) {
+	role := envOr(v.Role, "VAULT_ROLE")
+	if role == "" {
+		return "", fmt.Errorf("multiconfig: vault: neither a token nor a role is set")
+	}
+
+	jwt, err := ioutil.ReadFile(filepath.Join(serviceAccountDir, "token"))
+	if err != nil {
+		return "", fmt.Errorf("multiconfig: vault: reading the service account token: %s", err)
+	}
+
+	authMount := v.AuthMount
+	if authMount == "" {
+		authMount = "kubernetes"
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"role": role,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var auth struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	url := addr + "/v1/auth/" + strings.Trim(authMount, "/") + "/login"
+	if err := v.do(ctx, http.MethodPost, url, "", body, &auth); err != nil {
+		return "", err
+	}
+
+	if auth.Auth.ClientToken == "" {
+		return "", fmt.Errorf("multiconfig: vault: POST %s: no client token returned", url)
+	}
+
+	return auth.Auth.ClientToken, nil
+}
+
+// do performs a request to the Vault API and decodes the JSON response into
+// out.
+func (v *VaultLoader) do(ctx context.Context, method, url, token string, body []byte, out interface{}) error {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if ns := envOr(v.Namespace, "VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := v.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("multiconfig: vault: %s %s: unexpected status %s", method, url, resp.Status)
+	}
+
+	// numbers are kept as written instead of being converted to float64
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+	if err := dec.Decode(out); err != nil {
+		return fmt.Errorf("multiconfig: vault: %s %s: %s", method, url, err)
+	}
+
+	return nil
+}
+
+// flattenSecret adds the values of the secret data to vars, keyed by their
+// upper cased name. The keys of nested objects are joined with "_", lists are
+// joined with the separator.
+func flattenSecret(vars map[string]string, prefix string, data map[string]interface{}, sep string) {
+	if sep == "" {
+		sep = ","
+	}
+
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		name := strings.ToUpper(key)
+		if prefix != "" {
+			name = prefix + "_" + name
+		}
+
+		switch val := data[key].(type) {
+		case nil:
+		case map[string]interface{}:
+			flattenSecret(vars, name, val, sep)
+		case []interface{}:
+			elems := make([]string, len(val))
+			for i, elem := range val {
+				elems[i] = fmt.Sprint(elem)
+			}
+			vars[name] = strings.Join(elems, sep)
+		default:
+			vars[name] = fmt.Sprint(val)
+		}
+	}
+}
+
+// envOr returns val, or the value of the environment variable named by the
+// key if val is empty.
+func envOr(val, key string) string {
+	if val != "" {
+		return val
+	}
+
+	return os.Getenv(key)
+}
@@ -0,0 +1,172 @@
+package multiconfig
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const vaultSecret = `{
+  "data": {
+    "data": {
+      "server_name": "koding",
+      "server_port": 6060,
+      "server_enabled": true,
+      "server_id": 1234567890,
+      "server_labels": [123, 456],
+      "SERVER_USERS": "ankara,istanbul",
+      "server_interval": "10s",
+      "server": {
+        "postgres": {
+          "enabled": true,
+          "port": 5432,
+          "hosts": ["192.168.2.1", "192.168.2.2", "192.168.2.3"],
+          "dbname": "configdb",
+          "availabilityratio": 8.23
+        }
+      }
+    },
+    "metadata": {"version": 3}
+  }
+}`
+
+func newVaultServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/kubernetes/login":
+			var login struct{ Role, JWT string }
+			if err := json.NewDecoder(r.Body).Decode(&login); err != nil || login.Role != "app" || login.JWT != "jwt" {
+				http.Error(w, "permission denied", http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"auth": {"client_token": "role-token"}}`))
+		case "/v1/secret/data/myapp/config":
+			if tok := r.Header.Get("X-Vault-Token"); tok != "root" && tok != "role-token" {
+				http.Error(w, "permission denied", http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(vaultSecret))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestVaultLoader(t *testing.T) {
+	srv := newVaultServer(t)
+	defer srv.Close()
+
+	s := &Server{}
+	l := &VaultLoader{Address: srv.URL, Token: "root", Path: "myapp/config"}
+	if err := l.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	testStruct(t, s, getDefaultServer())
+}
+
+func TestVaultLoaderEnv(t *testing.T) {
+	srv := newVaultServer(t)
+	defer srv.Close()
+
+	t.Setenv("VAULT_ADDR", srv.URL)
+	t.Setenv("VAULT_TOKEN", "root")
+
+	// the secret overrides the file values
+	m := newDefaultLoader(
+		&TagLoader{},
+		&TOMLLoader{Path: testTOML},
+		&VaultLoader{Path: "myapp/config", Prefix: "SERVER"},
+	)
+
+	s := &Server{}
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	testStruct(t, s, getDefaultServer())
+
+	if got, want := m.Sources(s)["Postgres.Port"], "vault secret/myapp/config SERVER_POSTGRES_PORT"; got != want {
+		t.Errorf("Postgres.Port source: got %q, want %q", got, want)
+	}
+}
+
+func TestVaultLoaderRole(t *testing.T) {
+	srv := newVaultServer(t)
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "multiconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "token"), []byte("jwt\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(dir string) { serviceAccountDir = dir }(serviceAccountDir)
+	serviceAccountDir = dir
+
+	s := &Server{}
+	l := &VaultLoader{Address: srv.URL, Role: "app", Path: "/myapp/config/"}
+	if err := l.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	testStruct(t, s, getDefaultServer())
+
+	l.Role = "other"
+	if err := l.Load(&Server{}); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected a permission error, got: %v", err)
+	}
+}
+
+func TestVaultLoaderErrors(t *testing.T) {
+	srv := newVaultServer(t)
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		loader *VaultLoader
+		err    string
+	}{
+		{"no path", &VaultLoader{Address: srv.URL, Token: "root"}, ErrSourceNotSet.Error()},
+		{"no address", &VaultLoader{Token: "root", Path: "myapp/config"}, "address is not set"},
+		{"no token", &VaultLoader{Address: srv.URL, Path: "myapp/config"}, "neither a token nor a role is set"},
+		{"bad token", &VaultLoader{Address: srv.URL, Token: "bad", Path: "myapp/config"}, "403"},
+		{"missing", &VaultLoader{Address: srv.URL, Token: "root", Path: "missing"}, "404"},
+		{"strict", &VaultLoader{Address: srv.URL, Token: "root", Path: "myapp/config", Strict: true, Prefix: "SERVER_POSTGRES"},
+			"SERVER_POSTGRES_AVAILABILITYRATIO"},
+	}
+
+	for _, test := range tests {
+		err := test.loader.Load(&Server{})
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: expected an error containing %q, got: %v", test.name, test.err, err)
+		}
+	}
+}
+
+func TestVaultLoaderTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	l := &VaultLoader{
+		Address: srv.URL,
+		Token:   "root",
+		Path:    "myapp/config",
+		Client:  &http.Client{Timeout: 10 * time.Millisecond},
+	}
+	if err := l.Load(&Server{}); err == nil {
+		t.Error("expected a timeout error")
+	}
+}
@@ -0,0 +1,68 @@
+package multiconfig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SchemaVersionKey is the key of a config source declaring the version of
+// the schema it's written for. It's matched case insensitively.
+const SchemaVersionKey = "schemaVersion"
+
+// VersionRange is a range of schema versions supported by a binary. A
+// source declaring a schema version outside of the range is rejected before
+// it's loaded, so a config written for a newer, incompatible schema can't
+// silently half-load into an older binary. Sources which don't declare any
+// schema version are accepted.
+type VersionRange struct {
+	// Min is the lowest supported version.
+	Min int
+
+	// Max is the highest supported version. Zero means there is no upper
+	// bound.
+	Max int
+}
+
+// String returns the range in a human readable form.
+func (r VersionRange) String() string {
+	if r.Max == 0 {
+		return fmt.Sprintf("%d or later", r.Min)
+	}
+
+	if r.Min == r.Max {
+		return strconv.Itoa(r.Min)
+	}
+
+	return fmt.Sprintf("%d to %d", r.Min, r.Max)
+}
+
+// check verifies the schema version declared by the decoded source raw.
+func (r VersionRange) check(source string, raw map[string]interface{}) error {
+	var declared interface{}
+	for key, val := range raw {
+		if strings.EqualFold(key, SchemaVersionKey) {
+			declared = val
+		}
+	}
+
+	if declared == nil {
+		return nil
+	}
+
+	version, err := strconv.Atoi(strings.TrimSpace(fmt.Sprint(declared)))
+	if err != nil {
+		return fmt.Errorf("multiconfig: %s: %s %q is not an integer", source, SchemaVersionKey, fmt.Sprint(declared))
+	}
+
+	switch {
+	case version > r.Max && r.Max != 0:
+		return fmt.Errorf("multiconfig: %s: %s %d is newer than the supported %s, upgrade the application to load this config",
+			source, SchemaVersionKey, version, r)
+	case version < r.Min:
+		return fmt.Errorf("multiconfig: %s: %s %d is older than the supported %s, migrate the config to a supported version",
+			source, SchemaVersionKey, version, r)
+	}
+
+	return nil
+}
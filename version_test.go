@@ -0,0 +1,56 @@
+package multiconfig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSupportedVersion(t *testing.T) {
+	tests := []struct {
+		data string
+		err  string
+	}{
+		{data: "Name = \"koding\"\n"},
+		{data: "schemaVersion = 2\nName = \"koding\"\n"},
+		{data: "SchemaVersion = 2\nName = \"koding\"\n"},
+		{data: "SchemaVersion = \"3\"\n", err: "multiconfig: toml: schemaVersion 3 is newer than the supported 1 to 2, upgrade the application to load this config"},
+		{data: "schemaversion = 0\n", err: "multiconfig: toml: schemaVersion 0 is older than the supported 1 to 2, migrate the config to a supported version"},
+		{data: "schemaVersion = \"v2\"\n", err: `multiconfig: toml: schemaVersion "v2" is not an integer`},
+	}
+
+	for _, test := range tests {
+		l := &TOMLLoader{
+			Reader: strings.NewReader(test.data),
+			FileOptions: FileOptions{
+				SupportedVersion: &VersionRange{Min: 1, Max: 2},
+				Strict:           true,
+			},
+		}
+
+		s := &Server{}
+		err := l.Load(s)
+
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%q: %s", test.data, err)
+		case test.err != "" && (err == nil || err.Error() != test.err):
+			t.Errorf("%q: expected error %q, got: %v", test.data, test.err, err)
+		case test.err != "" && s.Name != "":
+			t.Errorf("%q: config should not be loaded", test.data)
+		}
+	}
+}
+
+func TestVersionRangeString(t *testing.T) {
+	tests := map[VersionRange]string{
+		{Min: 1}:         "1 or later",
+		{Min: 2, Max: 2}: "2",
+		{Min: 1, Max: 3}: "1 to 3",
+	}
+
+	for r, want := range tests {
+		if got := r.String(); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package multiconfig
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"math/rand"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// DefaultWatchInterval is how often the files watched by Watch are checked
+// for changes if the WatchInterval of the DefaultLoader is not set.
+const DefaultWatchInterval = time.Second
+
+// DefaultRemoteWatchInterval is how often the remote sources watched by Watch
+// are polled for changes if the RemoteWatchInterval of the DefaultLoader is
+// not set.
+const DefaultRemoteWatchInterval = 30 * time.Second
+
+// maxRefreshBackoff is the number of times the refresh interval of Watch is
+// doubled, at most, while the reloads keep failing.
+const maxRefreshBackoff = 5
+
+// ErrNothingToWatch states that the loader doesn't read any source to watch.
+var ErrNothingToWatch = errors.New("multiconfig: no config source to watch")
+
+// Watch watches the configuration files read by d for changes, as well as the
+// sources read by the HTTPLoader, EtcdLoader, ConsulLoader,
+// KubernetesLoader, GCPSecretManagerLoader and GCSLoader whose Watch option
+// is set. When the content of a source
+// changes, the configuration is loaded into a new struct of the type of s,
+// validated, and handed to onChange along with the previous configuration,
+// which is s for the first change.
+//
+// The struct s itself is never modified, so it can keep being read while a
+// new configuration is loaded. A configuration that fails to load or to
+// validate is reported to the Logger of d and skipped, the previous one
+// remains current.
+//
+// Files are polled every WatchInterval, which works the same way on every
+// platform and for editors replacing files instead of writing them. Remote
+// sources are polled every RemoteWatchInterval, each poll giving up after
+// the interval. The sources without a revision to poll, such as SSM
+// parameters, are reloaded every interval given to RefreshEvery. The
+// returned function stops watching: it cancels the pending requests, and
+// onChange is not called anymore once it returns.
+func (d *DefaultLoader) Watch(s interface{}, onChange func(old, new interface{})) (stop func(), err error) {
+	t := reflect.TypeOf(s)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("multiconfig: cannot watch %T, a pointer to a struct is required", s)
+	}
+
+	sources := watchedSources(d.Loader)
+	if len(sources) == 0 && d.refresh <= 0 {
+		return nil, ErrNothingToWatch
+	}
+
+	interval := d.WatchInterval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	remoteInterval := d.RemoteWatchInterval
+	if remoteInterval <= 0 {
+		remoteInterval = DefaultRemoteWatchInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	poll := func(src watchedSource) ([]byte, error) {
+		if !src.remote {
+			return src.revision(ctx)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, remoteInterval)
+		defer cancel()
+		return src.revision(ctx)
+	}
+
+	sums := make([][]byte, len(sources))
+	local, remote := false, false
+	for i, src := range sources {
+		// an optional file is watched until it's created
+		if sums[i], err = poll(src); err != nil && !(src.optional && err == ErrFileNotFound) {
+			cancel()
+			return nil, err
+		}
+
+		local = local || !src.remote
+		remote = remote || src.remote
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		// a nil channel never fires, for the sources or the refresh unused
+		var tick, remoteTick, refresh <-chan time.Time
+		if local {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		if remote {
+			ticker := time.NewTicker(remoteInterval)
+			defer ticker.Stop()
+			remoteTick = ticker.C
+		}
+
+		var timer *time.Timer
+		failures := 0
+		if d.refresh > 0 {
+			timer = time.NewTimer(refreshDelay(d.refresh, failures))
+			defer timer.Stop()
+			refresh = timer.C
+		}
+
+		current := s
+		for {
+			refreshing, polling := false, false
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick:
+			case <-remoteTick:
+				polling = true
+			case <-refresh:
+				refreshing = true
+			}
+
+			changed := refreshing
+			for i, src := range sources {
+				// a refresh polls every source, so that the change it
+				// reloads isn't reported again on the next tick
+				if !refreshing && src.remote != polling {
+					continue
+				}
+
+				// a file being replaced may be missing for a moment, and a
+				// remote source unreachable, it's checked again on the next
+				// tick
+				if sum, err := poll(src); err == nil && !bytes.Equal(sum, sums[i]) {
+					sums[i] = sum
+					changed = true
+				}
+			}
+
+			if !changed {
+				continue
+			}
+
+			conf := reflect.New(t.Elem()).Interface()
+			err := d.reload(ctx, conf)
+			if ctx.Err() != nil {
+				d.forget(conf)
+				return
+			}
+
+			if refreshing {
+				if err != nil {
+					failures++
+				} else {
+					failures = 0
+				}
+				timer.Reset(refreshDelay(d.refresh, failures))
+			}
+
+			if err != nil {
+				d.logger().Printf("multiconfig: reloading config: %s", err)
+				d.forget(conf)
+				continue
+			}
+
+			// a refresh reloads the sources whether they changed or not
+			if refreshing {
+				if changes, err := Diff(current, conf); err == nil && len(changes) == 0 {
+					d.forget(conf)
+					continue
+				}
+			}
+
+			if ctx.Err() != nil {
+				d.forget(conf)
+				return
+			}
+
+			onChange(current, conf)
+
+			// the sources of the replaced configuration are forgotten, the
+			// ones of s are kept for Sources
+			if current != s {
+				d.forget(current)
+			}
+			current = conf
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			cancel()
+			wg.Wait()
+		})
+	}, nil
+}
+
+// RefreshEvery makes Watch reload the configuration every interval, for the
+// sources which can't be watched, such as AWS SSM parameters or an
+// HTTPLoader without Watch. onChange is only called if a field changed, see
+// Diff. The interval varies by up to 10% so that the instances of a service
+// don't hit the sources at once, and it's doubled after each failing
+// reload, at most 5 times, so up to 32 times the interval, until a reload
+// succeeds.
+func (d *DefaultLoader) RefreshEvery(interval time.Duration) {
+	d.refresh = interval
+}
+
+// refreshDelay returns the delay before the next refresh of Watch, every
+// interval with a jitter of 10%, backing off after the given number of
+// failed refreshes.
+func refreshDelay(interval time.Duration, failures int) time.Duration {
+	delay := interval << uint(minInt(failures, maxRefreshBackoff))
+	if jitter := int64(delay / 10); jitter > 0 {
+		delay += time.Duration(rand.Int63n(2*jitter+1) - jitter)
+	}
+
+	return delay
+}
+
+// reload loads and validates conf, the requests being bound to ctx.
+func (d *DefaultLoader) reload(ctx context.Context, conf interface{}) error {
+	if err := d.LoadContext(ctx, conf); err != nil {
+		return err
+	}
+
+	if d.Validator != nil {
+		return d.Validate(conf)
+	}
+
+	return nil
+}
+
+// watchedSource is a configuration source read by a loader.
+type watchedSource struct {
+	// revision returns the checksum of the content of the source
+	revision func(ctx context.Context) ([]byte, error)
+
+	// optional is true if the source may not exist
+	optional bool
+
+	// remote is true if the source is read over the network, and polled
+	// every RemoteWatchInterval
+	remote bool
+}
+
+// fileRevision returns the function computing the checksum of the content of
+// the file.
+func fileRevision(fsys fs.FS, path string) func(context.Context) ([]byte, error) {
+	return func(context.Context) ([]byte, error) {
+		f, err := openConfig(fsys, path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		data, err := ioutil.ReadAll(f)
+		if err != nil {
+			return nil, err
+		}
+
+		sum := sha256.Sum256(data)
+		return sum[:], nil
+	}
+}
+
+// watchedSources returns the sources read by l, including the ones read by
+// the loaders of a MultiLoader.
+func watchedSources(l Loader) []watchedSource {
+	var sources []watchedSource
+	add := func(fsys fs.FS, path string, r interface{}) {
+		if path != "" && r == nil {
+			sources = append(sources, watchedSource{revision: fileRevision(fsys, path)})
+		}
+	}
+
+	switch l := l.(type) {
+	case multiLoader:
+		for _, loader := range l {
+			sources = append(sources, watchedSources(loader)...)
+		}
+	case *DefaultLoader:
+		sources = watchedSources(l.Loader)
+	case *optionalLoader:
+		sources = watchedSources(l.Loader)
+		for i := range sources {
+			sources[i].optional = true
+		}
+	case *TOMLLoader:
+		add(l.FS, l.Path, l.Reader)
+	case *JSONLoader:
+		add(l.FS, l.Path, l.Reader)
+	case *YAMLLoader:
+		add(l.FS, l.Path, l.Reader)
+	case *HCLLoader:
+		add(l.FS, l.Path, l.Reader)
+	case *INILoader:
+		add(l.FS, l.Path, l.Reader)
+	case *DotEnvLoader:
+		add(l.FS, l.Path, l.Reader)
+	case *EtcdLoader:
+		if l.Watch {
+			sources = append(sources, watchedSource{revision: l.revision, remote: true})
+		}
+	case *ConsulLoader:
+		if l.Watch {
+			sources = append(sources, watchedSource{revision: l.revision, remote: true})
+		}
+	case *HTTPLoader:
+		if l.Watch {
+			sources = append(sources, watchedSource{revision: l.revision, remote: true})
+		}
+	case *KubernetesLoader:
+		// a mounted ConfigMap is read like a file
+		if l.Watch {
+			sources = append(sources, watchedSource{revision: l.revision, remote: l.Dir == ""})
+		}
+	case *GCPSecretManagerLoader:
+		if l.Watch {
+			sources = append(sources, watchedSource{revision: l.revision, remote: true})
+		}
+	case *GCSLoader:
+		if l.Watch {
+			sources = append(sources, watchedSource{revision: l.revision, remote: true})
+		}
+	}
+
+	return sources
+}
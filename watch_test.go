@@ -0,0 +1,180 @@
+package multiconfig
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "multiconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.toml")
+	write := func(content string) {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("Name = \"koding\"\n[Postgres]\nPort = 5432\nHosts = [\"localhost\"]\n")
+
+	m := NewWithPath(path)
+	m.WatchInterval = 10 * time.Millisecond
+
+	s := new(Server)
+	m.MustLoad(s)
+
+	logs := make(logChan, 10)
+	m.Logger = log.New(logs, "", 0)
+
+	type change struct{ old, new *Server }
+	changes := make(chan change, 1)
+	stop, err := m.Watch(s, func(old, new interface{}) {
+		changes <- change{old.(*Server), new.(*Server)}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	// a config missing its required name is skipped
+	write("Name = \"\"\n[Postgres]\nPort = 5432\nHosts = [\"localhost\"]\n")
+	select {
+	case msg := <-logs:
+		if !strings.Contains(msg, "multiconfig: reloading config:") {
+			t.Errorf("unexpected log %q", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("invalid config not logged")
+	}
+	write("Name = \"reloaded\"\n[Postgres]\nPort = 6432\nHosts = [\"localhost\"]\n")
+
+	select {
+	case c := <-changes:
+		if c.old != s {
+			t.Errorf("the old config should be the watched struct")
+		}
+
+		if c.new.Name != "reloaded" || c.new.Postgres.Port != 6432 {
+			t.Errorf("reloaded config is wrong: %+v", c.new)
+		}
+
+		if s.Name != "koding" {
+			t.Errorf("the watched struct should not be modified, got name %q", s.Name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("config change not notified")
+	}
+
+	stop()
+	write("Name = \"stopped\"\n[Postgres]\nPort = 6432\nHosts = [\"localhost\"]\n")
+	time.Sleep(50 * time.Millisecond)
+
+	select {
+	case c := <-changes:
+		t.Errorf("change notified after stop: %+v", c.new)
+	default:
+	}
+}
+
+// logChan is a writer sending each write to the channel, for the messages
+// logged by a goroutine.
+type logChan chan string
+
+func (c logChan) Write(p []byte) (int, error) {
+	select {
+	case c <- string(p):
+	default:
+	}
+	return len(p), nil
+}
+
+func TestWatchNothing(t *testing.T) {
+	if _, err := New().Watch(new(Server), func(old, new interface{}) {}); err != ErrNothingToWatch {
+		t.Errorf("watching no file should fail with ErrNothingToWatch, got: %v", err)
+	}
+}
+
+// refreshedLoader sets the name of the loaded Server from the names it's
+// given, one per load, failing for the empty ones.
+type refreshedLoader struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (l *refreshedLoader) Load(s interface{}) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	name := l.names[0]
+	if len(l.names) > 1 {
+		l.names = l.names[1:]
+	}
+
+	if name == "" {
+		return errors.New("source unreachable")
+	}
+
+	s.(*Server).Name = name
+	return nil
+}
+
+func TestWatchRefresh(t *testing.T) {
+	l := &refreshedLoader{names: []string{"koding", "koding", "", "koding", "refreshed"}}
+	m := &DefaultLoader{Loader: l}
+	m.RefreshEvery(5 * time.Millisecond)
+
+	s := new(Server)
+	if err := m.Load(s); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := make(chan *Server, 5)
+	stop, err := m.Watch(s, func(old, new interface{}) {
+		changes <- new.(*Server)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	// the reloads finding the same config, or failing, are not notified
+	select {
+	case c := <-changes:
+		if c.Name != "refreshed" {
+			t.Errorf("unexpected refreshed name: %q", c.Name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("config change not notified")
+	}
+}
+
+func TestRefreshDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{3, 8 * time.Second},
+		{maxRefreshBackoff + 3, 32 * time.Second},
+	}
+
+	for _, test := range tests {
+		for i := 0; i < 10; i++ {
+			got := refreshDelay(time.Second, test.failures)
+			if got < test.want*9/10 || got > test.want*11/10 {
+				t.Errorf("%d failures: delay %s is not within 10%% of %s", test.failures, got, test.want)
+			}
+		}
+	}
+}